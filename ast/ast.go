@@ -67,6 +67,92 @@ func (letStatement *LetStatement) String() string {
 	return out.String()
 }
 
+type HashLetStatement struct {
+	Token token.Token
+	Names []*Identifier
+	Value Expression
+}
+
+func (hashLetStatement *HashLetStatement) statementNode() {}
+func (hashLetStatement *HashLetStatement) TokenLiteral() string {
+	return hashLetStatement.Token.Literal
+}
+func (hashLetStatement *HashLetStatement) String() string {
+	var out bytes.Buffer
+
+	names := []string{}
+	for _, name := range hashLetStatement.Names {
+		names = append(names, name.String())
+	}
+
+	out.WriteString(hashLetStatement.TokenLiteral() + " ")
+	out.WriteString("{")
+	out.WriteString(strings.Join(names, ", "))
+	out.WriteString("}")
+	out.WriteString(" = ")
+
+	if hashLetStatement.Value != nil {
+		out.WriteString(hashLetStatement.Value.String())
+	}
+
+	out.WriteString(";")
+
+	return out.String()
+}
+
+type MultipleLetStatement struct {
+	Token token.Token
+	Names []*Identifier
+	Value Expression
+}
+
+func (multipleLetStatement *MultipleLetStatement) statementNode() {}
+func (multipleLetStatement *MultipleLetStatement) TokenLiteral() string {
+	return multipleLetStatement.Token.Literal
+}
+func (multipleLetStatement *MultipleLetStatement) String() string {
+	var out bytes.Buffer
+
+	names := []string{}
+	for _, name := range multipleLetStatement.Names {
+		names = append(names, name.String())
+	}
+
+	out.WriteString(multipleLetStatement.TokenLiteral() + " ")
+	out.WriteString(strings.Join(names, ", "))
+	out.WriteString(" = ")
+
+	if multipleLetStatement.Value != nil {
+		out.WriteString(multipleLetStatement.Value.String())
+	}
+
+	out.WriteString(";")
+
+	return out.String()
+}
+
+type AssignExpression struct {
+	Token token.Token
+	Name  *Identifier
+	Value Expression
+}
+
+func (assignExpression *AssignExpression) expressionNode() {}
+func (assignExpression *AssignExpression) TokenLiteral() string {
+	return assignExpression.Token.Literal
+}
+func (assignExpression *AssignExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(assignExpression.Name.String())
+	out.WriteString(" = ")
+	out.WriteString(assignExpression.Value.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type Identifier struct {
 	Token token.Token
 	Value string
@@ -97,6 +183,168 @@ func (returnStatement *ReturnStatement) String() string {
 	return out.String()
 }
 
+type ThrowStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+func (throwStatement *ThrowStatement) statementNode()       {}
+func (throwStatement *ThrowStatement) TokenLiteral() string { return throwStatement.Token.Literal }
+func (throwStatement *ThrowStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(throwStatement.TokenLiteral() + " ")
+
+	if throwStatement.Value != nil {
+		out.WriteString(throwStatement.Value.String())
+	}
+
+	out.WriteString(";")
+
+	return out.String()
+}
+
+type AssertStatement struct {
+	Token     token.Token
+	Condition Expression
+	Message   Expression
+	Source    string // Condition as parsed, for the failure message once optimizations rewrite it
+}
+
+func (assertStatement *AssertStatement) statementNode()       {}
+func (assertStatement *AssertStatement) TokenLiteral() string { return assertStatement.Token.Literal }
+func (assertStatement *AssertStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(assertStatement.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(assertStatement.Condition.String())
+
+	if assertStatement.Message != nil {
+		out.WriteString(", ")
+		out.WriteString(assertStatement.Message.String())
+	}
+
+	out.WriteString(");")
+
+	return out.String()
+}
+
+type DeferStatement struct {
+	Token      token.Token
+	Expression Expression
+}
+
+func (deferStatement *DeferStatement) statementNode()       {}
+func (deferStatement *DeferStatement) TokenLiteral() string { return deferStatement.Token.Literal }
+func (deferStatement *DeferStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(deferStatement.TokenLiteral() + " ")
+
+	if deferStatement.Expression != nil {
+		out.WriteString(deferStatement.Expression.String())
+	}
+
+	out.WriteString(";")
+
+	return out.String()
+}
+
+type DoWhileExpression struct {
+	Token     token.Token
+	Label     *Identifier
+	Body      *BlockStatement
+	Condition Expression
+}
+
+func (doWhileExpression *DoWhileExpression) expressionNode() {}
+func (doWhileExpression *DoWhileExpression) TokenLiteral() string {
+	return doWhileExpression.Token.Literal
+}
+func (doWhileExpression *DoWhileExpression) String() string {
+	var out bytes.Buffer
+
+	if doWhileExpression.Label != nil {
+		out.WriteString(doWhileExpression.Label.String() + ": ")
+	}
+
+	out.WriteString("do ")
+	out.WriteString(doWhileExpression.Body.String())
+	out.WriteString(" while")
+	out.WriteString(doWhileExpression.Condition.String())
+
+	return out.String()
+}
+
+type WhileExpression struct {
+	Token     token.Token
+	Label     *Identifier
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (whileExpression *WhileExpression) expressionNode() {}
+func (whileExpression *WhileExpression) TokenLiteral() string {
+	return whileExpression.Token.Literal
+}
+func (whileExpression *WhileExpression) String() string {
+	var out bytes.Buffer
+
+	if whileExpression.Label != nil {
+		out.WriteString(whileExpression.Label.String() + ": ")
+	}
+
+	out.WriteString("while")
+	out.WriteString(whileExpression.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(whileExpression.Body.String())
+
+	return out.String()
+}
+
+type BreakStatement struct {
+	Token token.Token
+	Label *Identifier
+	Value Expression
+}
+
+func (breakStatement *BreakStatement) statementNode()       {}
+func (breakStatement *BreakStatement) TokenLiteral() string { return breakStatement.Token.Literal }
+func (breakStatement *BreakStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(breakStatement.TokenLiteral())
+
+	if breakStatement.Label != nil {
+		out.WriteString(" " + breakStatement.Label.String())
+	}
+
+	if breakStatement.Value != nil {
+		out.WriteString(" " + breakStatement.Value.String())
+	}
+
+	out.WriteString(";")
+
+	return out.String()
+}
+
+type ContinueStatement struct {
+	Token token.Token
+	Label *Identifier
+}
+
+func (continueStatement *ContinueStatement) statementNode() {}
+func (continueStatement *ContinueStatement) TokenLiteral() string {
+	return continueStatement.Token.Literal
+}
+func (continueStatement *ContinueStatement) String() string {
+	if continueStatement.Label != nil {
+		return continueStatement.TokenLiteral() + " " + continueStatement.Label.String() + ";"
+	}
+	return continueStatement.TokenLiteral() + ";"
+}
+
 type ExpressionStatement struct {
 	Token      token.Token
 	Expression Expression
@@ -122,6 +370,15 @@ func (integerLiteral *IntegerLiteral) expressionNode()      {}
 func (integerLiteral *IntegerLiteral) TokenLiteral() string { return integerLiteral.Token.Literal }
 func (integerLiteral *IntegerLiteral) String() string       { return integerLiteral.Token.Literal }
 
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (floatLiteral *FloatLiteral) expressionNode()      {}
+func (floatLiteral *FloatLiteral) TokenLiteral() string { return floatLiteral.Token.Literal }
+func (floatLiteral *FloatLiteral) String() string       { return floatLiteral.Token.Literal }
+
 type PrefixExpression struct {
 	Token    token.Token
 	Operator string
@@ -198,6 +455,37 @@ func (ifExpression *IfExpression) String() string {
 	return out.String()
 }
 
+type TryExpression struct {
+	Token     token.Token
+	Block     *BlockStatement
+	Parameter *Identifier
+	Catch     *BlockStatement
+	Finally   *BlockStatement
+}
+
+func (tryExpression *TryExpression) expressionNode()      {}
+func (tryExpression *TryExpression) TokenLiteral() string { return tryExpression.Token.Literal }
+func (tryExpression *TryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try ")
+	out.WriteString(tryExpression.Block.String())
+
+	if tryExpression.Catch != nil {
+		out.WriteString(" catch(")
+		out.WriteString(tryExpression.Parameter.String())
+		out.WriteString(") ")
+		out.WriteString(tryExpression.Catch.String())
+	}
+
+	if tryExpression.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(tryExpression.Finally.String())
+	}
+
+	return out.String()
+}
+
 type BlockStatement struct {
 	Token      token.Token
 	Statements []Statement
@@ -240,6 +528,33 @@ func (functionLiteral *FunctionLiteral) String() string {
 	return out.String()
 }
 
+// MacroLiteral is `macro(x, y) { ... }`, which is bound with a top-level let
+// and expanded before the program is evaluated.
+type MacroLiteral struct {
+	Token      token.Token
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (macroLiteral *MacroLiteral) expressionNode()      {}
+func (macroLiteral *MacroLiteral) TokenLiteral() string { return macroLiteral.Token.Literal }
+func (macroLiteral *MacroLiteral) String() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, parameter := range macroLiteral.Parameters {
+		params = append(params, parameter.String())
+	}
+
+	out.WriteString(macroLiteral.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(")")
+	out.WriteString(macroLiteral.Body.String())
+
+	return out.String()
+}
+
 type CallExpression struct {
 	Token     token.Token
 	Function  Expression
@@ -315,9 +630,32 @@ func (indexExpression *IndexExpression) String() string {
 	return out.String()
 }
 
+type OptionalChainExpression struct {
+	Token    token.Token
+	Left     Expression
+	Property *Identifier
+}
+
+func (optionalChainExpression *OptionalChainExpression) expressionNode() {}
+func (optionalChainExpression *OptionalChainExpression) TokenLiteral() string {
+	return optionalChainExpression.Token.Literal
+}
+func (optionalChainExpression *OptionalChainExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(optionalChainExpression.Left.String())
+	out.WriteString("?.")
+	out.WriteString(optionalChainExpression.Property.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type HashLiteral struct {
 	Token token.Token
 	Pairs map[Expression]Expression
+	Order []Expression // Keys of Pairs in source order
 }
 
 func (hashLiteral *HashLiteral) expressionNode()      {}
@@ -326,8 +664,8 @@ func (hashLiteral *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for key, value := range hashLiteral.Pairs {
-		pairs = append(pairs, key.String()+": "+value.String())
+	for _, key := range hashLiteral.Keys() {
+		pairs = append(pairs, key.String()+": "+hashLiteral.Pairs[key].String())
 	}
 
 	out.WriteString("{")
@@ -336,3 +674,27 @@ func (hashLiteral *HashLiteral) String() string {
 
 	return out.String()
 }
+
+// Keys returns the keys of Pairs in source order. Keys missing from Order,
+// as in literals built by hand rather than by the parser, follow in no
+// particular order.
+func (hashLiteral *HashLiteral) Keys() []Expression {
+	if len(hashLiteral.Order) == len(hashLiteral.Pairs) {
+		return hashLiteral.Order
+	}
+
+	keys := make([]Expression, 0, len(hashLiteral.Pairs))
+	listed := make(map[Expression]bool, len(hashLiteral.Order))
+	for _, key := range hashLiteral.Order {
+		if _, ok := hashLiteral.Pairs[key]; ok && !listed[key] {
+			keys = append(keys, key)
+			listed[key] = true
+		}
+	}
+	for key := range hashLiteral.Pairs {
+		if !listed[key] {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,75 @@
+package ast
+
+import "reflect"
+
+// Copy returns a deep copy of the tree rooted at node, so it can be modified
+// without changing node. Nodes shared within the tree, like the keys of a
+// hash literal's Pairs and Order, stay shared in the copy.
+func Copy(node Node) Node {
+	if node == nil || isNilNode(node) {
+		return node
+	}
+	return deepCopy(reflect.ValueOf(node), make(map[copied]reflect.Value)).Interface().(Node)
+}
+
+// copied identifies a pointer that has been copied already.
+type copied struct {
+	address uintptr
+	typ     reflect.Type
+}
+
+func deepCopy(value reflect.Value, copies map[copied]reflect.Value) reflect.Value {
+	switch value.Kind() {
+	case reflect.Pointer:
+		if value.IsNil() {
+			return value
+		}
+		key := copied{value.Pointer(), value.Type()}
+		if copy, ok := copies[key]; ok {
+			return copy
+		}
+		copy := reflect.New(value.Type().Elem())
+		copies[key] = copy
+		copy.Elem().Set(deepCopy(value.Elem(), copies))
+		return copy
+
+	case reflect.Interface:
+		if value.IsNil() {
+			return value
+		}
+		copy := reflect.New(value.Type()).Elem()
+		copy.Set(deepCopy(value.Elem(), copies))
+		return copy
+
+	case reflect.Struct:
+		copy := reflect.New(value.Type()).Elem()
+		for i := 0; i < value.NumField(); i++ {
+			copy.Field(i).Set(deepCopy(value.Field(i), copies))
+		}
+		return copy
+
+	case reflect.Slice:
+		if value.IsNil() {
+			return value
+		}
+		copy := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		for i := 0; i < value.Len(); i++ {
+			copy.Index(i).Set(deepCopy(value.Index(i), copies))
+		}
+		return copy
+
+	case reflect.Map:
+		if value.IsNil() {
+			return value
+		}
+		copy := reflect.MakeMapWithSize(value.Type(), value.Len())
+		iterator := value.MapRange()
+		for iterator.Next() {
+			copy.SetMapIndex(deepCopy(iterator.Key(), copies), deepCopy(iterator.Value(), copies))
+		}
+		return copy
+
+	default:
+		return value
+	}
+}
@@ -0,0 +1,46 @@
+package ast
+
+import (
+	"monkey/token"
+	"testing"
+)
+
+func TestCopy(t *testing.T) {
+	key := &StringLiteral{Token: token.Token{Type: token.STRING, Literal: "a"}, Value: "a"}
+	original := &Program{Statements: []Statement{
+		&ExpressionStatement{Expression: &FunctionLiteral{
+			Token:      token.Token{Type: token.FUNCTION, Literal: "fn"},
+			Parameters: []*Identifier{{Token: token.Token{Type: token.IDENT, Literal: "x"}, Value: "x"}},
+			Body: &BlockStatement{Statements: []Statement{
+				&ExpressionStatement{Expression: &InfixExpression{
+					Left:     &Identifier{Token: token.Token{Type: token.IDENT, Literal: "x"}, Value: "x"},
+					Operator: "+",
+					Right:    &IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "1"}, Value: 1},
+				}},
+			}},
+		}},
+		&ExpressionStatement{Expression: &HashLiteral{
+			Pairs: map[Expression]Expression{key: &IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "2"}, Value: 2}},
+			Order: []Expression{key},
+		}},
+	}}
+
+	copied := Copy(original).(*Program)
+	if copied.String() != original.String() {
+		t.Fatalf("copy differs. expected=%q, got=%q", original, copied)
+	}
+
+	function := copied.Statements[0].(*ExpressionStatement).Expression.(*FunctionLiteral)
+	function.Body.Statements[0].(*ExpressionStatement).Expression.(*InfixExpression).Right = &IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "5"}, Value: 5}
+	if original.String() == copied.String() {
+		t.Errorf("modifying the copy changed the original. got=%s", original)
+	}
+
+	hash := copied.Statements[1].(*ExpressionStatement).Expression.(*HashLiteral)
+	if _, ok := hash.Pairs[hash.Order[0]]; !ok {
+		t.Errorf("copied hash key isn't shared by Pairs and Order")
+	}
+	if hash.Order[0] == Expression(key) {
+		t.Errorf("hash key wasn't copied")
+	}
+}
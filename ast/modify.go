@@ -0,0 +1,145 @@
+package ast
+
+// ModifierFunc is called by Modify for every node, after the node's children
+// have been modified, and returns the node to put in its place.
+type ModifierFunc func(Node) Node
+
+// Modify walks the tree rooted at node depth-first, replacing each node with
+// what modifier returns for it. Replacements of the wrong kind for their
+// position, such as a statement where an expression is expected, are
+// dropped in favour of the original node.
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+	case *Program:
+		for i, statement := range node.Statements {
+			node.Statements[i] = modifyStatement(statement, modifier)
+		}
+
+	case *BlockStatement:
+		for i, statement := range node.Statements {
+			node.Statements[i] = modifyStatement(statement, modifier)
+		}
+
+	case *ExpressionStatement:
+		node.Expression = modifyExpression(node.Expression, modifier)
+
+	case *LetStatement:
+		node.Value = modifyExpression(node.Value, modifier)
+
+	case *HashLetStatement:
+		node.Value = modifyExpression(node.Value, modifier)
+
+	case *MultipleLetStatement:
+		node.Value = modifyExpression(node.Value, modifier)
+
+	case *AssignExpression:
+		node.Value = modifyExpression(node.Value, modifier)
+
+	case *ReturnStatement:
+		node.ReturnValue = modifyExpression(node.ReturnValue, modifier)
+
+	case *ThrowStatement:
+		node.Value = modifyExpression(node.Value, modifier)
+
+	case *AssertStatement:
+		node.Condition = modifyExpression(node.Condition, modifier)
+		node.Message = modifyExpression(node.Message, modifier)
+
+	case *DeferStatement:
+		node.Expression = modifyExpression(node.Expression, modifier)
+
+	case *BreakStatement:
+		node.Value = modifyExpression(node.Value, modifier)
+
+	case *DoWhileExpression:
+		node.Body = modifyBlock(node.Body, modifier)
+		node.Condition = modifyExpression(node.Condition, modifier)
+
+	case *WhileExpression:
+		node.Condition = modifyExpression(node.Condition, modifier)
+		node.Body = modifyBlock(node.Body, modifier)
+
+	case *PrefixExpression:
+		node.Right = modifyExpression(node.Right, modifier)
+
+	case *InfixExpression:
+		node.Left = modifyExpression(node.Left, modifier)
+		node.Right = modifyExpression(node.Right, modifier)
+
+	case *IfExpression:
+		node.Condition = modifyExpression(node.Condition, modifier)
+		node.Consequence = modifyBlock(node.Consequence, modifier)
+		node.Alternative = modifyBlock(node.Alternative, modifier)
+
+	case *TryExpression:
+		node.Block = modifyBlock(node.Block, modifier)
+		node.Catch = modifyBlock(node.Catch, modifier)
+		node.Finally = modifyBlock(node.Finally, modifier)
+
+	case *FunctionLiteral:
+		node.Body = modifyBlock(node.Body, modifier)
+
+	case *MacroLiteral:
+		node.Body = modifyBlock(node.Body, modifier)
+
+	case *CallExpression:
+		node.Function = modifyExpression(node.Function, modifier)
+		for i, argument := range node.Arguments {
+			node.Arguments[i] = modifyExpression(argument, modifier)
+		}
+
+	case *ArrayLiteral:
+		for i, element := range node.Elements {
+			node.Elements[i] = modifyExpression(element, modifier)
+		}
+
+	case *IndexExpression:
+		node.Left = modifyExpression(node.Left, modifier)
+		node.Index = modifyExpression(node.Index, modifier)
+
+	case *OptionalChainExpression:
+		node.Left = modifyExpression(node.Left, modifier)
+
+	case *HashLiteral:
+		pairs := make(map[Expression]Expression, len(node.Pairs))
+		order := make([]Expression, 0, len(node.Pairs))
+		for _, key := range node.Keys() {
+			modifiedKey := modifyExpression(key, modifier)
+			pairs[modifiedKey] = modifyExpression(node.Pairs[key], modifier)
+			order = append(order, modifiedKey)
+		}
+		node.Pairs, node.Order = pairs, order
+	}
+
+	return modifier(node)
+}
+
+func modifyStatement(statement Statement, modifier ModifierFunc) Statement {
+	if statement == nil {
+		return nil
+	}
+	if modified, ok := Modify(statement, modifier).(Statement); ok {
+		return modified
+	}
+	return statement
+}
+
+func modifyExpression(expression Expression, modifier ModifierFunc) Expression {
+	if expression == nil {
+		return nil
+	}
+	if modified, ok := Modify(expression, modifier).(Expression); ok {
+		return modified
+	}
+	return expression
+}
+
+func modifyBlock(block *BlockStatement, modifier ModifierFunc) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	if modified, ok := Modify(block, modifier).(*BlockStatement); ok {
+		return modified
+	}
+	return block
+}
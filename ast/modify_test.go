@@ -0,0 +1,87 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestModify(t *testing.T) {
+	one := func() Expression { return &IntegerLiteral{Value: 1} }
+	two := func() Expression { return &IntegerLiteral{Value: 2} }
+
+	turnOneIntoTwo := func(node Node) Node {
+		integer, ok := node.(*IntegerLiteral)
+		if !ok || integer.Value != 1 {
+			return node
+		}
+
+		integer.Value = 2
+		return integer
+	}
+
+	tests := []struct {
+		input    Node
+		expected Node
+	}{
+		{one(), two()},
+		{
+			&Program{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			&Program{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+		},
+		{
+			&InfixExpression{Left: one(), Operator: "+", Right: two()},
+			&InfixExpression{Left: two(), Operator: "+", Right: two()},
+		},
+		{
+			&PrefixExpression{Operator: "-", Right: one()},
+			&PrefixExpression{Operator: "-", Right: two()},
+		},
+		{
+			&IndexExpression{Left: one(), Index: one()},
+			&IndexExpression{Left: two(), Index: two()},
+		},
+		{
+			&IfExpression{
+				Condition:   one(),
+				Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+				Alternative: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			},
+			&IfExpression{
+				Condition:   two(),
+				Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+				Alternative: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+			},
+		},
+		{&ReturnStatement{ReturnValue: one()}, &ReturnStatement{ReturnValue: two()}},
+		{&LetStatement{Value: one()}, &LetStatement{Value: two()}},
+		{
+			&FunctionLiteral{Body: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}}},
+			&FunctionLiteral{Body: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}}},
+		},
+		{
+			&CallExpression{Function: one(), Arguments: []Expression{one(), one()}},
+			&CallExpression{Function: two(), Arguments: []Expression{two(), two()}},
+		},
+		{&ArrayLiteral{Elements: []Expression{one(), one()}}, &ArrayLiteral{Elements: []Expression{two(), two()}}},
+		{&ReturnStatement{}, &ReturnStatement{}},
+	}
+
+	for _, test := range tests {
+		modified := Modify(test.input, turnOneIntoTwo)
+
+		if !reflect.DeepEqual(modified, test.expected) {
+			t.Errorf("not equal. got=%#v, want=%#v", modified, test.expected)
+		}
+	}
+
+	hashLiteral := &HashLiteral{Pairs: map[Expression]Expression{one(): one()}}
+	Modify(hashLiteral, turnOneIntoTwo)
+
+	for key, value := range hashLiteral.Pairs {
+		key, _ := key.(*IntegerLiteral)
+		value, _ := value.(*IntegerLiteral)
+		if key.Value != 2 || value.Value != 2 {
+			t.Errorf("hash pair was not modified. got=%d: %d", key.Value, value.Value)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package ast
+
+// Inspect walks the tree rooted at node depth-first, calling fn for every
+// node before its children. The children of a node are skipped when fn
+// returns false for it. Identifiers naming bindings, parameters, labels and
+// properties are visited along with the expressions.
+func Inspect(node Node, fn func(Node) bool) {
+	if node == nil || isNilNode(node) || !fn(node) {
+		return
+	}
+
+	for _, child := range children(node) {
+		Inspect(child, fn)
+	}
+}
+
+// children returns the child nodes of node that are present, in source
+// order.
+func children(node Node) []Node {
+	var nodes []Node
+	add := func(children ...Node) {
+		for _, child := range children {
+			if child != nil {
+				nodes = append(nodes, child)
+			}
+		}
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		for _, statement := range node.Statements {
+			add(statement)
+		}
+	case *BlockStatement:
+		for _, statement := range node.Statements {
+			add(statement)
+		}
+	case *ExpressionStatement:
+		add(node.Expression)
+	case *LetStatement:
+		add(node.Name, node.Value)
+	case *HashLetStatement:
+		for _, name := range node.Names {
+			add(name)
+		}
+		add(node.Value)
+	case *MultipleLetStatement:
+		for _, name := range node.Names {
+			add(name)
+		}
+		add(node.Value)
+	case *AssignExpression:
+		add(node.Name, node.Value)
+	case *ReturnStatement:
+		add(node.ReturnValue)
+	case *ThrowStatement:
+		add(node.Value)
+	case *AssertStatement:
+		add(node.Condition, node.Message)
+	case *DeferStatement:
+		add(node.Expression)
+	case *BreakStatement:
+		add(node.Label, node.Value)
+	case *ContinueStatement:
+		add(node.Label)
+	case *DoWhileExpression:
+		add(node.Label, node.Body, node.Condition)
+	case *WhileExpression:
+		add(node.Label, node.Condition, node.Body)
+	case *PrefixExpression:
+		add(node.Right)
+	case *InfixExpression:
+		add(node.Left, node.Right)
+	case *IfExpression:
+		add(node.Condition, node.Consequence, node.Alternative)
+	case *TryExpression:
+		add(node.Block, node.Parameter, node.Catch, node.Finally)
+	case *FunctionLiteral:
+		for _, parameter := range node.Parameters {
+			add(parameter)
+		}
+		add(node.Body)
+	case *MacroLiteral:
+		for _, parameter := range node.Parameters {
+			add(parameter)
+		}
+		add(node.Body)
+	case *CallExpression:
+		add(node.Function)
+		for _, argument := range node.Arguments {
+			add(argument)
+		}
+	case *ArrayLiteral:
+		for _, element := range node.Elements {
+			add(element)
+		}
+	case *IndexExpression:
+		add(node.Left, node.Index)
+	case *OptionalChainExpression:
+		add(node.Left, node.Property)
+	case *HashLiteral:
+		for _, key := range node.Keys() {
+			add(key, node.Pairs[key])
+		}
+	}
+
+	return nodes
+}
+
+// isNilNode reports whether node is a typed nil pointer, as left behind by
+// optional parts like a missing else branch.
+func isNilNode(node Node) bool {
+	switch node := node.(type) {
+	case *Identifier:
+		return node == nil
+	case *BlockStatement:
+		return node == nil
+	default:
+		return false
+	}
+}
@@ -0,0 +1,53 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	identifier := func(name string) *Identifier { return &Identifier{Value: name} }
+
+	program := &Program{Statements: []Statement{
+		&LetStatement{Name: identifier("f"), Value: &FunctionLiteral{
+			Parameters: []*Identifier{identifier("x")},
+			Body: &BlockStatement{Statements: []Statement{
+				&ExpressionStatement{Expression: &IfExpression{
+					Condition:   identifier("x"),
+					Consequence: &BlockStatement{Statements: []Statement{&ReturnStatement{ReturnValue: identifier("y")}}},
+				}},
+			}},
+		}},
+		&ExpressionStatement{Expression: &CallExpression{
+			Function:  identifier("f"),
+			Arguments: []Expression{&InfixExpression{Left: identifier("a"), Operator: "+", Right: &IntegerLiteral{Value: 1}}},
+		}},
+	}}
+
+	var names []string
+	Inspect(program, func(node Node) bool {
+		if identifier, ok := node.(*Identifier); ok {
+			names = append(names, identifier.Value)
+		}
+		return true
+	})
+
+	expected := []string{"f", "x", "x", "y", "f", "a"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("wrong identifiers visited. expected=%v, got=%v", expected, names)
+	}
+
+	names = nil
+	Inspect(program, func(node Node) bool {
+		if identifier, ok := node.(*Identifier); ok {
+			names = append(names, identifier.Value)
+		}
+		_, isFunction := node.(*FunctionLiteral)
+		return !isFunction
+	})
+
+	expected = []string{"f", "f", "a"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("wrong identifiers visited when skipping functions. expected=%v, got=%v", expected, names)
+	}
+}
@@ -0,0 +1,381 @@
+// Package checker finds mistakes in programs without running them.
+package checker
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/diagnostic"
+	"monkey/evaluator"
+	"monkey/token"
+	"sort"
+	"strings"
+)
+
+// Options turn on the warnings Diagnose doesn't report by default.
+type Options struct {
+	Shadowing bool // Warn about bindings that hide an outer binding or builtin
+}
+
+// Diagnose reports the mistakes in program as diagnostics, ordered by
+// position, rather than stopping at the first one as evaluation does.
+//
+// Identifiers that refer to a binding that is never defined are errors with
+// the code "undefined". Bindings are visible throughout the function they're
+// defined in, so a use before the definition isn't reported, and functions
+// that call eval with a second argument are skipped since they can define
+// anything.
+//
+// Parameters and local variables of functions that are never read are
+// warnings with the codes "unused-parameter" and "unused-variable", and
+// bindings that hide another are "shadowing" when options turn it on. Names
+// starting with an underscore are left out, so they can mark values that are
+// deliberately ignored.
+func Diagnose(program *ast.Program, options Options) []diagnostic.Diagnostic {
+	diagnostics, _ := analyze(program, options)
+	return diagnostics
+}
+
+// analyze checks program, returning what it found and the scope of the
+// builtins, which holds the program's.
+func analyze(program *ast.Program, options Options) ([]diagnostic.Diagnostic, *scope) {
+	globals := newScope(nil)
+	for _, name := range evaluator.Globals() {
+		globals.bindings[name] = &binding{used: true}
+	}
+
+	checker := &checker{options: options, diagnostics: []diagnostic.Diagnostic{}}
+	checker.checkFunction(program, nil, globals)
+	checker.checkUnused(globals)
+
+	sort.SliceStable(checker.diagnostics, func(i, j int) bool {
+		a, b := checker.diagnostics[i].Span.Start, checker.diagnostics[j].Span.Start
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	return checker.diagnostics, globals
+}
+
+// A binding is a name defined in a scope.
+type binding struct {
+	definition token.Token
+	parameter  bool
+	mutable    bool // Whether the binding is defined with var
+	used       bool // Whether the binding is ever read
+}
+
+type scope struct {
+	bindings map[string]*binding
+	local    bool // Whether the scope is a function's rather than the program's
+	dynamic  bool // Whether eval can define names in the scope
+	outer    *scope
+	inner    []*scope
+}
+
+func newScope(outer *scope) *scope {
+	s := &scope{bindings: make(map[string]*binding), outer: outer}
+	if outer != nil {
+		outer.inner = append(outer.inner, s)
+	}
+	return s
+}
+
+func (s *scope) define(name *ast.Identifier, parameter bool) {
+	if _, ok := s.bindings[name.Value]; !ok {
+		s.bindings[name.Value] = &binding{definition: name.Token, parameter: parameter}
+	}
+}
+
+// resolve reports whether name is or may be defined in s or the scopes
+// enclosing it, marking the binding as read when read is set.
+func (s *scope) resolve(name string, read bool) bool {
+	for ; s != nil; s = s.outer {
+		if binding, ok := s.bindings[name]; ok {
+			binding.used = binding.used || read
+			return true
+		}
+		if s.dynamic {
+			return true
+		}
+	}
+	return false
+}
+
+// suggest returns the name visible from s that is closest to the undefined
+// name, for catching typos. Names more than a third of name's length away
+// aren't considered close.
+func (s *scope) suggest(name string) (string, bool) {
+	best, bestDistance := "", len(name)/3+1
+	for ; s != nil; s = s.outer {
+		for candidate := range s.bindings {
+			distance := editDistance(name, candidate)
+			if distance < bestDistance || distance == bestDistance && best != "" && candidate < best {
+				best, bestDistance = candidate, distance
+			}
+		}
+	}
+	return best, best != ""
+}
+
+// editDistance returns the Levenshtein distance between a and b, counting
+// runes.
+func editDistance(a, b string) int {
+	source, target := []rune(a), []rune(b)
+	previous := make([]int, len(target)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := range source {
+		current := make([]int, len(target)+1)
+		current[0] = i + 1
+		for j := range target {
+			cost := 1
+			if source[i] == target[j] {
+				cost = 0
+			}
+			current[j+1] = min(previous[j+1]+1, current[j]+1, previous[j]+cost)
+		}
+		previous = current
+	}
+
+	return previous[len(target)]
+}
+
+type checker struct {
+	options     Options
+	diagnostics []diagnostic.Diagnostic
+}
+
+// report adds a diagnostic about the name defined or used at tok.
+func (checker *checker) report(severity diagnostic.Severity, code string, tok token.Token, message string, notes ...string) {
+	checker.diagnostics = append(checker.diagnostics, diagnostic.Diagnostic{
+		Code:     code,
+		Severity: severity,
+		Message:  message,
+		Span: diagnostic.Span{
+			Start: diagnostic.Position{Line: tok.Line, Column: tok.Column},
+			End:   diagnostic.Position{Line: tok.Line, Column: tok.Column + len(tok.Literal)},
+		},
+		Notes: notes,
+	})
+}
+
+// checkFunction checks body, the body of a function taking parameters or
+// the whole program, in a new scope inside outer.
+func (checker *checker) checkFunction(body ast.Node, parameters []*ast.Identifier, outer *scope) {
+	scope := newScope(outer)
+	scope.local = outer.outer != nil
+	for _, parameter := range parameters {
+		scope.define(parameter, true)
+	}
+	declare(body, scope)
+
+	if checker.options.Shadowing {
+		checker.checkShadowing(scope)
+	}
+	checker.check(body, scope)
+}
+
+// checkShadowing warns about the bindings of s that hide one of an enclosing
+// scope.
+func (checker *checker) checkShadowing(s *scope) {
+	for name, inner := range s.bindings {
+		if strings.HasPrefix(name, "_") {
+			continue
+		}
+
+		for outer := s.outer; outer != nil; outer = outer.outer {
+			shadowed, ok := outer.bindings[name]
+			if !ok {
+				continue
+			}
+
+			message := fmt.Sprintf("%s shadows the builtin %s", name, name)
+			if outer.outer != nil {
+				message = fmt.Sprintf("%s shadows the binding defined at %d:%d",
+					name, shadowed.definition.Line, shadowed.definition.Column)
+			}
+			checker.report(diagnostic.Warning, "shadowing", inner.definition, message,
+				"prefix the name with _ to leave it out of this warning")
+			break
+		}
+	}
+}
+
+// declare adds the names bound in body to scope, leaving out those bound in
+// nested functions.
+func declare(body ast.Node, scope *scope) {
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FunctionLiteral, *ast.MacroLiteral:
+			return false
+		case *ast.LetStatement:
+			scope.define(node.Name, false)
+			if node.Token.Type == token.VAR {
+				scope.bindings[node.Name.Value].mutable = true
+			}
+		case *ast.HashLetStatement:
+			for _, name := range node.Names {
+				scope.define(name, false)
+			}
+		case *ast.MultipleLetStatement:
+			for _, name := range node.Names {
+				scope.define(name, false)
+			}
+		case *ast.CallExpression:
+			if function, ok := node.Function.(*ast.Identifier); ok && function.Value == "eval" && len(node.Arguments) == 2 {
+				scope.dynamic = true
+			}
+		}
+		return true
+	})
+}
+
+// check reports the undefined identifiers node refers to, skipping the ones
+// that name bindings, labels and properties.
+func (checker *checker) check(node ast.Node, scope *scope) {
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FunctionLiteral:
+			checker.checkFunction(node.Body, node.Parameters, scope)
+			return false
+
+		case *ast.MacroLiteral:
+			checker.checkFunction(node.Body, node.Parameters, scope)
+			return false
+
+		case *ast.CallExpression:
+			if calls(node, "quote") {
+				checker.checkUnquoted(node, scope)
+				return false
+			}
+
+		case *ast.LetStatement:
+			checker.check(node.Value, scope)
+			return false
+
+		case *ast.HashLetStatement:
+			checker.check(node.Value, scope)
+			return false
+
+		case *ast.MultipleLetStatement:
+			checker.check(node.Value, scope)
+			return false
+
+		case *ast.AssignExpression:
+			checker.reference(node.Name, scope, false)
+			checker.check(node.Value, scope)
+			return false
+
+		case *ast.TryExpression:
+			checker.check(node.Block, scope)
+			checker.checkCatch(node, scope)
+			checker.check(node.Finally, scope)
+			return false
+
+		case *ast.OptionalChainExpression:
+			checker.check(node.Left, scope)
+			return false
+
+		case *ast.BreakStatement:
+			checker.check(node.Value, scope)
+			return false
+
+		case *ast.ContinueStatement:
+			return false
+
+		case *ast.DoWhileExpression:
+			checker.check(node.Body, scope)
+			checker.check(node.Condition, scope)
+			return false
+
+		case *ast.WhileExpression:
+			checker.check(node.Condition, scope)
+			checker.check(node.Body, scope)
+			return false
+
+		case *ast.Identifier:
+			checker.reference(node, scope, true)
+		}
+
+		return true
+	})
+}
+
+// checkCatch checks the catch clause of try in a new scope inside outer
+// holding just its parameter, which is all evaluation binds there. The other
+// names bound in the clause were declared in outer.
+func (checker *checker) checkCatch(try *ast.TryExpression, outer *scope) {
+	if try.Catch == nil {
+		return
+	}
+
+	scope := newScope(outer)
+	scope.local, scope.dynamic = outer.local, outer.dynamic
+	if try.Parameter != nil {
+		scope.define(try.Parameter, false)
+	}
+
+	if checker.options.Shadowing {
+		checker.checkShadowing(scope)
+	}
+	checker.check(try.Catch, scope)
+}
+
+// calls reports whether call is of the function named name.
+func calls(call *ast.CallExpression, name string) bool {
+	function, ok := call.Function.(*ast.Identifier)
+	return ok && function.Value == name
+}
+
+// checkUnquoted checks the arguments of the calls of unquote within quoted,
+// a call of quote, since the rest of it is code rather than references.
+func (checker *checker) checkUnquoted(quoted *ast.CallExpression, scope *scope) {
+	for _, argument := range quoted.Arguments {
+		ast.Inspect(argument, func(node ast.Node) bool {
+			if call, ok := node.(*ast.CallExpression); ok && calls(call, "unquote") {
+				for _, argument := range call.Arguments {
+					checker.check(argument, scope)
+				}
+				return false
+			}
+			return true
+		})
+	}
+}
+
+func (checker *checker) reference(identifier *ast.Identifier, scope *scope, read bool) {
+	if scope.resolve(identifier.Value, read) {
+		return
+	}
+
+	message := fmt.Sprintf("identifier not found: %s", identifier.Value)
+	if suggestion, ok := scope.suggest(identifier.Value); ok {
+		message += fmt.Sprintf(", did you mean %s?", suggestion)
+	}
+	checker.report(diagnostic.Error, "undefined", identifier.Token, message)
+}
+
+// checkUnused warns about the bindings of local scopes within s that are
+// never read, and reports whether eval may read names from s.
+func (checker *checker) checkUnused(s *scope) bool {
+	dynamic := s.dynamic
+	for _, inner := range s.inner {
+		dynamic = checker.checkUnused(inner) || dynamic
+	}
+	if !s.local || dynamic {
+		return dynamic
+	}
+
+	for name, binding := range s.bindings {
+		if binding.used || strings.HasPrefix(name, "_") {
+			continue
+		}
+
+		kind, code := "local variable", "unused-variable"
+		if binding.parameter {
+			kind, code = "parameter", "unused-parameter"
+		}
+		checker.report(diagnostic.Warning, code, binding.definition, fmt.Sprintf("unused %s %s", kind, name))
+	}
+	return false
+}
@@ -0,0 +1,295 @@
+package checker
+
+import (
+	"monkey/ast"
+	"monkey/diagnostic"
+	"monkey/lexer"
+	"monkey/parser"
+	"reflect"
+	"testing"
+)
+
+// A finding is the message and position of a diagnostic, which is all most
+// of the tests compare.
+type finding struct {
+	Message string
+	Line    int
+	Column  int
+}
+
+// findings returns what Diagnose reports for program with the given severity.
+func findings(program *ast.Program, options Options, severity diagnostic.Severity) []finding {
+	found := []finding{}
+	for _, d := range Diagnose(program, options) {
+		if d.Severity == severity {
+			found = append(found, finding{Message: d.Message, Line: d.Span.Start.Line, Column: d.Span.Start.Column})
+		}
+	}
+	return found
+}
+
+func TestCheckUndefinedIdentifiers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []finding
+	}{
+		{"let x = 1; x + len([])", []finding{}},
+		{"foo + bar", []finding{
+			{Message: "identifier not found: foo", Line: 1, Column: 1},
+			{Message: "identifier not found: bar", Line: 1, Column: 7},
+		}},
+		{"let f = fn(a) { a + b };\nf(c)", []finding{
+			{Message: "identifier not found: b", Line: 1, Column: 21},
+			{Message: "identifier not found: c", Line: 2, Column: 3},
+		}},
+		{"let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } };\nlet odd = fn(n) { even(n - 1) };", []finding{}},
+		{"let f = fn() { let y = 1; }; y", []finding{
+			{Message: "identifier not found: y", Line: 1, Column: 30},
+		}},
+		{"let {a, b} = {}; let c, d = [1, 2]; a + b + c + d", []finding{}},
+		{`try { throw "x" } catch (e) { e }`, []finding{}},
+		{`try { throw "x" } catch (e) { let y = e; }; e + y`, []finding{
+			{Message: "identifier not found: e", Line: 1, Column: 45},
+		}},
+		{"let h = {}; h?.missing", []finding{}},
+		{"outer: do { break outer; } while (false)", []finding{}},
+		{"var n = 0; n = n + 1; m = 2", []finding{
+			{Message: "identifier not found: m", Line: 1, Column: 23},
+		}},
+		{"|x| x * k", []finding{
+			{Message: "identifier not found: k", Line: 1, Column: 9},
+		}},
+		{`eval("let z = 1;", true); z`, []finding{}},
+		{`eval("let z = 1;"); z`, []finding{
+			{Message: "identifier not found: z", Line: 1, Column: 21},
+		}},
+		{"puts(PI, INT, rand())", []finding{}},
+		{"quote(foo + unquote(bar))", []finding{
+			{Message: "identifier not found: bar", Line: 1, Column: 21},
+		}},
+		{"let m = macro(x) { quote(unquote(x) + y) }; m(1)", []finding{}},
+	}
+
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.input))
+		program := p.ParseProgram()
+		if len(p.Diagnostics()) != 0 {
+			t.Fatalf("parser errors for %q: %v", test.input, p.Diagnostics())
+		}
+
+		errors := findings(program, Options{}, diagnostic.Error)
+		if !reflect.DeepEqual(errors, test.expected) {
+			t.Errorf("wrong errors for %q.\nexpected=%+v\ngot=%+v", test.input, test.expected, errors)
+		}
+	}
+}
+
+func TestCheckSuggestions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let length = 1; lenght", "identifier not found: lenght, did you mean length?"},
+		{`uper("a")`, "identifier not found: uper, did you mean upper?"},
+		{"let f = fn(counter) { fn() { countr } };", "identifier not found: countr, did you mean counter?"},
+		{"let ab = 1; abc", "identifier not found: abc, did you mean ab?"},
+		{"zzzzzz", "identifier not found: zzzzzz"},
+		{"let y = 1; x", "identifier not found: x"},
+	}
+
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.input))
+		program := p.ParseProgram()
+		if len(p.Diagnostics()) != 0 {
+			t.Fatalf("parser errors for %q: %v", test.input, p.Diagnostics())
+		}
+
+		errors := findings(program, Options{}, diagnostic.Error)
+		if len(errors) != 1 {
+			t.Errorf("wrong number of errors for %q. got=%+v", test.input, errors)
+			continue
+		}
+		if errors[0].Message != test.expected {
+			t.Errorf("wrong message for %q. expected=%q, got=%q", test.input, test.expected, errors[0].Message)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"lenght", "length", 2},
+		{"héllo", "hallo", 1},
+	}
+
+	for _, test := range tests {
+		if distance := editDistance(test.a, test.b); distance != test.expected {
+			t.Errorf("editDistance(%q, %q) wrong. expected=%d, got=%d", test.a, test.b, test.expected, distance)
+		}
+	}
+}
+
+func TestLintUnusedBindings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []finding
+	}{
+		{"let x = 1; let f = fn(a) { a }; f(x)", []finding{}},
+		{"let unused = 1;", []finding{}},
+		{"let f = fn(a, b) { let c = a; 1 };", []finding{
+			{Message: "unused parameter b", Line: 1, Column: 15},
+			{Message: "unused local variable c", Line: 1, Column: 24},
+		}},
+		{"let f = fn(_ignored) { let _skip = 1; 2 };", []finding{}},
+		{"let f = fn() { var n = 0; n = 1; };", []finding{
+			{Message: "unused local variable n", Line: 1, Column: 20},
+		}},
+		{"let f = fn(n) { fn() { n } };", []finding{}},
+		{"let f = fn() { let g = fn() { g() }; };", []finding{}},
+		{`let f = fn() { try { 1 } catch (e) { 2 } };`, []finding{
+			{Message: "unused local variable e", Line: 1, Column: 33},
+		}},
+		{`let f = fn(x) { fn() { eval("x", true) } };`, []finding{}},
+		{`let f = fn() { let {a, b} = {}; a };`, []finding{
+			{Message: "unused local variable b", Line: 1, Column: 24},
+		}},
+	}
+
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.input))
+		program := p.ParseProgram()
+		if len(p.Diagnostics()) != 0 {
+			t.Fatalf("parser errors for %q: %v", test.input, p.Diagnostics())
+		}
+
+		warnings := findings(program, Options{}, diagnostic.Warning)
+		if !reflect.DeepEqual(warnings, test.expected) {
+			t.Errorf("wrong warnings for %q.\nexpected=%+v\ngot=%+v", test.input, test.expected, warnings)
+		}
+	}
+}
+
+func TestLintShadowing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []finding
+	}{
+		{"let x = 1; let f = fn(y) { x + y }; f(x)", []finding{}},
+		{"let len = fn(x) { x }; len(1)", []finding{
+			{Message: "len shadows the builtin len", Line: 1, Column: 5},
+		}},
+		{"let x = 1;\nlet f = fn(x) { x };\nf(x)", []finding{
+			{Message: "x shadows the binding defined at 1:5", Line: 2, Column: 12},
+		}},
+		{"let f = fn(a) { let g = fn() { let a = 2; a }; g() + a };\nf(1)", []finding{
+			{Message: "a shadows the binding defined at 1:12", Line: 1, Column: 36},
+		}},
+		{"let f = fn(puts) { puts };\nf(1)", []finding{
+			{Message: "puts shadows the builtin puts", Line: 1, Column: 12},
+		}},
+		{"let _ = 1; let f = fn(_) { 2 }; f(1)", []finding{}},
+	}
+
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.input))
+		program := p.ParseProgram()
+		if len(p.Diagnostics()) != 0 {
+			t.Fatalf("parser errors for %q: %v", test.input, p.Diagnostics())
+		}
+
+		warnings := findings(program, Options{Shadowing: true}, diagnostic.Warning)
+		if !reflect.DeepEqual(warnings, test.expected) {
+			t.Errorf("wrong warnings for %q.\nexpected=%+v\ngot=%+v", test.input, test.expected, warnings)
+		}
+	}
+
+	program := parser.New(lexer.New("let len = 1; len")).ParseProgram()
+	if warnings := findings(program, Options{}, diagnostic.Warning); len(warnings) != 0 {
+		t.Errorf("shadowing reported without being turned on. got=%+v", warnings)
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	input := "let f = fn(count) { let len = 1; 2 };\nf(cont)"
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Diagnostics())
+	}
+
+	expected := []diagnostic.Diagnostic{
+		{
+			Code: "unused-parameter", Severity: diagnostic.Warning, Message: "unused parameter count",
+			Span: diagnostic.Span{Start: diagnostic.Position{Line: 1, Column: 12}, End: diagnostic.Position{Line: 1, Column: 17}},
+		},
+		{
+			Code: "shadowing", Severity: diagnostic.Warning, Message: "len shadows the builtin len",
+			Span:  diagnostic.Span{Start: diagnostic.Position{Line: 1, Column: 25}, End: diagnostic.Position{Line: 1, Column: 28}},
+			Notes: []string{"prefix the name with _ to leave it out of this warning"},
+		},
+		{
+			Code: "unused-variable", Severity: diagnostic.Warning, Message: "unused local variable len",
+			Span: diagnostic.Span{Start: diagnostic.Position{Line: 1, Column: 25}, End: diagnostic.Position{Line: 1, Column: 28}},
+		},
+		{
+			Code: "undefined", Severity: diagnostic.Error, Message: "identifier not found: cont",
+			Span: diagnostic.Span{Start: diagnostic.Position{Line: 2, Column: 3}, End: diagnostic.Position{Line: 2, Column: 7}},
+		},
+	}
+
+	diagnostics := Diagnose(program, Options{Shadowing: true})
+	if !reflect.DeepEqual(diagnostics, expected) {
+		t.Errorf("wrong diagnostics.\nexpected=%+v\ngot=%+v", expected, diagnostics)
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	input := "let f = fn(x) { var total = x; total };\nvar count = f(1);"
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Diagnostics())
+	}
+
+	builtins := Symbols(program)
+	if len(builtins.Inner) != 1 {
+		t.Fatalf("builtins don't hold just the program's scope. got=%d", len(builtins.Inner))
+	}
+	if symbol, ok := builtins.Lookup("puts"); !ok || symbol.Kind != Builtin {
+		t.Errorf("puts isn't a builtin. got=%+v", symbol)
+	}
+
+	globals := builtins.Inner[0]
+	expected := []Symbol{
+		{Name: "f", Kind: Global, Used: true, Line: 1, Column: 5},
+		{Name: "count", Kind: Global, Mutable: true, Line: 2, Column: 5},
+	}
+	if !reflect.DeepEqual(globals.Symbols, expected) {
+		t.Errorf("wrong globals.\nexpected=%+v\ngot=%+v", expected, globals.Symbols)
+	}
+
+	if len(globals.Inner) != 1 {
+		t.Fatalf("wrong number of function scopes. got=%d", len(globals.Inner))
+	}
+	function := globals.Inner[0]
+	expected = []Symbol{
+		{Name: "x", Kind: Parameter, Used: true, Line: 1, Column: 12},
+		{Name: "total", Kind: Local, Mutable: true, Used: true, Line: 1, Column: 21},
+	}
+	if !reflect.DeepEqual(function.Symbols, expected) {
+		t.Errorf("wrong locals.\nexpected=%+v\ngot=%+v", expected, function.Symbols)
+	}
+
+	if symbol, ok := function.Lookup("count"); !ok || symbol.Kind != Global {
+		t.Errorf("count isn't visible from the function. got=%+v", symbol)
+	}
+	visible := function.Visible()
+	if len(visible) != len(builtins.Symbols)+4 || visible[0].Name > visible[len(visible)-1].Name {
+		t.Errorf("wrong visible symbols. got=%+v", visible)
+	}
+}
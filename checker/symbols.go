@@ -0,0 +1,122 @@
+package checker
+
+import (
+	"monkey/ast"
+	"sort"
+)
+
+// A Kind is what defines a Symbol.
+type Kind int
+
+const (
+	Builtin   Kind = iota // Provided by the interpreter
+	Global                // Bound at the top of the program
+	Local                 // Bound in a function
+	Parameter             // A function's parameter
+)
+
+func (kind Kind) String() string {
+	switch kind {
+	case Builtin:
+		return "builtin"
+	case Global:
+		return "global"
+	case Local:
+		return "local"
+	default:
+		return "parameter"
+	}
+}
+
+// A Symbol is a name defined in a Scope, along with the position of the
+// identifier defining it, which is zero for builtins.
+type Symbol struct {
+	Name    string
+	Kind    Kind
+	Mutable bool // Whether it's bound with var
+	Used    bool // Whether it's ever read
+	Line    int
+	Column  int
+}
+
+// A Scope is the symbols defined in the builtins, the program or a function,
+// with the scopes of the functions nested in it in the order they appear.
+type Scope struct {
+	Symbols []Symbol // Ordered by position, builtins by name
+	Dynamic bool     // Whether eval can define names in the scope
+	Outer   *Scope
+	Inner   []*Scope
+}
+
+// Symbols returns the scope of the builtins, whose only inner scope is the
+// program's, for tools that need to know which names are defined where,
+// like editors completing names.
+func Symbols(program *ast.Program) *Scope {
+	_, globals := analyze(program, Options{})
+	return snapshot(globals, nil)
+}
+
+func snapshot(s *scope, outer *Scope) *Scope {
+	snapped := &Scope{Dynamic: s.dynamic, Outer: outer}
+	for name, binding := range s.bindings {
+		symbol := Symbol{
+			Name:    name,
+			Kind:    Local,
+			Mutable: binding.mutable,
+			Used:    binding.used,
+			Line:    binding.definition.Line,
+			Column:  binding.definition.Column,
+		}
+		switch {
+		case s.outer == nil:
+			symbol.Kind = Builtin
+		case binding.parameter:
+			symbol.Kind = Parameter
+		case !s.local:
+			symbol.Kind = Global
+		}
+		snapped.Symbols = append(snapped.Symbols, symbol)
+	}
+	sort.Slice(snapped.Symbols, func(i, j int) bool {
+		a, b := snapped.Symbols[i], snapped.Symbols[j]
+		if a.Line != b.Line || a.Column != b.Column {
+			return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+		}
+		return a.Name < b.Name
+	})
+
+	for _, inner := range s.inner {
+		snapped.Inner = append(snapped.Inner, snapshot(inner, snapped))
+	}
+	return snapped
+}
+
+// Lookup returns the symbol name refers to in scope, looking through the
+// enclosing scopes.
+func (scope *Scope) Lookup(name string) (Symbol, bool) {
+	for ; scope != nil; scope = scope.Outer {
+		for _, symbol := range scope.Symbols {
+			if symbol.Name == name {
+				return symbol, true
+			}
+		}
+	}
+	return Symbol{}, false
+}
+
+// Visible returns the symbols that can be referred to from scope, those of
+// inner scopes hiding the ones they shadow, ordered by name.
+func (scope *Scope) Visible() []Symbol {
+	seen := make(map[string]bool)
+	visible := []Symbol{}
+	for ; scope != nil; scope = scope.Outer {
+		for _, symbol := range scope.Symbols {
+			if !seen[symbol.Name] {
+				seen[symbol.Name] = true
+				visible = append(visible, symbol)
+			}
+		}
+	}
+	sort.Slice(visible, func(i, j int) bool { return visible[i].Name < visible[j].Name })
+	return visible
+}
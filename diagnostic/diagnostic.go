@@ -0,0 +1,125 @@
+// Package diagnostic describes errors found in Monkey source and renders
+// them for people and tools to read.
+package diagnostic
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Severity is how serious a diagnostic is.
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+)
+
+func (severity Severity) String() string {
+	if severity == Warning {
+		return "warning"
+	}
+	return "error"
+}
+
+// MarshalText encodes severity as its name, so JSON shows "error" rather
+// than 0.
+func (severity Severity) MarshalText() ([]byte, error) {
+	return []byte(severity.String()), nil
+}
+
+// A Position is a 1-based line and column in source, columns counting
+// bytes. Line is 0 when the position isn't known.
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// A Span is the range of source a diagnostic is about. End is just past the
+// last byte, and equal to Start when only the start is known.
+type Span struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// A Diagnostic is an error or warning about source, with a Code naming the
+// kind of problem that tools can rely on, unlike Message.
+type Diagnostic struct {
+	Code     string   `json:"code"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	File     string   `json:"file,omitempty"`
+	Span     Span     `json:"span"`
+	Notes    []string `json:"notes,omitempty"`
+}
+
+// Text renders diagnostic for people with Render, showing the offending line
+// of source, and its notes below that.
+func (diagnostic Diagnostic) Text(source string) string {
+	message := diagnostic.Message
+	if diagnostic.Severity == Warning {
+		message = "warning: " + message
+	}
+
+	var out strings.Builder
+	out.WriteString(Render(diagnostic.File, source, diagnostic.Span.Start.Line, diagnostic.Span.Start.Column, message))
+	for _, note := range diagnostic.Notes {
+		out.WriteString("    note: " + note + "\n")
+	}
+	return out.String()
+}
+
+// JSON encodes diagnostics as a JSON array for tools to read.
+func JSON(diagnostics []Diagnostic) ([]byte, error) {
+	if diagnostics == nil {
+		diagnostics = []Diagnostic{}
+	}
+	return json.Marshal(diagnostics)
+}
+
+// Render formats message as reported at line and column of source, where
+// file names the source and may be empty. When line is within source, the
+// offending line is shown below the message with a caret under the column.
+func Render(file, source string, line, column int, message string) string {
+	var out strings.Builder
+
+	switch {
+	case file != "" && line > 0:
+		fmt.Fprintf(&out, "%s:%d:%d: ", file, line, column)
+	case file != "":
+		fmt.Fprintf(&out, "%s: ", file)
+	case line > 0:
+		fmt.Fprintf(&out, "%d:%d: ", line, column)
+	}
+	out.WriteString(message + "\n")
+
+	lines := strings.Split(source, "\n")
+	if line < 1 || line > len(lines) {
+		return out.String()
+	}
+
+	text := strings.TrimRight(lines[line-1], "\r")
+	out.WriteString("    " + text + "\n")
+	out.WriteString("    " + caretIndent(text, column) + "^\n")
+
+	return out.String()
+}
+
+// caretIndent returns the whitespace that lines a caret up with column of
+// text, keeping tabs so the caret is aligned however wide they're shown.
+func caretIndent(text string, column int) string {
+	if column > len(text)+1 {
+		column = len(text) + 1
+	}
+
+	var indent strings.Builder
+	for _, r := range text[:max(column-1, 0)] {
+		if r == '\t' {
+			indent.WriteRune('\t')
+		} else {
+			indent.WriteRune(' ')
+		}
+	}
+	return indent.String()
+}
@@ -0,0 +1,77 @@
+package diagnostic
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		file     string
+		source   string
+		line     int
+		column   int
+		message  string
+		expected string
+	}{
+		{
+			"main.monkey", "let x = 1;\nlet y = ;", 2, 9, "no prefix parse function for ; found",
+			"main.monkey:2:9: no prefix parse function for ; found\n    let y = ;\n            ^\n",
+		},
+		{
+			"", "\tx + true", 1, 4, "type mismatch",
+			"1:4: type mismatch\n    \tx + true\n    \t  ^\n",
+		},
+		{
+			"", `"héllo" + 1`, 1, 10, "type mismatch",
+			"1:10: type mismatch\n    \"héllo\" + 1\n            ^\n",
+		},
+		{"main.monkey", "x", 0, 0, "boom", "main.monkey: boom\n"},
+		{"", "x", 3, 1, "boom", "3:1: boom\n"},
+		{"", "x\r\ny", 1, 2, "eof", "1:2: eof\n    x\n     ^\n"},
+	}
+
+	for _, test := range tests {
+		rendered := Render(test.file, test.source, test.line, test.column, test.message)
+
+		if rendered != test.expected {
+			t.Errorf("wrong rendering. expected=%q, got=%q", test.expected, rendered)
+		}
+	}
+}
+
+func TestDiagnosticText(t *testing.T) {
+	warning := Diagnostic{
+		Code:     "shadowing",
+		Severity: Warning,
+		Message:  "len shadows the builtin len",
+		File:     "main.monkey",
+		Span:     Span{Start: Position{Line: 1, Column: 5}, End: Position{Line: 1, Column: 8}},
+		Notes:    []string{"prefix the name with _"},
+	}
+	expected := "main.monkey:1:5: warning: len shadows the builtin len\n    let len = 1;\n        ^\n    note: prefix the name with _\n"
+
+	if text := warning.Text("let len = 1;"); text != expected {
+		t.Errorf("wrong text. expected=%q, got=%q", expected, text)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	diagnostics := []Diagnostic{{
+		Code:     "syntax",
+		Severity: Error,
+		Message:  "no prefix parse function for ; found",
+		Span:     Span{Start: Position{Line: 2, Column: 9}, End: Position{Line: 2, Column: 10}},
+	}}
+	expected := `[{"code":"syntax","severity":"error","message":"no prefix parse function for ; found",` +
+		`"span":{"start":{"line":2,"column":9},"end":{"line":2,"column":10}}}]`
+
+	encoded, err := JSON(diagnostics)
+	if err != nil {
+		t.Fatalf("JSON returned an error: %s", err)
+	}
+	if string(encoded) != expected {
+		t.Errorf("wrong JSON. expected=%s, got=%s", expected, encoded)
+	}
+
+	if encoded, _ := JSON(nil); string(encoded) != "[]" {
+		t.Errorf("wrong JSON for no diagnostics. expected=[], got=%s", encoded)
+	}
+}
@@ -3,8 +3,17 @@ package evaluator
 import (
 	"fmt"
 	"monkey/object"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
+// maxRepeatLength is the length in bytes of the longest string `repeat`
+// builds, so a huge count is an error rather than a crash.
+const maxRepeatLength = 1 << 30
+
 var builtins = map[string]*object.Builtin{
 	"len": {
 		Fn: func(args ...object.Object) object.Object {
@@ -16,7 +25,9 @@ var builtins = map[string]*object.Builtin{
 			case *object.Array:
 				return &object.Integer{Value: int64(len(arg.Elements))}
 			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
+				return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+			case *object.Hash:
+				return &object.Integer{Value: int64(len(arg.Pairs))}
 			default:
 				return newError("argument to `len` not supported, got %s",
 					args[0].Type())
@@ -114,13 +125,1230 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 
-	"puts": {
+	"clone": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			return deepCopy(args[0], make(map[object.Object]object.Object))
+		},
+	},
+
+	"enumerate": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("argument to `enumerate` must be ARRAY, got %s",
+					args[0].Type())
+			}
+
+			pairs := make([]object.Object, len(arr.Elements))
+			for i, element := range arr.Elements {
+				pairs[i] = &object.Array{
+					Elements: []object.Object{&object.Integer{Value: int64(i)}, element},
+				}
+			}
+
+			return &object.Array{Elements: pairs}
+		},
+	},
+
+	"unique": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("argument to `unique` must be ARRAY, got %s",
+					args[0].Type())
+			}
+
+			seen := make(map[object.HashKey]bool)
+			elements := []object.Object{}
+			for _, element := range arr.Elements {
+				hashable, ok := element.(object.Hashable)
+				if !ok {
+					return newError("unusable as hash key: %s", element.Type())
+				}
+
+				key := hashable.HashKey()
+				if !seen[key] {
+					seen[key] = true
+					elements = append(elements, element)
+				}
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	},
+
+	"reverse": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("argument to `reverse` must be ARRAY, got %s",
+					args[0].Type())
+			}
+
+			length := len(arr.Elements)
+			elements := make([]object.Object, length)
+			for i, element := range arr.Elements {
+				elements[length-1-i] = element
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	},
+
+	"concat": {
 		Fn: func(args ...object.Object) object.Object {
+			elements := []object.Object{}
 			for _, arg := range args {
-				fmt.Println(arg.Inspect())
+				arr, ok := arg.(*object.Array)
+				if !ok {
+					return newError("arguments to `concat` must be ARRAY, got %s",
+						arg.Type())
+				}
+				elements = append(elements, arr.Elements...)
 			}
 
-			return NULL
+			return &object.Array{Elements: elements}
+		},
+	},
+
+	"flatten": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("argument to `flatten` must be ARRAY, got %s",
+					args[0].Type())
+			}
+
+			elements := []object.Object{}
+			for _, element := range arr.Elements {
+				if nested, ok := element.(*object.Array); ok {
+					elements = append(elements, nested.Elements...)
+				} else {
+					elements = append(elements, element)
+				}
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	},
+
+	"zip": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2",
+					len(args))
+			}
+
+			for _, arg := range args {
+				if arg.Type() != object.ARRAY_OBJ {
+					return newError("arguments to `zip` must be ARRAY, got %s",
+						arg.Type())
+				}
+			}
+
+			left := args[0].(*object.Array).Elements
+			right := args[1].(*object.Array).Elements
+			length := min(len(left), len(right))
+
+			pairs := make([]object.Object, length)
+			for i := 0; i < length; i++ {
+				pairs[i] = &object.Array{Elements: []object.Object{left[i], right[i]}}
+			}
+
+			return &object.Array{Elements: pairs}
+		},
+	},
+
+	"range": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 || len(args) > 3 {
+				return newError("wrong number of arguments. got=%d, want=1 to 3",
+					len(args))
+			}
+
+			bounds := make([]int64, len(args))
+			for i, arg := range args {
+				integer, ok := arg.(*object.Integer)
+				if !ok {
+					return newError("arguments to `range` must be INTEGER, got %s",
+						arg.Type())
+				}
+				bounds[i] = integer.Value
+			}
+
+			start, end, step := int64(0), bounds[0], int64(1)
+			if len(bounds) > 1 {
+				start, end = bounds[0], bounds[1]
+			}
+			if len(bounds) > 2 {
+				step = bounds[2]
+			}
+
+			if step == 0 {
+				return newError("range step must not be zero")
+			}
+
+			// The count is worked out up front, since stepping past end could
+			// overflow and wrap around to before it.
+			var count uint64
+			if step > 0 && start < end {
+				count = (uint64(end)-uint64(start)-1)/uint64(step) + 1
+			} else if step < 0 && start > end {
+				count = (uint64(start)-uint64(end)-1)/(-uint64(step)) + 1
+			}
+
+			elements := []object.Object{}
+			for n := uint64(0); n < count; n++ {
+				elements = append(elements, &object.Integer{Value: start + int64(n)*step})
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	},
+
+	"sum": {
+		Fn: func(args ...object.Object) object.Object {
+			values, err := numberElements("sum", args)
+			if err != nil {
+				return err
+			}
+
+			if hasFloat(values) {
+				var total float64
+				for _, value := range values {
+					total += toFloat(value)
+				}
+				return &object.Float{Value: total}
+			}
+
+			var total int64
+			for _, value := range values {
+				total += value.(*object.Integer).Value
+			}
+
+			return &object.Integer{Value: total}
+		},
+	},
+
+	"keys": {
+		Fn: func(args ...object.Object) object.Object {
+			pairs, err := hashPairsArgument("keys", args)
+			if err != nil {
+				return err
+			}
+
+			elements := make([]object.Object, len(pairs))
+			for i, pair := range pairs {
+				elements[i] = pair.Key
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	},
+
+	"values": {
+		Fn: func(args ...object.Object) object.Object {
+			pairs, err := hashPairsArgument("values", args)
+			if err != nil {
+				return err
+			}
+
+			elements := make([]object.Object, len(pairs))
+			for i, pair := range pairs {
+				elements[i] = pair.Value
+			}
+
+			return &object.Array{Elements: elements}
 		},
 	},
+
+	"entries": {
+		Fn: func(args ...object.Object) object.Object {
+			pairs, err := hashPairsArgument("entries", args)
+			if err != nil {
+				return err
+			}
+
+			elements := make([]object.Object, len(pairs))
+			for i, pair := range pairs {
+				elements[i] = &object.Array{
+					Elements: []object.Object{pair.Key, pair.Value},
+				}
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	},
+
+	// delete leaves its argument untouched and returns a new hash without
+	// the key, in the same way push returns a new array.
+	"delete": {
+		Fn: func(args ...object.Object) object.Object {
+			hash, key, err := hashAndKey("delete", args)
+			if err != nil {
+				return err
+			}
+
+			result := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair, len(hash.Pairs))}
+			for _, pair := range hash.Ordered() {
+				if hashKey := pair.Key.(object.Hashable).HashKey(); hashKey != key {
+					result.Set(hashKey, pair)
+				}
+			}
+
+			return result
+		},
+	},
+
+	"has_key": {
+		Fn: func(args ...object.Object) object.Object {
+			hash, key, err := hashAndKey("has_key", args)
+			if err != nil {
+				return err
+			}
+
+			_, ok := hash.Pairs[key]
+			return nativeBoolToBooleanObject(ok)
+		},
+	},
+
+	"merge": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 {
+				return newError("wrong number of arguments. got=%d, want at least 1",
+					len(args))
+			}
+
+			result := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
+			for _, arg := range args {
+				hash, ok := arg.(*object.Hash)
+				if !ok {
+					return newError("arguments to `merge` must be HASH, got %s",
+						arg.Type())
+				}
+				for _, pair := range hash.Ordered() {
+					result.Set(pair.Key.(object.Hashable).HashKey(), pair)
+				}
+			}
+
+			return result
+		},
+	},
+
+	"split": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2",
+					len(args))
+			}
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("first argument to `split` must be STRING, got %s",
+					args[0].Type())
+			}
+
+			separator, ok := args[1].(*object.String)
+			if !ok {
+				return newError("second argument to `split` must be STRING, got %s",
+					args[1].Type())
+			}
+
+			parts := strings.Split(str.Value, separator.Value)
+			elements := make([]object.Object, len(parts))
+			for i, part := range parts {
+				elements[i] = &object.String{Value: part}
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	},
+
+	"join": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2",
+					len(args))
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("first argument to `join` must be ARRAY, got %s",
+					args[0].Type())
+			}
+
+			separator, ok := args[1].(*object.String)
+			if !ok {
+				return newError("second argument to `join` must be STRING, got %s",
+					args[1].Type())
+			}
+
+			parts := make([]string, len(arr.Elements))
+			for i, element := range arr.Elements {
+				str, ok := element.(*object.String)
+				if !ok {
+					return newError("elements passed to `join` must be STRING, got %s",
+						element.Type())
+				}
+				parts[i] = str.Value
+			}
+
+			return &object.String{Value: strings.Join(parts, separator.Value)}
+		},
+	},
+
+	"trim":       stringTransform("trim", strings.TrimSpace),
+	"trim_left":  stringTransform("trim_left", trimLeftSpace),
+	"trim_right": stringTransform("trim_right", trimRightSpace),
+	"upper":      stringTransform("upper", strings.ToUpper),
+	"lower":      stringTransform("lower", strings.ToLower),
+
+	"replace": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 3 {
+				return newError("wrong number of arguments. got=%d, want=3",
+					len(args))
+			}
+
+			for _, arg := range args {
+				if arg.Type() != object.STRING_OBJ {
+					return newError("arguments to `replace` must be STRING, got %s",
+						arg.Type())
+				}
+			}
+
+			str := args[0].(*object.String).Value
+			old := args[1].(*object.String).Value
+			replacement := args[2].(*object.String).Value
+
+			return &object.String{Value: strings.ReplaceAll(str, old, replacement)}
+		},
+	},
+
+	"contains": {
+		Fn: func(args ...object.Object) object.Object {
+			if arr, ok := arrayArgument(args); ok {
+				return nativeBoolToBooleanObject(indexOfElement(arr, args[1]) != -1)
+			}
+
+			str, substr, err := stringPair("contains", args)
+			if err != nil {
+				return err
+			}
+
+			return nativeBoolToBooleanObject(strings.Contains(str, substr))
+		},
+	},
+
+	"starts_with": stringPredicate("starts_with", strings.HasPrefix),
+	"ends_with":   stringPredicate("ends_with", strings.HasSuffix),
+
+	"index_of": {
+		Fn: func(args ...object.Object) object.Object {
+			if arr, ok := arrayArgument(args); ok {
+				return &object.Integer{Value: int64(indexOfElement(arr, args[1]))}
+			}
+
+			str, substr, err := stringPair("index_of", args)
+			if err != nil {
+				return err
+			}
+
+			index := strings.Index(str, substr)
+			if index > 0 {
+				index = utf8.RuneCountInString(str[:index])
+			}
+
+			return &object.Integer{Value: int64(index)}
+		},
+	},
+
+	"ord": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `ord` must be STRING, got %s",
+					args[0].Type())
+			}
+
+			runes := []rune(str.Value)
+			if len(runes) != 1 {
+				return newError("argument to `ord` must be a single character, got %q",
+					str.Value)
+			}
+
+			return &object.Integer{Value: int64(runes[0])}
+		},
+	},
+
+	"chr": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			code, ok := args[0].(*object.Integer)
+			if !ok {
+				return newError("argument to `chr` must be INTEGER, got %s",
+					args[0].Type())
+			}
+
+			if code.Value < 0 || code.Value > utf8.MaxRune || !utf8.ValidRune(rune(code.Value)) {
+				return newError("invalid character code: %d", code.Value)
+			}
+
+			return &object.String{Value: string(rune(code.Value))}
+		},
+	},
+
+	"substring": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 3 {
+				return newError("wrong number of arguments. got=%d, want=3",
+					len(args))
+			}
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("first argument to `substring` must be STRING, got %s",
+					args[0].Type())
+			}
+
+			start, ok := args[1].(*object.Integer)
+			if !ok {
+				return newError("second argument to `substring` must be INTEGER, got %s",
+					args[1].Type())
+			}
+
+			end, ok := args[2].(*object.Integer)
+			if !ok {
+				return newError("third argument to `substring` must be INTEGER, got %s",
+					args[2].Type())
+			}
+
+			runes := []rune(str.Value)
+			length := int64(len(runes))
+			if start.Value < 0 || end.Value > length || start.Value > end.Value {
+				return newError("substring bounds out of range [%d:%d] with length %d",
+					start.Value, end.Value, length)
+			}
+
+			return &object.String{Value: string(runes[start.Value:end.Value])}
+		},
+	},
+
+	"repeat": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2",
+					len(args))
+			}
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("first argument to `repeat` must be STRING, got %s",
+					args[0].Type())
+			}
+
+			count, ok := args[1].(*object.Integer)
+			if !ok {
+				return newError("second argument to `repeat` must be INTEGER, got %s",
+					args[1].Type())
+			}
+
+			if count.Value < 0 {
+				return newError("negative repeat count: %d", count.Value)
+			}
+			if len(str.Value) > 0 && count.Value > maxRepeatLength/int64(len(str.Value)) {
+				return newError("repeat count too large: %d", count.Value)
+			}
+
+			return &object.String{Value: strings.Repeat(str.Value, int(count.Value))}
+		},
+	},
+
+	"chars": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `chars` must be STRING, got %s",
+					args[0].Type())
+			}
+
+			elements := []object.Object{}
+			for _, r := range str.Value {
+				elements = append(elements, &object.String{Value: string(r)})
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	},
+
+	"format": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 {
+				return newError("wrong number of arguments. got=%d, want at least 1",
+					len(args))
+			}
+
+			format, ok := args[0].(*object.String)
+			if !ok {
+				return newError("first argument to `format` must be STRING, got %s",
+					args[0].Type())
+			}
+
+			return formatString(format.Value, args[1:])
+		},
+	},
+
+	"int": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Integer:
+				return arg
+			case *object.Float:
+				return &object.Integer{Value: int64(arg.Value)}
+			case *object.Boolean:
+				if arg.Value {
+					return &object.Integer{Value: 1}
+				}
+				return &object.Integer{Value: 0}
+			case *object.String:
+				value, err := strconv.ParseInt(strings.TrimSpace(arg.Value), 10, 64)
+				if err != nil {
+					return newError("cannot convert %q to INTEGER", arg.Value)
+				}
+				return &object.Integer{Value: value}
+			default:
+				return newError("cannot convert %s to INTEGER", arg.Type())
+			}
+		},
+	},
+
+	"float": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Integer:
+				return &object.Float{Value: float64(arg.Value)}
+			case *object.Float:
+				return arg
+			case *object.String:
+				value, err := strconv.ParseFloat(strings.TrimSpace(arg.Value), 64)
+				if err != nil {
+					return newError("cannot convert %q to FLOAT", arg.Value)
+				}
+				return &object.Float{Value: value}
+			default:
+				return newError("cannot convert %s to FLOAT", arg.Type())
+			}
+		},
+	},
+
+	// parse_int and parse_float are strict: whitespace or trailing garbage
+	// gives an error value, which scripts can check for with is_error.
+	"parse_int": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2",
+					len(args))
+			}
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("first argument to `parse_int` must be STRING, got %s",
+					args[0].Type())
+			}
+
+			base := int64(10)
+			if len(args) == 2 {
+				integer, ok := args[1].(*object.Integer)
+				if !ok {
+					return newError("second argument to `parse_int` must be INTEGER, got %s",
+						args[1].Type())
+				}
+				if integer.Value < 2 || integer.Value > 36 {
+					return newError("invalid base for `parse_int`: %d", integer.Value)
+				}
+				base = integer.Value
+			}
+
+			value, err := strconv.ParseInt(str.Value, int(base), 64)
+			if err != nil {
+				return newErrorValue("cannot parse %q as INTEGER", str.Value)
+			}
+
+			return &object.Integer{Value: value}
+		},
+	},
+
+	"parse_float": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `parse_float` must be STRING, got %s",
+					args[0].Type())
+			}
+
+			value, err := strconv.ParseFloat(str.Value, 64)
+			if err != nil {
+				return newErrorValue("cannot parse %q as FLOAT", str.Value)
+			}
+
+			return &object.Float{Value: value}
+		},
+	},
+
+	// str converts its argument to the string shown by puts.
+	"str": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			if str, ok := args[0].(*object.String); ok {
+				return str
+			}
+
+			return &object.String{Value: args[0].Inspect()}
+		},
+	},
+
+	// bool follows the same truthiness rules as if: only false and null are
+	// false, everything else (including 0, "" and []) is true.
+	"bool": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			return nativeBoolToBooleanObject(isTruthy(args[0]))
+		},
+	},
+
+	"error": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			message, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `error` must be STRING, got %s",
+					args[0].Type())
+			}
+
+			return &object.ErrorValue{Message: message.Value}
+		},
+	},
+
+	"is_error": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			return nativeBoolToBooleanObject(args[0].Type() == object.ERROR_VALUE_OBJ)
+		},
+	},
+
+	"error_message": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			errorValue, ok := args[0].(*object.ErrorValue)
+			if !ok {
+				return newError("argument to `error_message` must be ERROR_VALUE, got %s",
+					args[0].Type())
+			}
+
+			return &object.String{Value: errorValue.Message}
+		},
+	},
+
+	"type": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			return typeOf(args[0])
+		},
+	},
+}
+
+// The builtins below call back into Monkey functions, so they are registered
+// in init to keep the builtins table out of applyFunction's initialization
+// dependencies.
+func init() {
+	builtins["map"] = &object.Builtin{Fn: mapBuiltin}
+	builtins["filter"] = &object.Builtin{Fn: filterBuiltin}
+	builtins["reduce"] = &object.Builtin{Fn: reduceBuiltin}
+	builtins["sort"] = &object.Builtin{Fn: sortBuiltin}
+	builtins["each"] = &object.Builtin{Fn: eachBuiltin}
+}
+
+func mapBuiltin(args ...object.Object) object.Object {
+	arr, fn, err := arrayAndFunction("map", args)
+	if err != nil {
+		return err
+	}
+
+	elements := make([]object.Object, len(arr.Elements))
+	for i, element := range arr.Elements {
+		result := applyFunction(fn, []object.Object{element})
+		if isError(result) {
+			return result
+		}
+		elements[i] = result
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// eachBuiltin calls a function on every element for its side effects and
+// returns null.
+func eachBuiltin(args ...object.Object) object.Object {
+	arr, fn, err := arrayAndFunction("each", args)
+	if err != nil {
+		return err
+	}
+
+	for _, element := range arr.Elements {
+		result := applyFunction(fn, []object.Object{element})
+		if isError(result) {
+			return result
+		}
+	}
+
+	return NULL
+}
+
+func filterBuiltin(args ...object.Object) object.Object {
+	arr, fn, err := arrayAndFunction("filter", args)
+	if err != nil {
+		return err
+	}
+
+	elements := []object.Object{}
+	for _, element := range arr.Elements {
+		result := applyFunction(fn, []object.Object{element})
+		if isError(result) {
+			return result
+		}
+		if isTruthy(result) {
+			elements = append(elements, element)
+		}
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+func reduceBuiltin(args ...object.Object) object.Object {
+	if len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=3", len(args))
+	}
+
+	arr, fn, err := arrayAndFunction("reduce", []object.Object{args[0], args[2]})
+	if err != nil {
+		return err
+	}
+
+	accumulator := args[1]
+	for _, element := range arr.Elements {
+		accumulator = applyFunction(fn, []object.Object{accumulator, element})
+		if isError(accumulator) {
+			return accumulator
+		}
+	}
+
+	return accumulator
+}
+
+// sortBuiltin returns a sorted copy of an array. Without a comparator the
+// elements must all be INTEGER or all be STRING; a comparator fn(a, b)
+// returns whether a belongs before b. The sort is stable either way.
+func sortBuiltin(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2",
+			len(args))
+	}
+
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("first argument to `sort` must be ARRAY, got %s",
+			args[0].Type())
+	}
+
+	elements := make([]object.Object, len(arr.Elements))
+	copy(elements, arr.Elements)
+
+	var less func(a, b object.Object) (bool, object.Object)
+	if len(args) == 2 {
+		fn := args[1]
+		if !isCallable(fn) {
+			return newError("second argument to `sort` must be FUNCTION, got %s",
+				fn.Type())
+		}
+		less = func(a, b object.Object) (bool, object.Object) {
+			result := applyFunction(fn, []object.Object{a, b})
+			if isError(result) {
+				return false, result
+			}
+			return isTruthy(result), nil
+		}
+	} else {
+		less = naturalLess
+	}
+
+	var err object.Object
+	sort.SliceStable(elements, func(i, j int) bool {
+		if err != nil {
+			return false
+		}
+		var isLess bool
+		isLess, err = less(elements[i], elements[j])
+		return isLess
+	})
+	if err != nil {
+		return err
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+func naturalLess(a, b object.Object) (bool, object.Object) {
+	switch {
+	case a.Type() == object.INTEGER_OBJ && b.Type() == object.INTEGER_OBJ:
+		return a.(*object.Integer).Value < b.(*object.Integer).Value, nil
+	case a.Type() == object.STRING_OBJ && b.Type() == object.STRING_OBJ:
+		return a.(*object.String).Value < b.(*object.String).Value, nil
+	default:
+		return false, newError("cannot sort %s and %s without a comparator",
+			a.Type(), b.Type())
+	}
+}
+
+func arrayAndFunction(name string, args []object.Object) (*object.Array, object.Object, *object.Error) {
+	if len(args) != 2 {
+		return nil, nil, newError("wrong number of arguments. got=%d, want=2",
+			len(args))
+	}
+
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return nil, nil, newError("first argument to `%s` must be ARRAY, got %s",
+			name, args[0].Type())
+	}
+
+	fn := args[len(args)-1]
+	if !isCallable(fn) {
+		return nil, nil, newError("argument to `%s` must be FUNCTION, got %s",
+			name, fn.Type())
+	}
+
+	return arr, fn, nil
+}
+
+// arrayArgument reports whether args is an ARRAY followed by a single value,
+// which selects the array form of builtins that also work on strings.
+func arrayArgument(args []object.Object) (*object.Array, bool) {
+	if len(args) != 2 {
+		return nil, false
+	}
+
+	arr, ok := args[0].(*object.Array)
+	return arr, ok
+}
+
+func indexOfElement(arr *object.Array, target object.Object) int {
+	for i, element := range arr.Elements {
+		if deepEqual(element, target) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func hashPairsArgument(name string, args []object.Object) ([]object.HashPair, *object.Error) {
+	if len(args) != 1 {
+		return nil, newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return nil, newError("argument to `%s` must be HASH, got %s",
+			name, args[0].Type())
+	}
+
+	return hash.Ordered(), nil
+}
+
+// deepCopy copies arrays and hashes along with everything they contain. The
+// copies map records the copy of every container visited so far, so shared
+// and cyclic references are preserved rather than followed forever. Other
+// objects are immutable and returned as they are.
+func deepCopy(obj object.Object, copies map[object.Object]object.Object) object.Object {
+	if existing, ok := copies[obj]; ok {
+		return existing
+	}
+
+	switch obj := obj.(type) {
+	case *object.Array:
+		arr := &object.Array{Elements: make([]object.Object, len(obj.Elements))}
+		copies[obj] = arr
+		for i, element := range obj.Elements {
+			arr.Elements[i] = deepCopy(element, copies)
+		}
+		return arr
+	case *object.Hash:
+		hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair, len(obj.Pairs))}
+		copies[obj] = hash
+		for _, pair := range obj.Ordered() {
+			hash.Set(pair.Key.(object.Hashable).HashKey(), object.HashPair{Key: pair.Key, Value: deepCopy(pair.Value, copies)})
+		}
+		return hash
+	default:
+		return obj
+	}
+}
+
+// newHash builds a hash with string keys, for builtins returning records.
+// The keys are inserted in sorted order.
+func newHash(fields map[string]object.Object) *object.Hash {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair, len(fields))}
+	for _, name := range names {
+		key := &object.String{Value: name}
+		hash.Set(key.HashKey(), object.HashPair{Key: key, Value: fields[name]})
+	}
+	return hash
+}
+
+func hashAndKey(name string, args []object.Object) (*object.Hash, object.HashKey, *object.Error) {
+	if len(args) != 2 {
+		return nil, object.HashKey{}, newError("wrong number of arguments. got=%d, want=2",
+			len(args))
+	}
+
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return nil, object.HashKey{}, newError("first argument to `%s` must be HASH, got %s",
+			name, args[0].Type())
+	}
+
+	key, ok := args[1].(object.Hashable)
+	if !ok {
+		return nil, object.HashKey{}, newError("unusable as hash key: %s", args[1].Type())
+	}
+
+	return hash, key.HashKey(), nil
+}
+
+// numberElements returns the elements of the single array in args, checking
+// that they're all INTEGER or FLOAT.
+func numberElements(name string, args []object.Object) ([]object.Object, *object.Error) {
+	if len(args) != 1 {
+		return nil, newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return nil, newError("argument to `%s` must be ARRAY, got %s",
+			name, args[0].Type())
+	}
+
+	for _, element := range arr.Elements {
+		if !isNumber(element) {
+			return nil, newError("elements passed to `%s` must be INTEGER or FLOAT, got %s",
+				name, element.Type())
+		}
+	}
+
+	return arr.Elements, nil
+}
+
+// formatString expands the %s, %d, %f and %v verbs in format using the
+// Inspect output of args. A literal percent sign is written as %%.
+func formatString(format string, args []object.Object) object.Object {
+	var out strings.Builder
+	next := 0
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			out.WriteByte(format[i])
+			continue
+		}
+
+		i++
+		if i >= len(format) {
+			return newError("format string ends with a lone %%")
+		}
+
+		verb := format[i]
+		if verb == '%' {
+			out.WriteByte('%')
+			continue
+		}
+
+		if next >= len(args) {
+			return newError("missing argument for %%%c in format", verb)
+		}
+		arg := args[next]
+		next++
+
+		switch verb {
+		case 's', 'v':
+			if str, ok := arg.(*object.String); ok {
+				out.WriteString(str.Value)
+			} else {
+				out.WriteString(arg.Inspect())
+			}
+		case 'd':
+			integer, ok := arg.(*object.Integer)
+			if !ok {
+				return newError("%%d in format requires INTEGER, got %s", arg.Type())
+			}
+			fmt.Fprintf(&out, "%d", integer.Value)
+		case 'f':
+			if !isNumber(arg) {
+				return newError("%%f in format requires INTEGER or FLOAT, got %s",
+					arg.Type())
+			}
+			fmt.Fprintf(&out, "%f", toFloat(arg))
+		default:
+			return newError("unknown format verb %%%c", verb)
+		}
+	}
+
+	if next < len(args) {
+		return newError("too many arguments for format. got=%d, want=%d",
+			len(args), next)
+	}
+
+	return &object.String{Value: out.String()}
+}
+
+// stringTransform wraps a string-to-string function as a builtin taking a
+// single STRING argument.
+func stringTransform(name string, fn func(string) string) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `%s` must be STRING, got %s",
+					name, args[0].Type())
+			}
+
+			return &object.String{Value: fn(str.Value)}
+		},
+	}
+}
+
+// stringPredicate wraps a function testing a string against a substring as a
+// builtin taking two STRING arguments.
+func stringPredicate(name string, fn func(string, string) bool) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			str, substr, err := stringPair(name, args)
+			if err != nil {
+				return err
+			}
+
+			return nativeBoolToBooleanObject(fn(str, substr))
+		},
+	}
+}
+
+func stringPair(name string, args []object.Object) (string, string, *object.Error) {
+	if len(args) != 2 {
+		return "", "", newError("wrong number of arguments. got=%d, want=2",
+			len(args))
+	}
+
+	for _, arg := range args {
+		if arg.Type() != object.STRING_OBJ {
+			return "", "", newError("arguments to `%s` must be STRING, got %s",
+				name, arg.Type())
+		}
+	}
+
+	return args[0].(*object.String).Value, args[1].(*object.String).Value, nil
+}
+
+func trimLeftSpace(s string) string {
+	return strings.TrimLeftFunc(s, unicode.IsSpace)
+}
+
+func trimRightSpace(s string) string {
+	return strings.TrimRightFunc(s, unicode.IsSpace)
 }
@@ -0,0 +1,168 @@
+package evaluator
+
+import (
+	"fmt"
+	"io"
+	"monkey/object"
+	"strconv"
+	"strings"
+)
+
+// consoleBuiltins use the streams of the host of the interpreter calling
+// them.
+var consoleBuiltins = map[string]hostBuiltin{
+	"puts": func(host *object.Host, args ...object.Object) object.Object {
+		for _, arg := range args {
+			fmt.Fprintln(host.Stdout, arg.Inspect())
+		}
+
+		return NULL
+	},
+
+	// prints writes its arguments separated by spaces and without a
+	// trailing newline.
+	"prints": func(host *object.Host, args ...object.Object) object.Object {
+		io.WriteString(host.Stdout, joinInspected(args))
+		return NULL
+	},
+
+	// pp prints each argument on its own lines, with nested arrays and
+	// hashes indented.
+	"pp": func(host *object.Host, args ...object.Object) object.Object {
+		for _, arg := range args {
+			var out strings.Builder
+			prettyPrint(&out, arg, "", make(map[object.Object]bool))
+			fmt.Fprintln(host.Stdout, out.String())
+		}
+
+		return NULL
+	},
+
+	"eprintln": func(host *object.Host, args ...object.Object) object.Object {
+		fmt.Fprintln(host.Stderr, joinInspected(args))
+		return NULL
+	},
+
+	"read_line": func(host *object.Host, args ...object.Object) object.Object {
+		if len(args) != 0 {
+			return newError("wrong number of arguments. got=%d, want=0",
+				len(args))
+		}
+
+		return readLine(host)
+	},
+
+	// input is read_line with an optional prompt, which is written without
+	// a trailing newline.
+	"input": func(host *object.Host, args ...object.Object) object.Object {
+		if len(args) > 1 {
+			return newError("wrong number of arguments. got=%d, want=0 or 1",
+				len(args))
+		}
+
+		if len(args) == 1 {
+			prompt, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `input` must be STRING, got %s",
+					args[0].Type())
+			}
+			io.WriteString(host.Stdout, prompt.Value)
+		}
+
+		return readLine(host)
+	},
+}
+
+func init() {
+	for name, fn := range consoleBuiltins {
+		environmentBuiltins[name] = bindHost(fn)
+	}
+}
+
+// joinInspected joins its arguments with spaces, writing strings without
+// quotes like puts does.
+func joinInspected(args []object.Object) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = arg.Inspect()
+	}
+	return strings.Join(parts, " ")
+}
+
+// prettyPrint writes obj to out with nested values indented one level deeper
+// than indent. Strings are quoted so they can be told apart from other
+// values. A container that contains itself is shown as [...] or {...} where
+// it recurs; visiting holds the containers currently being printed.
+func prettyPrint(out *strings.Builder, obj object.Object, indent string, visiting map[object.Object]bool) {
+	const step = "  "
+
+	switch obj := obj.(type) {
+	case *object.String:
+		out.WriteString(strconv.Quote(obj.Value))
+	case *object.Array:
+		if visiting[obj] {
+			out.WriteString("[...]")
+			return
+		}
+		if len(obj.Elements) == 0 {
+			out.WriteString("[]")
+			return
+		}
+
+		visiting[obj] = true
+		out.WriteString("[\n")
+		for i, element := range obj.Elements {
+			out.WriteString(indent + step)
+			prettyPrint(out, element, indent+step, visiting)
+			if i < len(obj.Elements)-1 {
+				out.WriteString(",")
+			}
+			out.WriteString("\n")
+		}
+		out.WriteString(indent + "]")
+		delete(visiting, obj)
+	case *object.Hash:
+		if visiting[obj] {
+			out.WriteString("{...}")
+			return
+		}
+		if len(obj.Pairs) == 0 {
+			out.WriteString("{}")
+			return
+		}
+
+		visiting[obj] = true
+		out.WriteString("{\n")
+		pairs := obj.Ordered()
+		for i, pair := range pairs {
+			out.WriteString(indent + step)
+			prettyPrint(out, pair.Key, indent+step, visiting)
+			out.WriteString(": ")
+			prettyPrint(out, pair.Value, indent+step, visiting)
+			if i < len(pairs)-1 {
+				out.WriteString(",")
+			}
+			out.WriteString("\n")
+		}
+		out.WriteString(indent + "}")
+		delete(visiting, obj)
+	default:
+		out.WriteString(obj.Inspect())
+	}
+}
+
+// readLine returns the next line from the stdin of host without its line
+// ending, or null once the input is exhausted.
+func readLine(host *object.Host) object.Object {
+	line, err := host.Lines().ReadString('\n')
+	if err != nil && line == "" {
+		if err == io.EOF {
+			return NULL
+		}
+		return newError("read_line: %s", err)
+	}
+
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+	return &object.String{Value: line}
+}
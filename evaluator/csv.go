@@ -0,0 +1,91 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/csv"
+	"monkey/object"
+	"strings"
+)
+
+var csvBuiltins = map[string]*object.Builtin{
+	// csv_parse returns one array of strings per record. Records don't need
+	// to have the same number of fields.
+	"csv_parse": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `csv_parse` must be STRING, got %s",
+					args[0].Type())
+			}
+
+			reader := csv.NewReader(strings.NewReader(str.Value))
+			reader.FieldsPerRecord = -1
+
+			records, err := reader.ReadAll()
+			if err != nil {
+				return newError("csv_parse: %s", err)
+			}
+
+			rows := make([]object.Object, len(records))
+			for i, record := range records {
+				fields := make([]object.Object, len(record))
+				for j, field := range record {
+					fields[j] = &object.String{Value: field}
+				}
+				rows[i] = &object.Array{Elements: fields}
+			}
+
+			return &object.Array{Elements: rows}
+		},
+	},
+
+	// csv_stringify takes an array of rows, each an array of fields. Strings
+	// are written as they are and other values as they're inspected, quoted
+	// where CSV needs it.
+	"csv_stringify": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			rows, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("argument to `csv_stringify` must be ARRAY, got %s",
+					args[0].Type())
+			}
+
+			var out bytes.Buffer
+			writer := csv.NewWriter(&out)
+			for _, row := range rows.Elements {
+				fields, ok := row.(*object.Array)
+				if !ok {
+					return newError("rows passed to `csv_stringify` must be ARRAY, got %s",
+						row.Type())
+				}
+
+				record := make([]string, len(fields.Elements))
+				for i, field := range fields.Elements {
+					record[i] = stringValue(field)
+				}
+				if err := writer.Write(record); err != nil {
+					return newError("csv_stringify: %s", err)
+				}
+			}
+			writer.Flush()
+
+			return &object.String{Value: out.String()}
+		},
+	},
+}
+
+func init() {
+	for name, builtin := range csvBuiltins {
+		builtins[name] = builtin
+	}
+}
@@ -0,0 +1,82 @@
+package evaluator
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"monkey/object"
+)
+
+var encodingBuiltins = map[string]*object.Builtin{
+	"base64_encode": encoder("base64_encode", base64.StdEncoding.EncodeToString),
+	"base64_decode": decoder("base64_decode", base64.StdEncoding.DecodeString),
+	"hex_encode":    encoder("hex_encode", hex.EncodeToString),
+	"hex_decode":    decoder("hex_decode", hex.DecodeString),
+
+	// The hash builtins return hex-encoded digests.
+	"sha256": digest("sha256", sha256.New),
+	"sha1":   digest("sha1", sha1.New),
+	"md5":    digest("md5", md5.New),
+
+	"hmac_sha256": {
+		Fn: func(args ...object.Object) object.Object {
+			key, message, err := stringPair("hmac_sha256", args)
+			if err != nil {
+				return err
+			}
+
+			mac := hmac.New(sha256.New, []byte(key))
+			mac.Write([]byte(message))
+
+			return &object.String{Value: hex.EncodeToString(mac.Sum(nil))}
+		},
+	},
+}
+
+func init() {
+	for name, builtin := range encodingBuiltins {
+		builtins[name] = builtin
+	}
+}
+
+func encoder(name string, encode func([]byte) string) *object.Builtin {
+	return stringTransform(name, func(s string) string {
+		return encode([]byte(s))
+	})
+}
+
+func digest(name string, newHash func() hash.Hash) *object.Builtin {
+	return stringTransform(name, func(s string) string {
+		h := newHash()
+		h.Write([]byte(s))
+		return hex.EncodeToString(h.Sum(nil))
+	})
+}
+
+func decoder(name string, decode func(string) ([]byte, error)) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `%s` must be STRING, got %s",
+					name, args[0].Type())
+			}
+
+			decoded, err := decode(str.Value)
+			if err != nil {
+				return newErrorValue("%s: %s", name, err)
+			}
+
+			return &object.String{Value: string(decoded)}
+		},
+	}
+}
@@ -0,0 +1,134 @@
+package evaluator
+
+import (
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+)
+
+// environmentBuiltins are builtins that need state belonging to the running
+// interpreter rather than to the evaluator package. A new builtin is bound
+// to the environment every time one of these names is looked up.
+var environmentBuiltins = map[string]func(env *object.Environment) *object.Builtin{
+	"rand": func(env *object.Environment) *object.Builtin {
+		return &object.Builtin{
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 0 {
+					return newError("wrong number of arguments. got=%d, want=0",
+						len(args))
+				}
+
+				return &object.Float{Value: env.Random().Float64()}
+			},
+		}
+	},
+
+	"rand_int": func(env *object.Environment) *object.Builtin {
+		return &object.Builtin{
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1",
+						len(args))
+				}
+
+				n, ok := args[0].(*object.Integer)
+				if !ok {
+					return newError("argument to `rand_int` must be INTEGER, got %s",
+						args[0].Type())
+				}
+				if n.Value <= 0 {
+					return newError("argument to `rand_int` must be positive, got %d",
+						n.Value)
+				}
+
+				return &object.Integer{Value: env.Random().Int63n(n.Value)}
+			},
+		}
+	},
+
+	"seed": func(env *object.Environment) *object.Builtin {
+		return &object.Builtin{
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1",
+						len(args))
+				}
+
+				seed, ok := args[0].(*object.Integer)
+				if !ok {
+					return newError("argument to `seed` must be INTEGER, got %s",
+						args[0].Type())
+				}
+
+				env.Seed(seed.Value)
+				return NULL
+			},
+		}
+	},
+}
+
+func init() {
+	environmentBuiltins["eval"] = newEvalBuiltin
+}
+
+// A hostBuiltin is the function of a builtin that needs the host of the
+// interpreter calling it, such as one writing to its stdout.
+type hostBuiltin func(host *object.Host, args ...object.Object) object.Object
+
+// bindHost makes fn a builtin for environmentBuiltins, passing it the host
+// of the environment it's looked up in.
+func bindHost(fn hostBuiltin) func(env *object.Environment) *object.Builtin {
+	return func(env *object.Environment) *object.Builtin {
+		host := env.Host()
+		return &object.Builtin{
+			Fn: func(args ...object.Object) object.Object {
+				return fn(host, args...)
+			},
+		}
+	}
+}
+
+// newEvalBuiltin makes the eval builtin, which runs source code in a fresh
+// environment or, when the second argument is true, in the calling one.
+// Parse errors are returned as an error value.
+func newEvalBuiltin(env *object.Environment) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2",
+					len(args))
+			}
+
+			source, ok := args[0].(*object.String)
+			if !ok {
+				return newError("first argument to `eval` must be STRING, got %s",
+					args[0].Type())
+			}
+
+			target := object.NewEnvironment()
+			target.SetHost(env.Host())
+			target.SetImports(env.Imports())
+			if len(args) == 2 {
+				if args[1].Type() != object.BOOLEAN_OBJ {
+					return newError("second argument to `eval` must be BOOLEAN, got %s",
+						args[1].Type())
+				}
+				if args[1] == TRUE {
+					target = env
+				}
+			}
+
+			p := parser.New(lexer.New(source.Value))
+			program := p.ParseProgram()
+			if len(p.Diagnostics()) != 0 {
+				return newErrorValue("eval: %s", syntaxErrors(p.Diagnostics()))
+			}
+
+			result := Eval(program, target)
+			if result == nil {
+				return NULL
+			}
+			return result
+		},
+	}
+}
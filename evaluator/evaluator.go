@@ -2,8 +2,12 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
 	"monkey/ast"
 	"monkey/object"
+	"monkey/token"
+	"sort"
+	"strings"
 )
 
 var (
@@ -21,61 +25,96 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.ExpressionStatement:
 		return Eval(node.Expression, env)
 	case *ast.ReturnStatement:
+		if node.ReturnValue == nil {
+			return &object.ReturnValue{Value: NULL}
+		}
+		if call, ok := node.ReturnValue.(*ast.CallExpression); ok {
+			val := evalTailCall(call, env)
+			if isError(val) {
+				return val
+			}
+			return &object.ReturnValue{Value: val}
+		}
 		val := Eval(node.ReturnValue, env)
 		if isError(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
+	case *ast.ThrowStatement:
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		return at(node.Token, &object.Error{Message: val.Inspect(), Value: val})
+	case *ast.DeferStatement:
+		env.Defer(node.Expression)
+	case *ast.AssertStatement:
+		return at(node.Token, evalAssertStatement(node, env))
+	case *ast.DoWhileExpression:
+		return evalLoop(node.Label, node.Condition, node.Body, false, env)
+	case *ast.WhileExpression:
+		return evalLoop(node.Label, node.Condition, node.Body, true, env)
+	case *ast.BreakStatement:
+		return evalBreakStatement(node, env)
+	case *ast.ContinueStatement:
+		if node.Label != nil {
+			return &object.Continue{Label: node.Label.Value}
+		}
+		return &object.Continue{}
 	case *ast.LetStatement:
 		val := Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		bind(env, node.Token, node.Name.Value, val)
+	case *ast.AssignExpression:
+		return at(node.Token, evalAssignExpression(node, env))
+	case *ast.HashLetStatement:
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		return evalHashLetStatement(node, val, env)
+	case *ast.MultipleLetStatement:
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		return evalMultipleLetStatement(node, val, env)
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return at(node.Token, evalPrefixExpression(node.Operator, right))
 	case *ast.InfixExpression:
 		left := Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
+		if node.Operator == "??" {
+			return evalNullCoalescingExpression(left, node.Right, env)
+		}
 		right := Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		return at(node.Token, evalInfixExpression(node.Operator, left, right))
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
-	case *ast.CallExpression:
-		function := Eval(node.Function, env)
-		if isError(function) {
-			return function
-		}
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
-		}
-		return applyFunction(function, args)
-	case *ast.IndexExpression:
-		left := Eval(node.Left, env)
-		if isError(left) {
-			return left
-		}
-		index := Eval(node.Index, env)
-		if isError(index) {
-			return index
-		}
-		return evalIndexExpression(left, index)
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
+	case *ast.CallExpression, *ast.IndexExpression, *ast.OptionalChainExpression:
+		result, _ := evalLink(node.(ast.Expression), env)
+		return result
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		return at(node.Token, evalIdentifier(node, env))
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
 		return &object.Function{Parameters: params, Env: env, Body: body}
+	case *ast.MacroLiteral:
+		return at(node.Token, newError("macros must be bound with a let at the top of the program"))
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
@@ -86,6 +125,8 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalHashLiteral(node, env)
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 	case *ast.StringLiteral:
@@ -96,6 +137,16 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 }
 
 func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+	result := evalProgramStatements(program, env)
+
+	if env.HasDeferred() {
+		result = evalDeferred(env, result)
+	}
+
+	return result
+}
+
+func evalProgramStatements(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range program.Statements {
@@ -103,8 +154,8 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 
 		switch result := result.(type) {
 		case *object.ReturnValue:
-			return result.Value
-		case *object.Error:
+			return resolveTailCall(result.Value)
+		case *object.Error, *object.Exit:
 			return result
 		}
 	}
@@ -112,23 +163,156 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	return result
 }
 
+// evalDeferred runs the expressions deferred in env. An error raised by one
+// of them replaces result, but the remaining expressions still run.
+func evalDeferred(env *object.Environment, result object.Object) object.Object {
+	for _, expression := range env.TakeDeferred() {
+		evaluated := Eval(expression, env)
+		if isError(evaluated) {
+			result = evaluated
+		}
+	}
+
+	return result
+}
+
 func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range block.Statements {
 		result = Eval(statement, env)
 
-		if result != nil {
-			resultType := result.Type()
-			if resultType == object.RETURN_VALUE_OBJ || resultType == object.ERROR_OBJ {
-				return result
-			}
+		if interruptsBlock(result) {
+			return result
+		}
+	}
+
+	return result
+}
+
+// evalTailBlock evaluates a function body like evalBlockStatement, except
+// that a call in tail position is returned as an unapplied *object.TailCall.
+func evalTailBlock(block *ast.BlockStatement, env *object.Environment) object.Object {
+	var result object.Object
+
+	for index, statement := range block.Statements {
+		if index == len(block.Statements)-1 {
+			return evalTailStatement(statement, env)
+		}
+
+		result = Eval(statement, env)
+
+		if interruptsBlock(result) {
+			return result
 		}
 	}
 
 	return result
 }
 
+func evalTailStatement(statement ast.Statement, env *object.Environment) object.Object {
+	expressionStatement, ok := statement.(*ast.ExpressionStatement)
+	if !ok {
+		return Eval(statement, env)
+	}
+
+	switch expression := expressionStatement.Expression.(type) {
+	case *ast.CallExpression:
+		return evalTailCall(expression, env)
+	case *ast.IfExpression:
+		condition := Eval(expression.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+
+		if isTruthy(condition) {
+			return evalTailBlock(expression.Consequence, env)
+		} else if expression.Alternative != nil {
+			return evalTailBlock(expression.Alternative, env)
+		} else {
+			return NULL
+		}
+	default:
+		return Eval(statement, env)
+	}
+}
+
+func evalTailCall(node *ast.CallExpression, env *object.Environment) object.Object {
+	if isCallOf(node, "quote") {
+		return at(node.Token, quote(node, env))
+	}
+	function, shortCircuited := evalLink(node.Function, env)
+	if shortCircuited || isError(function) {
+		return function
+	}
+
+	args := evalExpressions(node.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	return &object.TailCall{Function: function, Arguments: args}
+}
+
+// evalLink evaluates node, which may be a link of a chain of calls, index
+// expressions and optional chains, and reports whether an optional chain
+// in it short-circuited. The links after one that did aren't evaluated, so
+// that a?.b["c"](d) is null when a is.
+func evalLink(node ast.Expression, env *object.Environment) (object.Object, bool) {
+	switch node := node.(type) {
+	case *ast.CallExpression:
+		if isCallOf(node, "quote") {
+			return at(node.Token, quote(node, env)), false
+		}
+		function, shortCircuited := evalLink(node.Function, env)
+		if shortCircuited || isError(function) {
+			return function, shortCircuited
+		}
+		args := evalExpressions(node.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0], false
+		}
+		return at(node.Token, applyFunction(function, args)), false
+	case *ast.IndexExpression:
+		left, shortCircuited := evalLink(node.Left, env)
+		if shortCircuited || isError(left) {
+			return left, shortCircuited
+		}
+		index := Eval(node.Index, env)
+		if isError(index) {
+			return index, false
+		}
+		return at(node.Token, evalIndexExpression(left, index)), false
+	case *ast.OptionalChainExpression:
+		left, shortCircuited := evalLink(node.Left, env)
+		if shortCircuited || isError(left) {
+			return left, shortCircuited
+		}
+		if left == NULL {
+			return NULL, true
+		}
+		return evalOptionalChainExpression(left, node.Property), false
+	default:
+		return Eval(node, env), false
+	}
+}
+
+// at records tok as the position an error was raised at, unless the error
+// already has one from a node evaluated further down the tree.
+func at(tok token.Token, obj object.Object) object.Object {
+	if err, ok := obj.(*object.Error); ok && err.Line == 0 {
+		err.Line, err.Column = tok.Line, tok.Column
+	}
+	return obj
+}
+
+func resolveTailCall(obj object.Object) object.Object {
+	if call, ok := obj.(*object.TailCall); ok {
+		return applyFunction(call.Function, call.Arguments)
+	}
+	return obj
+}
+
 func evalStatements(statements []ast.Statement, env *object.Environment) object.Object {
 	var result object.Object
 
@@ -156,8 +340,14 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 
 func evalInfixExpression(operator string, left, right object.Object) object.Object {
 	switch {
+	case operator == "in":
+		return evalInExpression(left, right)
+	case operator == ">>":
+		return evalComposeExpression(left, right)
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
 	case operator == "==":
@@ -173,6 +363,15 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	}
 }
 
+// evalNullCoalescingExpression only evaluates the right operand when the
+// left one is null.
+func evalNullCoalescingExpression(left object.Object, right ast.Expression, env *object.Environment) object.Object {
+	if left != NULL {
+		return left
+	}
+	return Eval(right, env)
+}
+
 func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
 	leftVal := left.(*object.Integer).Value
 	rightVal := right.(*object.Integer).Value
@@ -185,7 +384,15 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
 		return &object.Integer{Value: leftVal / rightVal}
+	case "**":
+		if rightVal < 0 {
+			return newError("negative exponent: %d", rightVal)
+		}
+		return &object.Integer{Value: integerPower(leftVal, rightVal)}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
@@ -194,12 +401,80 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
+	case "..":
+		return newRangeArray(leftVal, rightVal)
+	case "..=":
+		return newRangeArray(leftVal, rightVal+1)
 	default:
 		return newError("unknown operator: %s %s %s",
 			left.Type(), operator, right.Type())
 	}
 }
 
+// evalFloatInfixExpression handles arithmetic where at least one operand is
+// a float; an integer operand is converted to a float first.
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+	case "**":
+		return &object.Float{Value: math.Pow(leftVal, rightVal)}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
+	}
+}
+
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
+func toFloat(obj object.Object) float64 {
+	if integer, ok := obj.(*object.Integer); ok {
+		return float64(integer.Value)
+	}
+	return obj.(*object.Float).Value
+}
+
+func integerPower(base, exponent int64) int64 {
+	result := int64(1)
+	for exponent > 0 {
+		if exponent&1 == 1 {
+			result *= base
+		}
+		base *= base
+		exponent >>= 1
+	}
+	return result
+}
+
+// newRangeArray returns the integers from start up to, but not including,
+// end. The array is empty if end isn't greater than start.
+func newRangeArray(start, end int64) *object.Array {
+	elements := []object.Object{}
+	for i := start; i < end; i++ {
+		elements = append(elements, &object.Integer{Value: i})
+	}
+	return &object.Array{Elements: elements}
+}
+
 func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
 	if operator != "+" {
 		return newError("unknown operator: %s %s %s",
@@ -211,6 +486,105 @@ func evalStringInfixExpression(operator string, left, right object.Object) objec
 	return &object.String{Value: leftValue + rightValue}
 }
 
+// evalComposeExpression returns a function equivalent to
+// `fn(x) { right(left(x)) }`.
+func evalComposeExpression(left, right object.Object) object.Object {
+	if !isCallable(left) || !isCallable(right) {
+		return newError("unknown operator: %s >> %s", left.Type(), right.Type())
+	}
+
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			result := applyFunction(left, args)
+			if isError(result) {
+				return result
+			}
+			return applyFunction(right, []object.Object{result})
+		},
+	}
+}
+
+func isCallable(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Function, *object.Builtin:
+		return true
+	default:
+		return false
+	}
+}
+
+func evalInExpression(left, right object.Object) object.Object {
+	switch right := right.(type) {
+	case *object.Array:
+		// Elements are compared like contains and index_of do.
+		return nativeBoolToBooleanObject(indexOfElement(right, left) != -1)
+	case *object.Hash:
+		key, ok := left.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", left.Type())
+		}
+		_, ok = right.Pairs[key.HashKey()]
+		return nativeBoolToBooleanObject(ok)
+	case *object.String:
+		if left.Type() != object.STRING_OBJ {
+			return newError("type mismatch: %s in %s", left.Type(), right.Type())
+		}
+		return nativeBoolToBooleanObject(strings.Contains(right.Value, left.(*object.String).Value))
+	default:
+		return newError("unknown operator: %s in %s", left.Type(), right.Type())
+	}
+}
+
+// objectsEqual compares numbers and strings by value and every other
+// object by identity, just like the == operator does.
+func objectsEqual(left, right object.Object) bool {
+	switch left := left.(type) {
+	case *object.Integer:
+		other, ok := right.(*object.Integer)
+		return ok && left.Value == other.Value
+	case *object.Float:
+		other, ok := right.(*object.Float)
+		return ok && left.Value == other.Value
+	case *object.String:
+		other, ok := right.(*object.String)
+		return ok && left.Value == other.Value
+	default:
+		return left == right
+	}
+}
+
+// deepEqual is like objectsEqual but compares arrays element by element and
+// hashes pair by pair.
+func deepEqual(left, right object.Object) bool {
+	switch left := left.(type) {
+	case *object.Array:
+		other, ok := right.(*object.Array)
+		if !ok || len(left.Elements) != len(other.Elements) {
+			return false
+		}
+		for i, element := range left.Elements {
+			if !deepEqual(element, other.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *object.Hash:
+		other, ok := right.(*object.Hash)
+		if !ok || len(left.Pairs) != len(other.Pairs) {
+			return false
+		}
+		for key, pair := range left.Pairs {
+			otherPair, ok := other.Pairs[key]
+			if !ok || !deepEqual(pair.Value, otherPair.Value) {
+				return false
+			}
+		}
+		return true
+	default:
+		return objectsEqual(left, right)
+	}
+}
+
 func evalBangOperatorExpression(right object.Object) object.Object {
 	switch right {
 	case TRUE:
@@ -225,12 +599,14 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
+	switch right := right.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: -right.Value}
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
 		return newError("unknown operator: -%s", right.Type())
 	}
-
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
 }
 
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
@@ -248,6 +624,62 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 	}
 }
 
+func evalTryExpression(te *ast.TryExpression, env *object.Environment) object.Object {
+	result := evalProtectedBlock(te.Block, env)
+
+	if err, ok := result.(*object.Error); ok && te.Catch != nil {
+		var caught object.Object = &object.String{Value: err.Message}
+		if err.Value != nil {
+			caught = err.Value
+		}
+
+		result = evalProtectedBlock(te.Catch, object.NewCatchEnvironment(env, te.Parameter.Value, caught))
+	}
+
+	if te.Finally != nil {
+		finally := Eval(te.Finally, env)
+		if interruptsBlock(finally) {
+			return finally
+		}
+	}
+
+	return result
+}
+
+// evalProtectedBlock evaluates the block of a try or catch clause. A tail
+// call returned from inside it has to be applied right away, or any errors
+// it raises would escape the surrounding try expression.
+func evalProtectedBlock(block *ast.BlockStatement, env *object.Environment) object.Object {
+	result := Eval(block, env)
+
+	if returnValue, ok := result.(*object.ReturnValue); ok {
+		if call, ok := returnValue.Value.(*object.TailCall); ok {
+			value := resolveTailCall(call)
+			if isError(value) {
+				return value
+			}
+			return &object.ReturnValue{Value: value}
+		}
+	}
+
+	return result
+}
+
+// interruptsBlock reports whether obj stops the evaluation of the statements
+// following it: return values, errors, exits and loop control.
+func interruptsBlock(obj object.Object) bool {
+	if obj == nil {
+		return false
+	}
+
+	switch obj.Type() {
+	case object.RETURN_VALUE_OBJ, object.ERROR_OBJ, object.EXIT_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
+		return true
+	default:
+		return false
+	}
+}
+
 func isTruthy(obj object.Object) bool {
 	switch obj {
 	case NULL:
@@ -276,17 +708,33 @@ func evalExpressions(expressions []ast.Expression, env *object.Environment) []ob
 }
 
 func applyFunction(fn object.Object, args []object.Object) object.Object {
-	switch fn := fn.(type) {
-	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
+	for {
+		switch function := fn.(type) {
+		case *object.Function:
+			if len(args) != len(function.Parameters) {
+				return newError("wrong number of arguments. got=%d, want=%d", len(args), len(function.Parameters))
+			}
+			extendedEnv := extendFunctionEnv(function, args)
+			evaluated := unwrapReturnValue(evalTailBlock(function.Body, extendedEnv))
 
-	case *object.Builtin:
-		return fn.Fn(args...)
+			// The frame can't be reused for a tail call while deferred
+			// expressions still need its environment.
+			if extendedEnv.HasDeferred() {
+				return evalDeferred(extendedEnv, resolveTailCall(evaluated))
+			}
 
-	default:
-		return newError("not a function: %s", fn.Type())
+			call, ok := evaluated.(*object.TailCall)
+			if !ok {
+				return evaluated
+			}
+			fn, args = call.Function, call.Arguments
+
+		case *object.Builtin:
+			return function.Fn(args...)
+
+		default:
+			return newError("not a function: %s", fn.Type())
+		}
 	}
 }
 
@@ -346,10 +794,19 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 	return pair.Value
 }
 
+func evalOptionalChainExpression(left object.Object, property *ast.Identifier) object.Object {
+	switch left := left.(type) {
+	case *object.Hash:
+		return evalHashIndexExpression(left, &object.String{Value: property.Value})
+	default:
+		return newError("optional chaining not supported: %s", left.Type())
+	}
+}
+
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair, len(node.Pairs))}
 
-	for keyNode, valueNode := range node.Pairs {
+	for _, keyNode := range node.Keys() {
 		key := Eval(keyNode, env)
 		if isError(key) {
 			return key
@@ -360,16 +817,181 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 			return newError("unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
+		value := Eval(node.Pairs[keyNode], env)
 		if isError(value) {
 			return value
 		}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(hashKey.HashKey(), object.HashPair{Key: key, Value: value})
+	}
+
+	return hash
+}
+
+func evalBreakStatement(node *ast.BreakStatement, env *object.Environment) object.Object {
+	result := &object.Break{Value: NULL}
+
+	if node.Label != nil {
+		result.Label = node.Label.Value
+	}
+
+	if node.Value != nil {
+		value := Eval(node.Value, env)
+		if isError(value) {
+			return value
+		}
+		result.Value = value
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return result
+}
+
+// evalLoop evaluates a do-while loop, or a while loop if pretest is set, to
+// the value of the break that ended it, or to NULL once its condition is
+// false. A while loop checks its condition before the first iteration too.
+func evalLoop(labeled *ast.Identifier, condition ast.Expression, body *ast.BlockStatement, pretest bool, env *object.Environment) object.Object {
+	label := ""
+	if labeled != nil {
+		label = labeled.Value
+	}
+
+	for {
+		if pretest {
+			if done := evalLoopCondition(condition, env); done != nil {
+				return done
+			}
+		}
+
+		result := Eval(body, env)
+
+		switch result := result.(type) {
+		case *object.Break:
+			if result.Label == "" || result.Label == label {
+				return result.Value
+			}
+			return result
+		case *object.Continue:
+			if result.Label != "" && result.Label != label {
+				return result
+			}
+		default:
+			if interruptsBlock(result) {
+				return result
+			}
+		}
+
+		if !pretest {
+			if done := evalLoopCondition(condition, env); done != nil {
+				return done
+			}
+		}
+	}
+}
+
+// evalLoopCondition returns what a loop evaluates to if condition ends it,
+// NULL when it's false or the error evaluating it, and nil otherwise.
+func evalLoopCondition(condition ast.Expression, env *object.Environment) object.Object {
+	evaluated := Eval(condition, env)
+	if isError(evaluated) {
+		return evaluated
+	}
+
+	if !isTruthy(evaluated) {
+		return NULL
+	}
+	return nil
+}
+
+func evalAssertStatement(node *ast.AssertStatement, env *object.Environment) object.Object {
+	condition := Eval(node.Condition, env)
+	if isError(condition) {
+		return condition
+	}
+
+	if isTruthy(condition) {
+		return nil
+	}
+
+	source := node.Source
+	if source == "" {
+		source = node.Condition.String()
+	}
+	if node.Message == nil {
+		return newError("assertion failed: %s", source)
+	}
+
+	message := Eval(node.Message, env)
+	if isError(message) {
+		return message
+	}
+
+	return newError("assertion failed: %s: %s", source, message.Inspect())
+}
+
+func evalHashLetStatement(node *ast.HashLetStatement, val object.Object, env *object.Environment) object.Object {
+	hash, ok := val.(*object.Hash)
+	if !ok {
+		return newError("cannot destructure %s, want HASH", val.Type())
+	}
+
+	for _, name := range node.Names {
+		key := &object.String{Value: name.Value}
+
+		if pair, ok := hash.Pairs[key.HashKey()]; ok {
+			bind(env, node.Token, name.Value, pair.Value)
+		} else {
+			bind(env, node.Token, name.Value, NULL)
+		}
+	}
+
+	return nil
+}
+
+func evalMultipleLetStatement(node *ast.MultipleLetStatement, val object.Object, env *object.Environment) object.Object {
+	arr, ok := val.(*object.Array)
+	if !ok {
+		return newError("cannot unpack %s, want ARRAY", val.Type())
+	}
+
+	if len(arr.Elements) != len(node.Names) {
+		return newError("wrong number of values to unpack. got=%d, want=%d",
+			len(arr.Elements), len(node.Names))
+	}
+
+	for index, name := range node.Names {
+		bind(env, node.Token, name.Value, arr.Elements[index])
+	}
+
+	return nil
+}
+
+// bind declares name in env, mutably if the declaration started with var.
+func bind(env *object.Environment, declaration token.Token, name string, val object.Object) {
+	if declaration.Type == token.VAR {
+		env.SetMutable(name, val)
+	} else {
+		env.Set(name, val)
+	}
+}
+
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	val := Eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	found, mutable := env.Assign(node.Name.Value, val)
+	switch {
+	case !found:
+		if isBuiltinName(node.Name.Value) {
+			return newError("cannot assign to builtin %s", node.Name.Value)
+		}
+		return newError("identifier not found: %s", node.Name.Value)
+	case !mutable:
+		return newError("cannot assign to immutable binding %s", node.Name.Value)
+	}
+
+	return val
 }
 
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
@@ -381,9 +1003,49 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 		return builtin
 	}
 
+	if newBuiltin, ok := environmentBuiltins[node.Value]; ok {
+		return newBuiltin(env)
+	}
+
+	if t, ok := typesByName[node.Value]; ok {
+		return t
+	}
+
+	if constant, ok := mathConstants[node.Value]; ok {
+		return constant
+	}
+
 	return newError("identifier not found: " + node.Value)
 }
 
+// Globals returns the sorted names defined before a program starts running:
+// the builtins, the types and the math constants.
+func Globals() []string {
+	var names []string
+	for name := range builtins {
+		names = append(names, name)
+	}
+	for name := range environmentBuiltins {
+		names = append(names, name)
+	}
+	for name := range typesByName {
+		names = append(names, name)
+	}
+	for name := range mathConstants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isBuiltinName(name string) bool {
+	_, ok := builtins[name]
+	if !ok {
+		_, ok = environmentBuiltins[name]
+	}
+	return ok
+}
+
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	if input {
 		return TRUE
@@ -395,9 +1057,15 @@ func newError(format string, a ...interface{}) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(format, a...)}
 }
 
+func newErrorValue(format string, a ...interface{}) *object.ErrorValue {
+	return &object.ErrorValue{Message: fmt.Sprintf(format, a...)}
+}
+
+// isError reports whether obj aborts the evaluation of whatever produced it,
+// which is the case for errors and for exit.
 func isError(obj object.Object) bool {
 	if obj != nil {
-		return obj.Type() == object.ERROR_OBJ
+		return obj.Type() == object.ERROR_OBJ || obj.Type() == object.EXIT_OBJ
 	}
 	return false
 }
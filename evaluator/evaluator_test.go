@@ -1,10 +1,23 @@
 package evaluator
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
 	"monkey/lexer"
 	"monkey/object"
 	"monkey/parser"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestEvalIntegerExpression(t *testing.T) {
@@ -27,6 +40,15 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"3 * 2 ** 2", 12},
+		{"-2 ** 3", -8},
+		{"-2 ** 2", -4},
+		{"(-2) ** 2", 4},
+		{"-3 ** 2 + 1", -8},
+		{"7 ** 0", 1},
+		{"0 ** 0", 1},
 	}
 
 	for _, test := range tests {
@@ -44,6 +66,14 @@ func testEval(input string) object.Object {
 	return Eval(program, env)
 }
 
+// testEvalOn is testEval in an environment whose host is host.
+func testEvalOn(input string, host *object.Host) object.Object {
+	env := object.NewEnvironment()
+	env.SetHost(host)
+
+	return Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+}
+
 func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	result, ok := obj.(*object.Integer)
 	if !ok {
@@ -58,6 +88,42 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	return true
 }
 
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"2.5", 2.5},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3},
+		{"1 + 0.5", 1.5},
+		{"0.5 * 4", 2},
+		{"7 / 2.0", 3.5},
+		{"1.0 / 4", 0.25},
+		{"2.0 ** 3", 8},
+		{"4 ** 0.5", 2},
+		{"(1.5 + 2) * -2", -7},
+	}
+
+	for _, test := range tests {
+		testFloatObject(t, testEval(test.input), test.expected)
+	}
+}
+
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not Float. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if math.Abs(result.Value-expected) > 1e-9 {
+		t.Errorf("object has wrong value. got=%g, want=%g", result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -70,6 +136,10 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"1 < 1", false},
 		{"1 > 1", false},
 		{"1 == 1", true},
+		{"1.5 < 2", true},
+		{"2 > 2.5", false},
+		{"1.0 == 1", true},
+		{"0.1 + 0.2 != 0.3", true},
 		{"1 != 1", false},
 		{"1 == 2", false},
 		{"1 != 2", true},
@@ -157,6 +227,10 @@ func testNullObject(t *testing.T, obj object.Object) bool {
 	return true
 }
 
+func TestBareReturn(t *testing.T) {
+	testNullObject(t, testEval("let f = fn() { return; 5 }; f()"))
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -166,6 +240,7 @@ func TestReturnStatements(t *testing.T) {
 		{"return 10; 9;", 10},
 		{"return 2 * 5; 9;", 10},
 		{"9; return 2 * 5; 9;", 10},
+		{"let f = fn(x) {\n let y = x * 2\n return y\n}\nf(5)", 10},
 		{`
 if (10 > 1) {
 	if (10 > 1) {
@@ -212,6 +287,10 @@ func TestErrorHandling(t *testing.T) {
 			"5 + true;",
 			"type mismatch: INTEGER + BOOLEAN",
 		},
+		{
+			"let zero = 0; 1 / zero",
+			"division by zero",
+		},
 		{
 			"5 + true; 5;",
 			"type mismatch: INTEGER + BOOLEAN",
@@ -256,6 +335,94 @@ return 1;
 			`{"name": "Monkey"}[fn(x) { x }];`,
 			"unusable as hash key: FUNCTION",
 		},
+		{
+			"let {a} = [1, 2];",
+			"cannot destructure ARRAY, want HASH",
+		},
+		{
+			"let a, b = 5;",
+			"cannot unpack INTEGER, want ARRAY",
+		},
+		{
+			"let a, b = [1, 2, 3];",
+			"wrong number of values to unpack. got=3, want=2",
+		},
+		{
+			`throw "boom"; 5;`,
+			"boom",
+		},
+		{
+			`try { throw "boom"; } finally { 5 }`,
+			"boom",
+		},
+		{
+			`try { 1 } catch (e) { 2 } finally { throw "from finally"; }`,
+			"from finally",
+		},
+		{
+			`try { throw 1; } catch (e) { throw e + 1; }`,
+			"2",
+		},
+		{
+			`let fail = fn(m) { throw m; }; let f = fn() { defer fail("deferred"); 1 }; f();`,
+			"deferred",
+		},
+		{
+			`let user = {"age": 3}; user?.age?.years`,
+			"optional chaining not supported: INTEGER",
+		},
+		{
+			`1 in "one"`,
+			"type mismatch: INTEGER in STRING",
+		},
+		{
+			"1 in 2",
+			"unknown operator: INTEGER in INTEGER",
+		},
+		{
+			`[1] in {"a": 1}`,
+			"unusable as hash key: ARRAY",
+		},
+		{
+			"2 ** -1",
+			"negative exponent: -1",
+		},
+		{
+			"do { 1 } while (1 + true);",
+			"type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			"let x = 1; x = 2;",
+			"cannot assign to immutable binding x",
+		},
+		{
+			"let f = fn(x) { x = 2; }; f(1);",
+			"cannot assign to immutable binding x",
+		},
+		{
+			"var x = 1; let x = 2; x = 3;",
+			"cannot assign to immutable binding x",
+		},
+		{
+			"y = 2;",
+			"identifier not found: y",
+		},
+		{
+			"len = 2;",
+			"cannot assign to builtin len",
+		},
+		{
+			"let f = |x| x; f >> 1",
+			"unknown operator: FUNCTION >> INTEGER",
+		},
+		{
+			"assert(1 == 2);",
+			"assertion failed: (1 == 2)",
+		},
+		{
+			"let x = 1;\nassert(x > 1, \"x too small\"); 5",
+			"assertion failed: (x > 1): x too small",
+		},
 	}
 
 	for _, test := range tests {
@@ -275,6 +442,252 @@ return 1;
 	}
 }
 
+func TestAssertStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"assert(true); 5", 5},
+		{"assert(1 < 2, \"math works\"); 5", 5},
+		{"let f = fn(x) { assert(x > 0); x * 2 }; f(3)", 6},
+		{"try { assert(false); 1 } catch (e) { 2 }", 2},
+	}
+
+	for _, test := range tests {
+		testIntegerObject(t, testEval(test.input), test.expected)
+	}
+}
+
+func TestDeferStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{`let f = fn() { defer 1; 2 }; f()`, 2},
+		{`
+let f = fn() {
+	defer len("cleanup");
+	return 5;
+	10;
+};
+f();`,
+			5,
+		},
+		{`
+let g = fn(n) { n * 2 };
+let f = fn(n) {
+	defer 0;
+	g(n);
+};
+f(21);`,
+			42,
+		},
+		{`
+let fail = fn(m) { throw m; };
+let f = fn() { defer fail("boom"); 1 };
+try { f() } catch (e) { 7 }`,
+			7,
+		},
+	}
+
+	for _, test := range tests {
+		testIntegerObject(t, testEval(test.input), test.expected)
+	}
+}
+
+func TestDeferredOrder(t *testing.T) {
+	input := `
+let fail = fn(m) { throw m; };
+let f = fn() {
+	defer fail("first");
+	defer fail("second");
+	1;
+};
+try { f() } catch (e) { e }
+`
+	// Deferred expressions run last to first, so the first one deferred
+	// raises last and wins.
+	testStringObject(t, testEval(input), "first")
+}
+
+func TestDoWhileStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let i = 0; do { let i = i + 1; } while (i < 10); i", 10},
+		{"let i = 0; do { let i = i + 1; } while (false); i", 1},
+		{"let i = 5; do { let i = i * 2; } while (i < 5); i", 10},
+		{"let f = fn() { let i = 0; do { let i = i + 1; if (i == 3) { return i; } } while (true); 0 }; f()", 3},
+	}
+
+	for _, test := range tests {
+		testIntegerObject(t, testEval(test.input), test.expected)
+	}
+}
+
+func TestWhileStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"var i = 0; while (i < 10) { i = i + 1; } i", 10},
+		{"var i = 0; while (false) { i = 1; } i", 0},
+		{"while (false) { 1 }", nil},
+		{"var i = 0; var sum = 0; while (i < 4) { i = i + 1; if (i == 2) { continue; } sum = sum + i; } sum", 8},
+		{`
+var i = 0;
+var count = 0;
+outer: while (true) {
+	i = i + 1;
+	var j = 0;
+	while (j < 5) {
+		j = j + 1;
+		if (j == 3) { continue outer; }
+		if (i == 3) { break outer; }
+		count = count + 1;
+	}
+}
+count`,
+			4,
+		},
+		{"var i = 0; let x = while (true) { i = i + 1; if (i == 4) { break i * 10; } }; x", 40},
+		{`
+var i = 0;
+let x = outer: while (i < 10) {
+	i = i + 1;
+	do {
+		if (i == 3) { break outer i * 100; }
+	} while (false);
+};
+x`,
+			300,
+		},
+		{"let x = outer: while (false) { break outer 1; }; x", nil},
+		{"let outer = 2; let h = {outer: 1}; h[2]", 1},
+		{"let f = fn(n) { var i = 0; while (true) { i = i + 1; if (i == n) { return i; } } }; f(3)", 3},
+		{"while (nope) { 1 }", "identifier not found: nope"},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		switch expected := test.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok || errObj.Message != expected {
+				t.Errorf("wrong error for %q. expected=%q, got=%+v", test.input, expected, evaluated)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestLoopControl(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let i = 0; do { let i = i + 1; if (i == 5) { break; } } while (true); i", 5},
+		{`
+let i = 0;
+let sum = 0;
+do {
+	let i = i + 1;
+	if (i == 2) { continue; }
+	let sum = sum + i;
+} while (i < 4);
+sum`,
+			8,
+		},
+		{`
+let i = 0;
+let count = 0;
+outer: do {
+	let i = i + 1;
+	let j = 0;
+	do {
+		let j = j + 1;
+		if (j == 3) { continue outer; }
+		if (i == 3) { break outer; }
+		let count = count + 1;
+	} while (true);
+} while (true);
+count`,
+			4,
+		},
+		{`
+let i = 0;
+outer: do {
+	let i = i + 1;
+	do { break; } while (true);
+} while (i < 3);
+i`,
+			3,
+		},
+		{`
+let f = fn() {
+	let i = 0;
+	do {
+		let i = i + 1;
+		try { break; } finally { let i = i * 10; }
+	} while (true);
+	i;
+};
+f()`,
+			10,
+		},
+	}
+
+	for _, test := range tests {
+		testIntegerObject(t, testEval(test.input), test.expected)
+	}
+}
+
+func TestLoopExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"do { 1 } while (false)", nil},
+		{"let x = do { break; } while (true); x", nil},
+		{"let x = do { break 5; } while (true); x", 5},
+		{"let i = 0; let x = do { let i = i + 1; if (i == 4) { break i * 10; } } while (true); x", 40},
+		{`
+let f = fn() {
+	outer: do {
+		do { break outer 7; } while (true);
+	} while (true);
+};
+f()`,
+			7,
+		},
+		{`
+let f = fn() {
+	outer: do {
+		let inner = do { break 3; } while (true);
+		break outer inner + 1;
+	} while (true);
+};
+f()`,
+			4,
+		},
+		{"let x = do { 1 } while (false) ?? 9; x", 9},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		integer, ok := test.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
 func TestLetStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -291,6 +704,43 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestVarStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"var a = 5; a;", 5},
+		{"var a = 5; a = 6; a;", 6},
+		{"var a = 5; a = a * 2;", 10},
+		{"var a = 1; var b = 2; a = b = 3; a + b;", 6},
+		{"var a, b = [1, 2]; a = 10; a + b;", 12},
+		{`var {a} = {"a": 1}; a = 5; a;`, 5},
+		{"var i = 0; do { i = i + 1; } while (i < 10); i", 10},
+		{`
+var count = 0;
+let increment = fn() { count = count + 1; };
+increment();
+increment();
+count;`,
+			2,
+		},
+		{`
+let newCounter = fn() {
+	var n = 0;
+	fn() { n = n + 1; };
+};
+let counter = newCounter();
+counter();
+counter();`,
+			2,
+		},
+	}
+
+	for _, test := range tests {
+		testIntegerObject(t, testEval(test.input), test.expected)
+	}
+}
+
 func TestFunctionObject(t *testing.T) {
 	input := "fn(x) { x + 2; };"
 
@@ -327,6 +777,45 @@ func TestFunctionApplication(t *testing.T) {
 		{"let add = fn(x, y) { x + y; } add(5, 5);", 10},
 		{"let add = fn(x, y) { x + y; } add(5 + 5, add(5, 5));", 20},
 		{"fn(x) { x; }(5)", 5},
+		{"let double = |x| x * 2; double(5);", 10},
+		{"let add = |x, y| x + y; add(2, 3);", 5},
+		{"let answer = || 42; answer();", 42},
+		{"let adder = |x| |y| x + y; adder(1)(2);", 3},
+		{"5 |> |x| x + 1", 6},
+	}
+
+	for _, test := range tests {
+		testIntegerObject(t, testEval(test.input), test.expected)
+	}
+}
+
+func TestPipeOperator(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let double = fn(x) { x * 2 }; 5 |> double", 10},
+		{"let add = fn(x, y) { x + y }; let double = fn(x) { x * 2 }; 5 |> double |> add(1)", 11},
+		{`"hello" |> len`, 5},
+		{"[1, 2, 3] |> push(4) |> len", 4},
+	}
+
+	for _, test := range tests {
+		testIntegerObject(t, testEval(test.input), test.expected)
+	}
+}
+
+func TestFunctionComposition(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let inc = |x| x + 1; let double = |x| x * 2; (inc >> double)(5)", 12},
+		{"let inc = |x| x + 1; let double = |x| x * 2; (double >> inc)(5)", 11},
+		{"let inc = |x| x + 1; let f = inc >> inc >> inc; f(0)", 3},
+		{`let f = rest >> len; f([1, 2, 3])`, 2},
+		{"let add = fn(a, b) { a + b }; let double = |x| x * 2; (add >> double)(1, 2)", 6},
+		{"let double = |x| x * 2; 5 |> double >> double", 20},
 	}
 
 	for _, test := range tests {
@@ -382,8 +871,92 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
+		{`len("héllo")`, 5},
+		{`len("日本語")`, 3},
+		{`len({})`, 0},
+		{`len({"a": 1, "b": 2})`, 2},
 		{`len(1)`, "argument to `len` not supported, got INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
+		{`split(1, ",")`, "first argument to `split` must be STRING, got INTEGER"},
+		{`split("a", 1)`, "second argument to `split` must be STRING, got INTEGER"},
+		{`join([1, 2], ",")`, "elements passed to `join` must be STRING, got INTEGER"},
+		{`join("ab", ",")`, "first argument to `join` must be ARRAY, got STRING"},
+		{`upper(1)`, "argument to `upper` must be STRING, got INTEGER"},
+		{`trim("a", "b")`, "wrong number of arguments. got=2, want=1"},
+		{`replace("a", "b")`, "wrong number of arguments. got=2, want=3"},
+		{`replace("a", 1, "c")`, "arguments to `replace` must be STRING, got INTEGER"},
+		{`contains("a", 1)`, "arguments to `contains` must be STRING, got INTEGER"},
+		{`index_of("a")`, "wrong number of arguments. got=1, want=2"},
+		{`substring("abc", 2, 4)`, "substring bounds out of range [2:4] with length 3"},
+		{`substring("abc", 2, 1)`, "substring bounds out of range [2:1] with length 3"},
+		{`substring("abc", "1", 2)`, "second argument to `substring` must be INTEGER, got STRING"},
+		{`repeat("a", -1)`, "negative repeat count: -1"},
+		{`repeat("ab", 9223372036854775807)`, "repeat count too large: 9223372036854775807"},
+		{`repeat("ab", 1073741824)`, "repeat count too large: 1073741824"},
+		{`repeat(1, 2)`, "first argument to `repeat` must be STRING, got INTEGER"},
+		{`chars(1)`, "argument to `chars` must be STRING, got INTEGER"},
+		{`ord("ab")`, `argument to ` + "`ord`" + ` must be a single character, got "ab"`},
+		{`ord("")`, `argument to ` + "`ord`" + ` must be a single character, got ""`},
+		{`chr(-1)`, "invalid character code: -1"},
+		{`chr(55296)`, "invalid character code: 55296"},
+		{`chr("a")`, "argument to `chr` must be INTEGER, got STRING"},
+		{"keys([1])", "argument to `keys` must be HASH, got ARRAY"},
+		{"values({}, {})", "wrong number of arguments. got=2, want=1"},
+		{`delete([1], 0)`, "first argument to `delete` must be HASH, got ARRAY"},
+		{`has_key({}, [1])`, "unusable as hash key: ARRAY"},
+		{`merge()`, "wrong number of arguments. got=0, want at least 1"},
+		{`merge({}, [])`, "arguments to `merge` must be HASH, got ARRAY"},
+		{`int("4x")`, `cannot convert "4x" to INTEGER`},
+		{`int([])`, "cannot convert ARRAY to INTEGER"},
+		{`float("abc")`, `cannot convert "abc" to FLOAT`},
+		{`float(true)`, "cannot convert BOOLEAN to FLOAT"},
+		{`str()`, "wrong number of arguments. got=0, want=1"},
+		{`parse_int(1)`, "first argument to `parse_int` must be STRING, got INTEGER"},
+		{`parse_int("1", 1)`, "invalid base for `parse_int`: 1"},
+		{`parse_float(1.5)`, "argument to `parse_float` must be STRING, got FLOAT"},
+		{`read_file(1)`, "argument to `read_file` must be STRING, got INTEGER"},
+		{`write_file("x", 1)`, "second argument to `write_file` must be STRING, got INTEGER"},
+		{`append_file("x")`, "wrong number of arguments. got=1, want=2"},
+		{`hex_encode(1)`, "argument to `hex_encode` must be STRING, got INTEGER"},
+		{`error(1)`, "argument to `error` must be STRING, got INTEGER"},
+		{`error_message("boom")`, "argument to `error_message` must be ERROR_VALUE, got STRING"},
+		{`is_error()`, "wrong number of arguments. got=0, want=1"},
+		{`sha256(1)`, "argument to `sha256` must be STRING, got INTEGER"},
+		{`hmac_sha256("k")`, "wrong number of arguments. got=1, want=2"},
+		{`re_match("(", "x")`, "re_match: error parsing regexp: missing closing ): `(`"},
+		{`re_find_all("a", 1)`, "arguments to `re_find_all` must be STRING, got INTEGER"},
+		{`re_replace("a", "b")`, "wrong number of arguments. got=2, want=3"},
+		{`sleep("1")`, "argument to `sleep` must be INTEGER, got STRING"},
+		{`now(1)`, "wrong number of arguments. got=1, want=0"},
+		{`getenv(1)`, "argument to `getenv` must be STRING, got INTEGER"},
+		{`setenv("A", 1)`, "arguments to `setenv` must be STRING, got INTEGER"},
+		{`args(1)`, "wrong number of arguments. got=1, want=0"},
+		{`exit("1")`, "argument to `exit` must be INTEGER, got STRING"},
+		{`http_get(1)`, "argument to `http_get` must be STRING, got INTEGER"},
+		{`http_post("x", "y", [])`, "third argument to `http_post` must be HASH, got ARRAY"},
+		{`http_post("http://localhost", "", {"a": 1})`, "http_post: headers must map STRING to STRING, got STRING: INTEGER"},
+		{`serve("80", len)`, "first argument to `serve` must be INTEGER, got STRING"},
+		{`serve(80, 1)`, "second argument to `serve` must be FUNCTION, got INTEGER"},
+		{`tcp_connect("localhost", "80")`, "second argument to `tcp_connect` must be INTEGER, got STRING"},
+		{`read(1)`, "first argument to `read` must be CONNECTION, got INTEGER"},
+		{`write("a", "b")`, "first argument to `write` must be CONNECTION, got STRING"},
+		{`close([])`, "argument to `close` must be CONNECTION or LISTENER, got ARRAY"},
+		{`exec()`, "wrong number of arguments. got=0, want at least 1"},
+		{`exec("ls", 1)`, "arguments to `exec` must be STRING, got INTEGER"},
+		{`time_format("0", "2006")`, "first argument to `time_format` must be INTEGER, got STRING"},
+		{`time_parse("yesterday", "2006-01-02")`, `time_parse: parsing time "yesterday" as "2006-01-02": cannot parse "yesterday" as "2006"`},
+		{`time_parse(0, "2006")`, "arguments to `time_parse` must be STRING, got INTEGER"},
+		{`read_line(1)`, "wrong number of arguments. got=1, want=0"},
+		{`input(1)`, "argument to `input` must be STRING, got INTEGER"},
+		{`format()`, "wrong number of arguments. got=0, want at least 1"},
+		{`format(1)`, "first argument to `format` must be STRING, got INTEGER"},
+		{`format("%d")`, "missing argument for %d in format"},
+		{`format("%d", "a")`, "%d in format requires INTEGER, got STRING"},
+		{`format("%s", 1, 2)`, "too many arguments for format. got=2, want=1"},
+		{`format("%x", 1)`, "unknown format verb %x"},
+		{`format("50%")`, "format string ends with a lone %"},
+		{`path_join("a", 1)`, "arguments to `path_join` must be STRING, got INTEGER"},
+		{`path_ext(1)`, "argument to `path_ext` must be STRING, got INTEGER"},
 	}
 
 	for _, test := range tests {
@@ -408,38 +981,1471 @@ func TestBuiltinFunctions(t *testing.T) {
 	}
 }
 
-func TestArrayLiterals(t *testing.T) {
-	input := "[1, 2 * 2, 3 + 3]"
-
-	evaluated := testEval(input)
-	result, ok := evaluated.(*object.Array)
-	if !ok {
-		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
-	}
-
-	if len(result.Elements) != 3 {
-		t.Fatalf("array has wrong num of elements. got=%d", len(result.Elements))
-	}
-
-	testIntegerObject(t, result.Elements[0], 1)
-	testIntegerObject(t, result.Elements[1], 4)
-	testIntegerObject(t, result.Elements[2], 6)
-}
-
-func TestArrayIndexExpressions(t *testing.T) {
+func TestMathBuiltins(t *testing.T) {
 	tests := []struct {
 		input    string
 		expected interface{}
 	}{
-		{
-			"[1, 2, 3][0]",
-			1,
-		},
-		{
-			"[1, 2, 3][1]",
-			2,
-		},
-		{
+		{"abs(-5)", 5},
+		{"abs(5)", 5},
+		{"abs(0)", 0},
+		{"floor(7)", 7},
+		{"ceil(-7)", -7},
+		{"round(3)", 3},
+		{"abs(-2.5)", 2.5},
+		{"floor(2.7)", 2},
+		{"floor(-2.5)", -3},
+		{"ceil(2.1)", 3},
+		{"round(2.5)", 3},
+		{"round(2.4)", 2},
+		{"sqrt(16)", 4.0},
+		{"sqrt(2.25)", 1.5},
+		{"sqrt(0)", 0.0},
+		{"pow(2, 0.5) * pow(2, 0.5)", 2.0},
+		{"pow(2.0, 3)", 8.0},
+		{"sin(0)", 0.0},
+		{"cos(0)", 1.0},
+		{"sin(PI / 2)", 1.0},
+		{"tan(PI / 4)", 1.0},
+		{"exp(1)", math.E},
+		{"log(E)", 1.0},
+		{"log2(8)", 3.0},
+		{"PI", math.Pi},
+		{"pow(2, 10)", 1024},
+		{"pow(-3, 3)", -27},
+		{"pow(5, 0)", 1},
+		{`abs("1")`, "argument to `abs` must be INTEGER or FLOAT, got STRING"},
+		{"log(0)", "`log` of non-positive number: 0"},
+		{"log2(-1.5)", "`log2` of non-positive number: -1.5"},
+		{"sqrt(-0.5)", "square root of negative number: -0.5"},
+		{"abs(1, 2)", "wrong number of arguments. got=2, want=1"},
+		{"sqrt(-4)", "square root of negative number: -4"},
+		{`pow("2", 1)`, "arguments to `pow` must be INTEGER or FLOAT, got STRING"},
+		{"pow(2, -1)", "negative exponent: -1"},
+		{"pow(2)", "wrong number of arguments. got=1, want=2"},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q",
+					expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestRandomBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"let r = rand(); if (r < 0) { false } else { r < 1 }", true},
+		{"type(rand()) == FLOAT", true},
+		{"max(map(range(100), |_| rand_int(3))) < 3", true},
+		{"min(map(range(100), |_| rand_int(3))) > -1", true},
+		{"seed(42); let a = rand_int(1000000); seed(42); a == rand_int(1000000)", true},
+		{"seed(1); let a = rand(); let f = fn() { seed(1); rand() }; a == f()", true},
+	}
+
+	for _, test := range tests {
+		testBooleanObject(t, testEval(test.input), test.expected)
+	}
+
+	first := object.NewEnvironment()
+	second := object.NewEnvironment()
+	evalIn := func(input string, env *object.Environment) object.Object {
+		return Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+	}
+	evalIn("seed(7)", first)
+	evalIn("seed(7)", second)
+	evalIn("rand()", first)
+
+	a := evalIn("rand_int(1000000)", first)
+	b := evalIn("rand_int(1000000)", second)
+	if objectsEqual(a, b) {
+		t.Errorf("interpreters share a random source. got %s twice", a.Inspect())
+	}
+
+	for input, expected := range map[string]string{
+		"rand(1)":       "wrong number of arguments. got=1, want=0",
+		"rand_int(0)":   "argument to `rand_int` must be positive, got 0",
+		`rand_int("a")`: "argument to `rand_int` must be INTEGER, got STRING",
+		"seed(1.5)":     "argument to `seed` must be INTEGER, got FLOAT",
+		"rand = 1":      "cannot assign to builtin rand",
+	} {
+		errObj, ok := testEval(input).(*object.Error)
+		if !ok {
+			t.Errorf("%s did not return an Error", input)
+			continue
+		}
+		if errObj.Message != expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+		}
+	}
+}
+
+func TestConversionBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`int("42")`, 42},
+		{`int(" -7 ")`, -7},
+		{`int(3.9)`, 3},
+		{`int(-3.9)`, -3},
+		{`int(true)`, 1},
+		{`int(5)`, 5},
+		{`float("3.5")`, 3.5},
+		{`float(2)`, 2.0},
+		{`float("1e3")`, 1000.0},
+		{`str(42)`, "42"},
+		{`str(1.5)`, "1.5"},
+		{`str([1, "a"])`, "[1, a]"},
+		{`str("a")`, "a"},
+		{`str(int("12")) + "!"`, "12!"},
+		{`bool(0)`, true},
+		{`bool("")`, true},
+		{`bool([])`, true},
+		{`bool(false)`, false},
+		{`bool(if (false) { 1 })`, false},
+		{`parse_int("42")`, 42},
+		{`parse_int("-42")`, -42},
+		{`parse_int("ff", 16)`, 255},
+		{`parse_int("101", 2)`, 5},
+		{`is_error(parse_int("42x"))`, true},
+		{`is_error(parse_int(" 42"))`, true},
+		{`error_message(parse_int(""))`, `cannot parse "" as INTEGER`},
+		{`let n = parse_int("x"); if (is_error(n)) { 0 } else { n }`, 0},
+		{`parse_float("2.5")`, 2.5},
+		{`error_message(parse_float("2.5.1"))`, `cannot parse "2.5.1" as FLOAT`},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		case string:
+			testStringObject(t, evaluated, expected)
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case nil:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestFileBuiltins(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{fmt.Sprintf(`write_file(%q, "one")`, path), nil},
+		{fmt.Sprintf(`read_file(%q)`, path), "one"},
+		{fmt.Sprintf(`append_file(%q, ", two")`, path), nil},
+		{fmt.Sprintf(`read_file(%q)`, path), "one, two"},
+		{fmt.Sprintf(`write_file(%q, "three"); read_file(%q)`, path, path), "three"},
+		{fmt.Sprintf(`let content = read_file(%q); if (is_error(content)) { "missing" }`, path+".missing"), "missing"},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case string:
+			testStringObject(t, evaluated, expected)
+		case nil:
+			testNullObject(t, evaluated)
+		}
+	}
+
+	evaluated := testEval(fmt.Sprintf(`read_file(%q)`, path+".missing"))
+	errObj, ok := evaluated.(*object.ErrorValue)
+	if !ok {
+		t.Fatalf("object is not ErrorValue. got=%T (%+v)", evaluated, evaluated)
+	}
+	if !strings.HasPrefix(errObj.Message, "read_file: open ") {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestFileSystemBuiltins(t *testing.T) {
+	dir := t.TempDir()
+	nested := filepath.Join(dir, "a", "b")
+	file := filepath.Join(dir, "a", "notes.txt")
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{fmt.Sprintf(`exists(%q)`, nested), false},
+		{fmt.Sprintf(`mkdir(%q)`, nested), nil},
+		{fmt.Sprintf(`is_dir(%q)`, nested), true},
+		{fmt.Sprintf(`write_file(%q, "x"); exists(%q)`, file, file), true},
+		{fmt.Sprintf(`is_dir(%q)`, file), false},
+		{fmt.Sprintf(`join(list_dir(%q), ",")`, filepath.Join(dir, "a")), "b,notes.txt"},
+		{fmt.Sprintf(`is_error(remove(%q))`, filepath.Join(dir, "a")), true},
+		{fmt.Sprintf(`remove(%q); remove(%q); exists(%q)`, file, nested, nested), false},
+		{fmt.Sprintf(`is_error(list_dir(%q))`, nested), true},
+		{`exists(1)`, "argument to `exists` must be STRING, got INTEGER"},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				if errObj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q",
+						expected, errObj.Message)
+				}
+				continue
+			}
+			testStringObject(t, evaluated, expected)
+		}
+	}
+
+	host := object.NewHost()
+	host.Sandbox.DisableFileWrites = true
+
+	for _, name := range []string{"mkdir", "remove"} {
+		errObj, ok := testEvalOn(fmt.Sprintf(`%s(%q)`, name, nested), host).(*object.Error)
+		if !ok || errObj.Message != name+" is disabled by the sandbox" {
+			t.Errorf("%s was not disabled by the sandbox. got=%v", name, errObj)
+		}
+	}
+	errObj, ok := testEvalOn(fmt.Sprintf(`write_file(%q, "y")`, file), host).(*object.Error)
+	if !ok || errObj.Message != "write_file is disabled by the sandbox" {
+		t.Errorf("write_file was not disabled by the sandbox. got=%v", errObj)
+	}
+	testBooleanObject(t, testEval(fmt.Sprintf(`exists(%q)`, file)), false)
+}
+
+func TestPathBuiltins(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`path_join("a", "b", "c.txt")`, filepath.Join("a", "b", "c.txt")},
+		{`path_join("a/", "../b")`, "b"},
+		{`path_join()`, ""},
+		{`path_base("dir/file.tar.gz")`, "file.tar.gz"},
+		{`path_base("")`, "."},
+		{`path_dir("dir/sub/file")`, filepath.Join("dir", "sub")},
+		{`path_dir("file")`, "."},
+		{`path_ext("archive.tar.gz")`, ".gz"},
+		{`path_ext("Makefile")`, ""},
+		{`path_abs("x")`, filepath.Join(wd, "x")},
+	}
+
+	for _, test := range tests {
+		testStringObject(t, testEval(test.input), test.expected)
+	}
+}
+
+func TestConsoleInput(t *testing.T) {
+	host := object.NewHost()
+	host.Stdin = strings.NewReader("first\r\nsecond\nlast")
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"read_line()", "first"},
+		{"read_line()", "second"},
+		{"read_line()", "last"},
+		{"read_line()", nil},
+		{"input()", nil},
+	}
+
+	for _, test := range tests {
+		evaluated := testEvalOn(test.input, host)
+
+		switch expected := test.expected.(type) {
+		case string:
+			testStringObject(t, evaluated, expected)
+		case nil:
+			testNullObject(t, evaluated)
+		}
+	}
+
+	host.Stdin = strings.NewReader("a\nb\n")
+	testStringObject(t, testEvalOn(`let n = read_line(); n + input()`, host), "ab")
+}
+
+func TestConsoleOutput(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	host := object.NewHost()
+	host.Stdin, host.Stdout, host.Stderr = strings.NewReader("monkey\n"), &stdout, &stderr
+
+	testNullObject(t, testEvalOn(`puts("a", 1); prints("b", [2]); prints("c"); eprintln("oops", 3)`, host))
+	testStringObject(t, testEvalOn(`input("name? ")`, host), "monkey")
+	testNullObject(t, testEvalOn(`let f = fn() { eval("prints(4)") }; f()`, host))
+
+	if got, want := stdout.String(), "a\n1\nb [2]cname? 4"; got != want {
+		t.Errorf("wrong stdout. got=%q, want=%q", got, want)
+	}
+	if got, want := stderr.String(), "oops 3\n"; got != want {
+		t.Errorf("wrong stderr. got=%q, want=%q", got, want)
+	}
+}
+
+func TestJSONBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`json_parse("42")`, 42},
+		{`json_parse("2.5")`, 2.5},
+		{`json_parse("1e2")`, 100.0},
+		{`json_parse("true")`, true},
+		{`json_parse("null")`, nil},
+		{`json_parse("[1, 2, 3]")[2]`, 3},
+		{`json_parse("{'a': 1}")`, `json_parse: invalid character '\'' looking for beginning of object key string`},
+		{`json_parse("[1] 2")`, "json_parse: unexpected data after top-level value"},
+		{`let h = json_parse(json_stringify({"a": [1, {"b": if (false) { 0 }}]})); h["a"][1]["b"]`, nil},
+		{`json_stringify({"b": [1, 2.5, "x"], "a": true})`, `{"b":[1,2.5,"x"],"a":true}`},
+		{`json_stringify(json_parse(replace("{'z': 1, 'a': {'y': [], 'b': '<>'}, 'm': null}", "'", chr(34))))`, `{"z":1,"a":{"y":[],"b":"<>"},"m":null}`},
+		{`join(keys(json_parse(replace("{'c': 1, 'a': 2, 'b': 3, 'a': 4}", "'", chr(34)))), ",")`, "c,a,b"},
+		{`json_parse(replace("{'c': 1, 'a': 2, 'a': 4}", "'", chr(34)))["a"]`, 4},
+		{`json_stringify({"b": {"d": 1, "c": 2}}, 1)`, "{\n \"b\": {\n  \"d\": 1,\n  \"c\": 2\n }\n}"},
+		{`json_parse("[1, [2]")`, "json_parse: unexpected EOF"},
+		{`json_stringify([1, [2]], 2)`, "[\n  1,\n  [\n    2\n  ]\n]"},
+		{`json_stringify("<a>")`, `"<a>"`},
+		{`json_stringify({1: 2})`, "json_stringify: hash keys must be STRING, got INTEGER"},
+		{`json_stringify([len])`, "json_stringify: cannot encode BUILTIN"},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				if errObj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q",
+						expected, errObj.Message)
+				}
+				continue
+			}
+			testStringObject(t, evaluated, expected)
+		}
+	}
+
+	// String literals can't contain double quotes, so the JSON text is
+	// bound directly.
+	env := object.NewEnvironment()
+	env.Set("text", &object.String{Value: `{"name": "monkey", "tags": ["a"], "n": 1.5}`})
+	program := parser.New(lexer.New(`let h = json_parse(text); h["name"] + h["tags"][0] + str(h["n"])`)).ParseProgram()
+	testStringObject(t, Eval(program, env), "monkeya1.5")
+}
+
+func TestCSVBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`len(csv_parse("a,b
+c,d,e
+"))`, 2},
+		{`csv_parse("a,b
+c,d,e")[1][2]`, "e"},
+		{`len(csv_parse(""))`, 0},
+		{`csv_parse("x," + chr(34) + "y,z" + chr(34))[0][1]`, "y,z"},
+		{`csv_parse("a" + chr(34) + "b")`, `csv_parse: parse error on line 1, column 2: bare " in non-quoted-field`},
+		{`csv_stringify([["name", "age"], ["monkey", 3]])`, "name,age\nmonkey,3\n"},
+		{`csv_stringify([["a,b", true]])`, "\"a,b\",true\n"},
+		{`csv_stringify([])`, ""},
+		{`let rows = csv_parse(csv_stringify([["a b", "c,d"]])); rows[0][1]`, "c,d"},
+		{`csv_parse(1)`, "argument to `csv_parse` must be STRING, got INTEGER"},
+		{`csv_stringify([1])`, "rows passed to `csv_stringify` must be ARRAY, got INTEGER"},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				if errObj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q",
+						expected, errObj.Message)
+				}
+				continue
+			}
+			testStringObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestTimeBuiltins(t *testing.T) {
+	before := time.Now().UnixMilli()
+	now, ok := testEval("now()").(*object.Integer)
+	if !ok || now.Value < before || now.Value > time.Now().UnixMilli() {
+		t.Errorf("now() returned %v, want a timestamp after %d", now, before)
+	}
+
+	testBooleanObject(t, testEval("let start = clock(); sleep(5); clock() - start > 4.9"), true)
+	testBooleanObject(t, testEval("let a = clock(); a > clock()"), false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	host := object.NewHost()
+	host.Context = ctx
+
+	start := time.Now()
+	evaluated := testEvalOn("sleep(60000)", host)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("sleep ignored the context and took %s", elapsed)
+	}
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "sleep interrupted: context deadline exceeded" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestDateBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`time_format(0, "2006-01-02 15:04:05")`, "1970-01-01 00:00:00"},
+		{`time_format(1700000000123, "2006-01-02T15:04:05.000Z07:00")`, "2023-11-14T22:13:20.123Z"},
+		{`time_parse("2023-11-14 22:13:20", "2006-01-02 15:04:05")`, 1700000000000},
+		{`time_parse("2023-11-14T23:13:20+01:00", "2006-01-02T15:04:05Z07:00")`, 1700000000000},
+		{`let layout = "Jan 2 2006"; time_format(time_parse("Mar 7 2021", layout), layout)`, "Mar 7 2021"},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testStringObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestSystemBuiltins(t *testing.T) {
+	t.Setenv("MONKEY_TEST_VAR", "banana")
+
+	host := object.NewHost()
+	host.Args = []string{"one", "two"}
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`getenv("MONKEY_TEST_VAR")`, "banana"},
+		{`getenv("MONKEY_TEST_UNSET_VAR")`, nil},
+		{`setenv("MONKEY_TEST_VAR", "kiwi"); getenv("MONKEY_TEST_VAR")`, "kiwi"},
+		{`join(args(), ",")`, "one,two"},
+	}
+
+	for _, test := range tests {
+		evaluated := testEvalOn(test.input, host)
+
+		switch expected := test.expected.(type) {
+		case string:
+			testStringObject(t, evaluated, expected)
+		case nil:
+			testNullObject(t, evaluated)
+		}
+	}
+
+	if got := os.Getenv("MONKEY_TEST_VAR"); got != "kiwi" {
+		t.Errorf("setenv didn't change the process environment. got=%q", got)
+	}
+}
+
+func TestPrettyPrint(t *testing.T) {
+	var out bytes.Buffer
+	host := object.NewHost()
+	host.Stdout = &out
+
+	testNullObject(t, testEvalOn(`pp({"b": [1, "two", []], "a": {}, "c": {"d": true}}, 5, "s")`, host))
+
+	expected := `{
+  "b": [
+    1,
+    "two",
+    []
+  ],
+  "a": {},
+  "c": {
+    "d": true
+  }
+}
+5
+"s"
+`
+	if out.String() != expected {
+		t.Errorf("wrong output. got=%q, want=%q", out.String(), expected)
+	}
+
+	cyclic := &object.Array{}
+	cyclic.Elements = []object.Object{&object.Integer{Value: 1}, cyclic}
+	shared := &object.Array{}
+	var printed strings.Builder
+	prettyPrint(&printed, &object.Array{Elements: []object.Object{cyclic, shared, shared}}, "", make(map[object.Object]bool))
+
+	expected = "[\n  [\n    1,\n    [...]\n  ],\n  [],\n  []\n]"
+	if printed.String() != expected {
+		t.Errorf("wrong output for cycles. got=%q, want=%q", printed.String(), expected)
+	}
+}
+
+func TestExitBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"exit()", 0},
+		{"exit(3); 5", 3},
+		{"let f = fn() { exit(4); 1 }; f() + 1", 4},
+		{"try { exit(5) } catch (e) { 1 }", 5},
+		{"map([1, 2, 3], fn(x) { if (x == 2) { exit(x) } x })", 2},
+		{"var i = 0; do { i = i + 1; if (i == 6) { exit(i) } } while (true)", 6},
+		{"let f = fn() { return exit(7) }; f()", 7},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		exit, ok := evaluated.(*object.Exit)
+		if !ok {
+			t.Errorf("object is not Exit. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if exit.Code != test.expected {
+			t.Errorf("wrong exit code. got=%d, want=%d", exit.Code, test.expected)
+		}
+	}
+
+	var out bytes.Buffer
+	host := object.NewHost()
+	host.Stdout = &out
+
+	testEvalOn(`try { exit(1) } finally { puts("finally") }; puts("after")`, host)
+	testEvalOn(`let f = fn() { defer puts("deferred"); exit(1) }; f()`, host)
+	if got, want := out.String(), "finally\ndeferred\n"; got != want {
+		t.Errorf("wrong output while exiting. got=%q, want=%q", got, want)
+	}
+}
+
+func TestExecBuiltin(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`exec("sh", "-c", "echo out; echo err >&2")["stdout"]`, "out\n"},
+		{`exec("sh", "-c", "echo out; echo err >&2")["stderr"]`, "err\n"},
+		{`exec("sh", "-c", "exit 0")["status"]`, 0},
+		{`exec("sh", "-c", "exit 3")["status"]`, 3},
+		{`exec("sh", "-c", "echo $0", "a b")["stdout"]`, "a b\n"},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testStringObject(t, evaluated, expected)
+		}
+	}
+
+	evaluated := testEval(`exec("monkey-no-such-command")`)
+	if errObj, ok := evaluated.(*object.Error); !ok || !strings.HasPrefix(errObj.Message, "exec: ") {
+		t.Errorf("expected an exec error. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	host := object.NewHost()
+	host.Sandbox.DisableExec = true
+
+	evaluated = testEvalOn(`exec("sh", "-c", "exit 0")`, host)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "exec is disabled by the sandbox" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestHTTPClientBuiltins(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Method", r.Method)
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		fmt.Fprintf(w, "%s %s %s", r.Method, r.Header.Get("X-Token"), body)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{fmt.Sprintf(`http_get(%q)["status"]`, server.URL), 200},
+		{fmt.Sprintf(`http_get(%q)["body"]`, server.URL), "GET  "},
+		{fmt.Sprintf(`http_get(%q)["headers"]["X-Method"]`, server.URL), "GET"},
+		{fmt.Sprintf(`http_get(%q)["status"]`, server.URL+"/missing"), 404},
+		{fmt.Sprintf(`http_post(%q, "hi")["body"]`, server.URL), "POST  hi"},
+		{fmt.Sprintf(`http_post(%q, "hi", {"X-Token": "t"})["body"]`, server.URL), "POST t hi"},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testStringObject(t, evaluated, expected)
+		}
+	}
+
+	defer func(timeout time.Duration) { HTTPTimeout = timeout }(HTTPTimeout)
+	HTTPTimeout = 10 * time.Millisecond
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Second)
+	}))
+	defer slow.Close()
+
+	evaluated := testEval(fmt.Sprintf(`http_get(%q)`, slow.URL))
+	if errObj, ok := evaluated.(*object.Error); !ok || !strings.HasPrefix(errObj.Message, "http_get: ") {
+		t.Errorf("expected a timeout error. got=%T (%+v)", evaluated, evaluated)
+	}
+}
+
+func TestHTTPServerHandlerPanics(t *testing.T) {
+	handler := &object.Builtin{Fn: func(args ...object.Object) object.Object { panic("boom") }}
+	server := httptest.NewServer(newMonkeyHandler(handler))
+	defer server.Close()
+
+	// The second request would hang if the first left the handler locked.
+	for i := 0; i < 2; i++ {
+		resp, err := http.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != 500 || string(body) != "handler panicked: boom\n" {
+			t.Errorf("wrong response. got=%d %q", resp.StatusCode, body)
+		}
+	}
+}
+
+func TestHTTPServerHandler(t *testing.T) {
+	handler := testEval(`
+var count = 0;
+fn(req) {
+  count = count + 1;
+  if (ends_with(req["path"], "/hash")) {
+    return {"status": 201, "body": req["method"] + " " + req["body"], "headers": {"X-Count": count}};
+  }
+  if (ends_with(req["path"], "/fail")) {
+    return 1 + true;
+  }
+  "hello " + (req["query"]["name"] ?? "nobody")
+}`)
+
+	server := httptest.NewServer(newMonkeyHandler(handler))
+	defer server.Close()
+
+	tests := []struct {
+		method, path, body string
+		status             int
+		response, count    string
+	}{
+		{"GET", "/?name=monkey", "", 200, "hello monkey", ""},
+		{"GET", "/", "", 200, "hello nobody", ""},
+		{"POST", "/hash", "data", 201, "POST data", "3"},
+		{"GET", "/fail", "", 500, "type mismatch: INTEGER + BOOLEAN\n", ""},
+	}
+
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, server.URL+test.path, strings.NewReader(test.body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != test.status {
+			t.Errorf("%s %s: wrong status. got=%d, want=%d", test.method, test.path, resp.StatusCode, test.status)
+		}
+		if string(body) != test.response {
+			t.Errorf("%s %s: wrong body. got=%q, want=%q", test.method, test.path, body, test.response)
+		}
+		if got := resp.Header.Get("X-Count"); got != test.count {
+			t.Errorf("%s %s: wrong X-Count header. got=%q, want=%q", test.method, test.path, got, test.count)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	host := object.NewHost()
+	host.Context = ctx
+
+	testNullObject(t, testEvalOn(`serve(0, fn(req) { "" })`, host))
+}
+
+func TestTCPBuiltins(t *testing.T) {
+	env := object.NewEnvironment()
+	evalIn := func(input string) object.Object {
+		return Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+	}
+
+	listener, ok := evalIn("let listener = tcp_listen(0); listener").(*object.Listener)
+	if !ok {
+		t.Fatalf("tcp_listen did not return a Listener")
+	}
+	port := listener.Listener.Addr().(*net.TCPAddr).Port
+
+	done := make(chan object.Object)
+	go func() {
+		done <- evalIn(`let server = accept(listener); let msg = read(server); write(server, upper(msg)); close(server); msg`)
+	}()
+
+	client := testEval(fmt.Sprintf(`
+let conn = tcp_connect("127.0.0.1", %d);
+write(conn, "ping");
+let reply = read(conn, 2) + read(conn);
+let eof = read(conn);
+let invalid = try { read(conn, 0) } catch (e) { e };
+close(conn);
+reply + str(eof) + ", " + invalid`, port))
+	testStringObject(t, client, "PINGnull, second argument to `read` must be positive, got 0")
+	testStringObject(t, <-done, "ping")
+	testNullObject(t, evalIn("close(listener)"))
+
+	evaluated := evalIn("accept(listener)")
+	if errObj, ok := evaluated.(*object.Error); !ok || !strings.HasPrefix(errObj.Message, "accept: ") {
+		t.Errorf("expected an accept error. got=%T (%+v)", evaluated, evaluated)
+	}
+}
+
+func TestTCPListenerStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	host := object.NewHost()
+	host.Context = ctx
+
+	evaluated := testEvalOn(`let listener = tcp_listen(0); accept(listener)`, host)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("expected an accept error. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "accept: context deadline exceeded" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestErrorValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`is_error(error("boom"))`, true},
+		{`is_error("boom")`, false},
+		{`is_error(1 + 1)`, false},
+		{`error_message(error("boom"))`, "boom"},
+		{`let e = error("boom"); 1; 2; e == e`, true},
+		{`type(error("x")) == ERROR`, true},
+		{`str(error("boom"))`, "error: boom"},
+		{`let check = fn(x) { if (x < 0) { return error("negative") } x }; is_error(check(-1))`, true},
+		{`let check = fn(x) { if (x < 0) { return error("negative") } x }; check(2)`, 2},
+		{`try { throw error("thrown") } catch (e) { error_message(e) }`, "thrown"},
+		{`error_message(base64_decode("b@d"))`, "base64_decode: illegal base64 data at input byte 1"},
+		{`error_message(hex_decode("zz"))`, "hex_decode: encoding/hex: invalid byte: U+007A 'z'"},
+		{`map([1, "a"], |x| is_error(parse_int(str(x))))[1]`, true},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			testStringObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestEvalBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`eval("1 + 2")`, 3},
+		{`eval("let x = 5; x * x")`, 25},
+		{`eval("")`, nil},
+		{`let x = 2; eval("x", true)`, 2},
+		{`eval("let y = 7;", true); y`, 7},
+		{`var n = 1; eval("n = n + 1", true); n`, 2},
+		{`let f = fn(x) { eval("x * 10", true) }; f(4)`, 40},
+		{`eval("eval(" + chr(34) + "6" + chr(34) + ")")`, 6},
+		{`is_error(eval("let = 1"))`, true},
+		{`eval("x")`, "identifier not found: x"},
+		{`eval(1)`, "first argument to `eval` must be STRING, got INTEGER"},
+		{`eval("1", 1)`, "second argument to `eval` must be BOOLEAN, got INTEGER"},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q",
+					expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestTypeBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"type(5) == INT", true},
+		{"type(5.0) == FLOAT", true},
+		{"type(true) == BOOL", true},
+		{`type("five") == STRING`, true},
+		{"type([]) == ARRAY", true},
+		{"type({}) == HASH", true},
+		{"type(fn(x) { x }) == FUNCTION", true},
+		{"type(len) == BUILTIN", true},
+		{"type(if (false) { 1 }) == NULL", true},
+		{"type(INT) == TYPE", true},
+		{"type(5) == STRING", false},
+		{"type(5) != STRING", true},
+		{"type(1) == type(2)", true},
+		{"{INT: 1}[type(5)] == 1", true},
+	}
+
+	for _, test := range tests {
+		testBooleanObject(t, testEval(test.input), test.expected)
+	}
+
+	evaluated := testEval("type(5)")
+	if evaluated.Inspect() != "INT" {
+		t.Errorf("type(5) has wrong Inspect(). got=%q", evaluated.Inspect())
+	}
+}
+
+func TestStringBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`split("a,b,c", ",")`, []string{"a", "b", "c"}},
+		{`split("abc", "")`, []string{"a", "b", "c"}},
+		{`split("", ",")`, []string{""}},
+		{`split("a,,b", ",")`, []string{"a", "", "b"}},
+		{`join(["a", "b", "c"], ", ")`, "a, b, c"},
+		{`join([], ",")`, ""},
+		{`join(split("a-b", "-"), "+")`, "a+b"},
+		{`trim("  a b  ")`, "a b"},
+		{`trim_left("  a b  ")`, "a b  "},
+		{`trim_right("  a b  ")`, "  a b"},
+		{`upper("Monkey")`, "MONKEY"},
+		{`lower("Monkey")`, "monkey"},
+		{`replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`replace("abc", "x", "y")`, "abc"},
+		{`contains("monkey", "key")`, true},
+		{`contains("monkey", "ape")`, false},
+		{`starts_with("monkey", "mon")`, true},
+		{`starts_with("monkey", "key")`, false},
+		{`ends_with("monkey", "key")`, true},
+		{`ends_with("monkey", "mon")`, false},
+		{`index_of("monkey", "key")`, 3},
+		{`index_of("monkey", "ape")`, -1},
+		{`index_of("monkey", "")`, 0},
+		{`index_of("héllo", "l")`, 2},
+		{`substring("monkey", 3, 6)`, "key"},
+		{`substring("monkey", 0, 0)`, ""},
+		{`substring("héllo", 1, 3)`, "él"},
+		{`base64_encode("monkey")`, "bW9ua2V5"},
+		{`base64_decode("bW9ua2V5")`, "monkey"},
+		{`base64_decode(base64_encode("héllo"))`, "héllo"},
+		{`hex_encode("hi")`, "6869"},
+		{`hex_decode("6869")`, "hi"},
+		{`hex_encode("")`, ""},
+		{`sha256("abc")`, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{`sha1("abc")`, "a9993e364706816aba3e25717850c26c9cd0d89d"},
+		{`md5("")`, "d41d8cd98f00b204e9800998ecf8427e"},
+		{`hmac_sha256("key", "The quick brown fox jumps over the lazy dog")`, "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"},
+		{`re_match("^[a-z]+[0-9]*$", "monkey42")`, true},
+		{`re_match("^[a-z]+$", "monkey42")`, false},
+		{`re_find_all("[0-9]+", "a1b22c333")`, []string{"1", "22", "333"}},
+		{`re_find_all("x", "abc")`, []string{}},
+		{`re_replace("([a-z]+)@([a-z]+)", "me@host you@there", "$2:$1")`, "host:me there:you"},
+		{`re_replace("a+", "caaandy", "-")`, "c-ndy"},
+		{`substring("héllo", 0, len("héllo"))`, "héllo"},
+		{`substring("monkey", index_of("monkey", "n"), 4)`, "nk"},
+		{`repeat("ab", 3)`, "ababab"},
+		{`repeat("ab", 0)`, ""},
+		{`len(repeat("", 9223372036854775807))`, 0},
+		{`chars("abc")`, []string{"a", "b", "c"}},
+		{`chars("hé!")`, []string{"h", "é", "!"}},
+		{`chars("")`, []string{}},
+		{`ord("a")`, 97},
+		{`ord("é")`, 233},
+		{`chr(97)`, "a"},
+		{`chr(233)`, "é"},
+		{`chr(ord("a") + 1)`, "b"},
+		{`join(map(chars("hal"), |c| chr(ord(c) + 1)), "")`, "ibm"},
+		{`format("name=%s count=%d", "monkey", 3)`, "name=monkey count=3"},
+		{`format("%v and %s", [1, 2], {"a": true})`, "[1, 2] and {a: true}"},
+		{`format("%f", 2)`, "2.000000"},
+		{`format("%f %v %v", 0.5, 2.0, 1.25)`, "0.500000 2.0 1.25"},
+		{`format("100%%")`, "100%"},
+		{`format("plain")`, "plain"},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			testStringObject(t, evaluated, expected)
+		case []string:
+			arr, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if len(arr.Elements) != len(expected) {
+				t.Errorf("wrong num of elements. want=%d, got=%d",
+					len(expected), len(arr.Elements))
+				continue
+			}
+			for i, expectedElement := range expected {
+				testStringObject(t, arr.Elements[i], expectedElement)
+			}
+		}
+	}
+}
+
+func TestHigherOrderBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"map([1, 2, 3], fn(x) { x * 2 })", []int64{2, 4, 6}},
+		{"map([], fn(x) { x * 2 })", []int64{}},
+		{"map([1, 2], |x| x + 1)", []int64{2, 3}},
+		{"let y = 10; map([1, 2], fn(x) { x + y })", []int64{11, 12}},
+		{"filter([1, 2, 3, 4], fn(x) { x > 2 })", []int64{3, 4}},
+		{"filter([1, 2], fn(x) { false })", []int64{}},
+		{"reduce([1, 2, 3, 4], 0, fn(acc, x) { acc + x })", 10},
+		{"reduce([], 7, fn(acc, x) { acc + x })", 7},
+		{"map([[1], [2, 3]], len)", []int64{1, 2}},
+		{"reduce(map(filter([1, 2, 3, 4], |x| x > 1), |x| x * x), 0, |a, b| a + b)", 29},
+		{"sort([3, 1, 2])", []int64{1, 2, 3}},
+		{"sort([])", []int64{}},
+		{"let a = [2, 1]; sort(a); a", []int64{2, 1}},
+		{"sort([1, 3, 2], fn(a, b) { a > b })", []int64{3, 2, 1}},
+		{`map(sort(["bb", "a", "ccc"]), len)`, []int64{1, 2, 3}},
+		{"map(sort([[2, 1], [1, 2], [2, 2], [1, 3]], fn(a, b) { a[0] < b[0] }), |p| p[1])", []int64{2, 3, 1, 2}},
+		{`sort([1, "a"])`, "cannot sort STRING and INTEGER without a comparator"},
+		{"sort([2, 1], fn(a, b) { a + true })", "type mismatch: INTEGER + BOOLEAN"},
+		{"sort(1)", "first argument to `sort` must be ARRAY, got INTEGER"},
+		{"sort([1], 2)", "second argument to `sort` must be FUNCTION, got INTEGER"},
+		{"var total = 0; each([1, 2, 3], fn(x) { total = total + x }); total", 6},
+		{"each([], fn(x) { x })", nil},
+		{"each([1], fn(x) { x + true })", "type mismatch: INTEGER + BOOLEAN"},
+		{"each(1, len)", "first argument to `each` must be ARRAY, got INTEGER"},
+		{"map(enumerate([5, 6]), |p| p[0])", []int64{0, 1}},
+		{"map(enumerate([5, 6]), |p| p[1])", []int64{5, 6}},
+		{"enumerate([])", []int64{}},
+		{"enumerate({})", "argument to `enumerate` must be ARRAY, got HASH"},
+		{"map([1, 2], fn(x) { x + true })", "type mismatch: INTEGER + BOOLEAN"},
+		{"map(1, len)", "first argument to `map` must be ARRAY, got INTEGER"},
+		{"filter([1], 1)", "argument to `filter` must be FUNCTION, got INTEGER"},
+		{"reduce([1], fn(a, b) { a })", "wrong number of arguments. got=2, want=3"},
+		{"map([1, 2], fn(a, b) { a + b })", "wrong number of arguments. got=1, want=2"},
+		{"filter([1], fn() { true })", "wrong number of arguments. got=1, want=0"},
+		{"reduce([1, 2], 0, fn(a) { a })", "wrong number of arguments. got=2, want=1"},
+		{"sort([2, 1], fn(a) { a })", "wrong number of arguments. got=2, want=1"},
+		{"let f = fn(a, b) { a }; f(1)", "wrong number of arguments. got=1, want=2"},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q",
+					expected, errObj.Message)
+			}
+		case []int64:
+			testIntegerArray(t, evaluated, expected)
+		}
+	}
+}
+
+func TestArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if len(result.Elements) != 3 {
+		t.Fatalf("array has wrong num of elements. got=%d", len(result.Elements))
+	}
+
+	testIntegerObject(t, result.Elements[0], 1)
+	testIntegerObject(t, result.Elements[1], 4)
+	testIntegerObject(t, result.Elements[2], 6)
+}
+
+func TestArrayBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"reverse([1, 2, 3])", []int64{3, 2, 1}},
+		{"reverse([])", []int64{}},
+		{"let a = [1, 2]; reverse(a); a", []int64{1, 2}},
+		{"concat([1], [2, 3])", []int64{1, 2, 3}},
+		{"concat([1], [], [2], [3])", []int64{1, 2, 3}},
+		{"concat()", []int64{}},
+		{"flatten([[1, 2], 3, [4]])", []int64{1, 2, 3, 4}},
+		{"len(flatten([[[1, 2]], [3]]))", 2},
+		{"map(zip([1, 2, 3], [4, 5]), |p| p[0] * p[1])", []int64{4, 10}},
+		{"zip([], [1])", []int64{}},
+		{"range(5)", []int64{0, 1, 2, 3, 4}},
+		{"range(0)", []int64{}},
+		{"range(-2)", []int64{}},
+		{"range(2, 5)", []int64{2, 3, 4}},
+		{"range(5, 2)", []int64{}},
+		{"range(0, 10, 3)", []int64{0, 3, 6, 9}},
+		{"range(5, 0, -2)", []int64{5, 3, 1}},
+		{"range(0, 9223372036854775807, 4611686018427387904)", []int64{0, 4611686018427387904}},
+		{"range(0, -9223372036854775807 - 1, -9223372036854775807 - 1)", []int64{0}},
+		{"range(-9223372036854775807 - 1, 9223372036854775807, 9223372036854775807)", []int64{-9223372036854775808, -1, 9223372036854775806}},
+		{"reduce(range(1, 5), 1, |a, b| a * b)", 24},
+		{"range()", "wrong number of arguments. got=0, want=1 to 3"},
+		{`range("5")`, "arguments to `range` must be INTEGER, got STRING"},
+		{"range(0, 5, 0)", "range step must not be zero"},
+		{"sum([1, 2, 3])", 6},
+		{"sum([])", 0},
+		{"sum(range(101))", 5050},
+		{"min([3, -1, 2])", -1},
+		{"max([3, -1, 2])", 3},
+		{"max([7])", 7},
+		{"min([])", "`min` of empty array"},
+		{"max([])", "`max` of empty array"},
+		{`sum([1, "2"])`, "elements passed to `sum` must be INTEGER or FLOAT, got STRING"},
+		{"sum([1.5, 2])", 3.5},
+		{"sum([0.25, 0.5])", 0.75},
+		{"min(1.5, 2)", 1.5},
+		{"max(1.5, 2)", 2.0},
+		{"min([3, 0.5, 2])", 0.5},
+		{"max([2.5, -1.0])", 2.5},
+		{"max(1)", 1},
+		{"min(3, 1, 2)", 1},
+		{"max(3, 1, 2)", 3},
+		{"min()", "wrong number of arguments. got=0, want at least 1"},
+		{`max(1, "2")`, "arguments to `max` must be INTEGER or FLOAT, got STRING"},
+		{"unique([1, 2, 1, 3, 2])", []int64{1, 2, 3}},
+		{"unique([])", []int64{}},
+		{`len(unique(["a", "b", "a", true, true]))`, 3},
+		{"unique([[1]])", "unusable as hash key: ARRAY"},
+		{"index_of([1, 2, 3], 2)", 1},
+		{"index_of([1, 2, 3], 4)", -1},
+		{"index_of([[1], [1, 2]], [1, 2])", 1},
+		{`index_of([{"a": [1]}, {"a": [2]}], {"a": [2]})`, 1},
+		{`index_of([1, "a"], "a")`, 1},
+		{"contains([1, 2, 3], 2)", true},
+		{"contains([1, 2, 3], 4)", false},
+		{"contains([[1, 2], [3]], [3])", true},
+		{`contains([{"a": 1}], {"a": 2})`, false},
+		{`contains([{"a": 1}], {"a": 1})`, true},
+		{"contains([], [])", false},
+		{"reverse(1)", "argument to `reverse` must be ARRAY, got INTEGER"},
+		{"concat([1], 2)", "arguments to `concat` must be ARRAY, got INTEGER"},
+		{`flatten("ab")`, "argument to `flatten` must be ARRAY, got STRING"},
+		{"zip([1])", "wrong number of arguments. got=1, want=2"},
+		{"zip([1], 2)", "arguments to `zip` must be ARRAY, got INTEGER"},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q",
+					expected, errObj.Message)
+			}
+		case []int64:
+			testIntegerArray(t, evaluated, expected)
+		}
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedLine   int
+		expectedColumn int
+	}{
+		{"1 + true", 1, 3},
+		{"let x = 1;\n  -true", 2, 3},
+		{"let f = fn(x) {\n  x + true\n};\nf(1)", 2, 5},
+		{"foobar", 1, 1},
+		{"let a = [1];\na[\"x\"]", 2, 2},
+		{"len(1)", 1, 4},
+		{`throw "boom"`, 1, 1},
+		{"let y = 1;\ny = 2", 2, 3},
+		{"let x = 1;\nassert(x > 1)", 2, 1},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", test.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Line != test.expectedLine || errObj.Column != test.expectedColumn {
+			t.Errorf("wrong position for %q. expected=%d:%d, got=%d:%d", test.input,
+				test.expectedLine, test.expectedColumn, errObj.Line, errObj.Column)
+		}
+	}
+
+	module := filepath.Join(t.TempDir(), "failing.monkey")
+	if err := os.WriteFile(module, []byte("let x = 1;\nx + true;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	errObj, ok := testEval(`import("` + module + `")`).(*object.Error)
+	if !ok {
+		t.Fatalf("importing %s did not fail", module)
+	}
+	if errObj.File != module || errObj.Line != 2 || errObj.Column != 3 {
+		t.Errorf("wrong position. expected=%s:2:3, got=%s:%d:%d",
+			module, errObj.File, errObj.Line, errObj.Column)
+	}
+}
+
+func TestImportBuiltin(t *testing.T) {
+	dir := t.TempDir()
+	module := filepath.Join(dir, "greet.monkey")
+	if err := os.WriteFile(module, []byte(`let greet = fn(name) { "hello " + name };`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "broken.monkey")
+	if err := os.WriteFile(broken, []byte(`let = 1;`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`import("strings")["capitalize"]("monkey")`, "Monkey"},
+		{`import("strings")["pad_left"]("7", 3, "0")`, "007"},
+		{`import("strings")["pad_left"]("7", 4, "ab")`, "aba7"},
+		{`import("strings")["pad_left"]("long", 2, "0")`, "long"},
+		{`import("strings")["pad_right"]("é", 4, "xy")`, "éxyx"},
+		{`import("strings")["pad_right"]("7", 3, "0")`, "700"},
+		{`try { import("strings")["pad_left"]("7", 3, "") } catch (e) { e }`, "pad_left: fill must not be empty"},
+		{`try { import("strings")["pad_right"]("7", 3, "") } catch (e) { e }`, "pad_right: fill must not be empty"},
+		{`join(import("strings")["words"]("  a  b "), ",")`, "a,b"},
+		{`import("lists")["count"]([1, 2, 3], fn(x) { x > 1 })`, 2},
+		{`import("lists")["find"]([1, 2, 3], fn(x) { x > 1 })`, 2},
+		{`import("maps")["pick"]({"a": 1, "b": 2}, ["b"])["b"]`, 2},
+		{`import("testing")["equal"]([1, {"a": 2}], [1, {"a": 2}])`, true},
+		{`import("` + module + `")["greet"]("monkey")`, "hello monkey"},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			testStringObject(t, evaluated, expected)
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{`import("nope")`, `cannot import "nope": no such module`},
+		{`import("` + broken + `")`, `cannot import "` + broken + `": expected next token to be IDENT, got = instead; no prefix parse function for = found`},
+		{`import(1)`, "argument to `import` must be STRING, got INTEGER"},
+	}
+
+	for _, test := range errorTests {
+		errObj, ok := testEval(test.input).(*object.Error)
+		if !ok {
+			t.Errorf("object is not Error for %q", test.input)
+			continue
+		}
+		if errObj.Message != test.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q",
+				test.expected, errObj.Message)
+		}
+	}
+
+	host := object.NewHost()
+	if first, second := testEvalOn(`import("lists")`, host), testEvalOn(`import("lists")`, host); first != second {
+		t.Errorf("module was not cached. got=%p and %p", first, second)
+	}
+	if first, other := testEvalOn(`import("lists")`, host), testEval(`import("lists")`); first == other {
+		t.Errorf("module was shared with another host. got=%p", first)
+	}
+}
+
+func TestImportCycles(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.monkey"), filepath.Join(dir, "b.monkey")
+	if err := os.WriteFile(a, []byte(`let b = import("`+b+`");`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte(`let a = import("`+a+`");`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	errObj, ok := testEval(`import("` + a + `")`).(*object.Error)
+	if !ok {
+		t.Fatalf("importing a cycle did not fail")
+	}
+	if expected := fmt.Sprintf("import cycle involving %q", a); errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+	}
+}
+
+func TestConcurrentImports(t *testing.T) {
+	module := filepath.Join(t.TempDir(), "slow.monkey")
+	if err := os.WriteFile(module, []byte(`sleep(20); let n = 1;`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	host := object.NewHost()
+	results := make(chan object.Object)
+	for range 2 {
+		go func() { results <- testEvalOn(`import("`+module+`")["n"]`, host) }()
+	}
+	for range 2 {
+		testIntegerObject(t, <-results, 1)
+	}
+}
+
+func TestTestingModule(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	host := object.NewHost()
+	host.Stdout, host.Stderr = &stdout, &stderr
+
+	input := `let t = import("testing");
+t["run"]({
+  "good": fn() { t["assert_equal"]([1], [1]) },
+  "bad": fn() { t["assert_equal"](1, 2) }
+})`
+
+	testIntegerObject(t, testEvalOn(input, host), 1)
+	if stdout.String() != "ok   good\n" {
+		t.Errorf("wrong output. got=%q", stdout.String())
+	}
+	if stderr.String() != "FAIL bad: expected 2, got 1\n" {
+		t.Errorf("wrong error output. got=%q", stderr.String())
+	}
+}
+
+func TestCloneBuiltin(t *testing.T) {
+	testBooleanObject(t, testEval(`let a = [1, [2, {"k": [3]}]]; index_of([a], clone(a)) == 0`), true)
+	testIntegerObject(t, testEval(`clone(5)`), 5)
+	testStringObject(t, testEval(`clone("s")`), "s")
+
+	inner := &object.Array{Elements: []object.Object{&object.Integer{Value: 1}}}
+	outer := &object.Array{Elements: []object.Object{inner, inner}}
+	cyclic := &object.Array{}
+	cyclic.Elements = []object.Object{cyclic}
+
+	env := object.NewEnvironment()
+	env.Set("outer", outer)
+	env.Set("cyclic", cyclic)
+	evalIn := func(input string) object.Object {
+		return Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+	}
+
+	copied, ok := evalIn("clone(outer)").(*object.Array)
+	if !ok {
+		t.Fatalf("clone did not return an Array")
+	}
+	if copied == outer || copied.Elements[0] == inner {
+		t.Errorf("clone did not copy nested arrays")
+	}
+	if copied.Elements[0] != copied.Elements[1] {
+		t.Errorf("clone did not preserve shared references")
+	}
+	if !deepEqual(copied, outer) {
+		t.Errorf("clone is not equal to the original. got=%s", copied.Inspect())
+	}
+
+	copiedCycle, ok := evalIn("clone(cyclic)").(*object.Array)
+	if !ok {
+		t.Fatalf("clone did not return an Array")
+	}
+	if copiedCycle == cyclic || copiedCycle.Elements[0] != copiedCycle {
+		t.Errorf("clone did not preserve the cycle")
+	}
+}
+
+func TestRangeLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []int64
+	}{
+		{"1..5", []int64{1, 2, 3, 4}},
+		{"1..=5", []int64{1, 2, 3, 4, 5}},
+		{"let n = 3; 0..n * 2", []int64{0, 1, 2, 3, 4, 5}},
+		{"-2..1", []int64{-2, -1, 0}},
+		{"5..5", []int64{}},
+		{"5..1", []int64{}},
+		{"5..=5", []int64{5}},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		result, ok := evaluated.(*object.Array)
+		if !ok {
+			t.Errorf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if len(result.Elements) != len(test.expected) {
+			t.Errorf("array has wrong num of elements. want=%d, got=%d",
+				len(test.expected), len(result.Elements))
+			continue
+		}
+
+		for i, expected := range test.expected {
+			testIntegerObject(t, result.Elements[i], expected)
+		}
+	}
+}
+
+func TestArrayIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{
+			"[1, 2, 3][0]",
+			1,
+		},
+		{
+			"[1, 2, 3][1]",
+			2,
+		},
+		{
 			"[1, 2, 3][2]",
 			3,
 		},
@@ -524,6 +2530,90 @@ func TestHashLiterals(t *testing.T) {
 	}
 }
 
+func TestOptionalChaining(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let user = {"address": {"city": 5}}; user?.address?.city`, 5},
+		{`let user = {"name": "Monkey"}; user?.address?.city`, nil},
+		{`let user = {"address": {"zip": 1}}; user?.address?.city`, nil},
+		{`let user = {"address": {"city": 5}}; user?.address?.city + 1`, 6},
+		{`{"a": 1}?.b`, nil},
+		{`let user = {}["missing"]; user?.address["city"]`, nil},
+		{`let user = {}["missing"]; user?.address["city"]?.zip`, nil},
+		{`let user = {}["missing"]; user?.greet(1)`, nil},
+		{`var n = 0; let user = {}["missing"]; user?.greet(n = 1); n`, 0},
+		{`let f = fn(user) { return user?.greet(1); }; f({}["missing"])`, nil},
+		{`let user = {"greet": fn(x) { x + 1 }}; user?.greet(1)`, 2},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		integer, ok := test.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestNullCoalescing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{"a": 1}["a"] ?? 2`, 1},
+		{`{"a": 1}["b"] ?? 2`, 2},
+		{`let user = {}; user?.address?.city ?? 3`, 3},
+		{`if (false) { 1 } ?? 4`, 4},
+		{`0 ?? 5`, 0},
+		{`false ?? 6`, false},
+		{`5 ?? undefinedVariable`, 5},
+		{`[][0] ?? [][1]`, nil},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		switch expected := test.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestInOperator(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"3 in [1, 2, 3]", true},
+		{"4 in [1, 2, 3]", false},
+		{`"b" in ["a", "b"]`, true},
+		{"true in [1, true]", true},
+		{"1 in []", false},
+		{"[1] in [[1]]", true},
+		{`{"a": [2]} in [1, {"a": [2]}]`, true},
+		{"[1] in [[2]]", false},
+		{`"k" in {"k": 1}`, true},
+		{`"x" in {"k": 1}`, false},
+		{"2 in {1: 1, 2: 2}", true},
+		{`"ell" in "hello"`, true},
+		{`"elk" in "hello"`, false},
+		{`"" in "hello"`, true},
+		{"1 + 1 in 1..3", true},
+	}
+
+	for _, test := range tests {
+		testBooleanObject(t, testEval(test.input), test.expected)
+	}
+}
+
 func TestHashIndexExpressions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -569,3 +2659,363 @@ func TestHashIndexExpressions(t *testing.T) {
 		}
 	}
 }
+
+func TestHashBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`keys({"b": 2, "a": 1, "c": 3})`, []string{"b", "a", "c"}},
+		{`keys({})`, []int64{}},
+		{`keys({3: 0, 1: 0, 2: 0})`, []int64{3, 1, 2}},
+		{`keys({"a": 1, "b": 2, "a": 3})`, []string{"a", "b"}},
+		{`keys(merge({"b": 1}, {"a": 2, "b": 3}))`, []string{"b", "a"}},
+		{`keys(delete({"a": 1, "b": 2, "c": 3}, "b"))`, []string{"a", "c"}},
+		{`keys(clone({"z": 1, "y": 2}))`, []string{"z", "y"}},
+		{`values({"b": 2, "a": 1, "c": 3})`, []int64{2, 1, 3}},
+		{`map(entries({"b": 2, "a": 1}), |e| e[1] * 10)`, []int64{20, 10}},
+		{`entries({"a": 1})[0][0]`, "a"},
+		{`len(entries({true: 1, 1: 2, "x": 3}))`, 3},
+		{`keys(delete({"a": 1, "b": 2}, "a"))`, []string{"b"}},
+		{`keys(delete({"a": 1}, "z"))`, []string{"a"}},
+		{`let h = {"a": 1}; delete(h, "a"); keys(h)`, []string{"a"}},
+		{`has_key({"a": 1}, "a")`, true},
+		{`has_key({"a": 1}, "b")`, false},
+		{`has_key({"a": if (false) { 1 }}, "a")`, true},
+		{`values(merge({"a": 1, "b": 2}, {"b": 3, "c": 4}))`, []int64{1, 3, 4}},
+		{`values(merge({"a": 1}, {"a": 2}, {"a": 3}))`, []int64{3}},
+		{`keys(merge({"a": 1}))`, []string{"a"}},
+		{`let a = {"a": 1}; merge(a, {"b": 2}); keys(a)`, []string{"a"}},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			testStringObject(t, evaluated, expected)
+		case []int64:
+			testIntegerArray(t, evaluated, expected)
+		case []string:
+			arr, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if len(arr.Elements) != len(expected) {
+				t.Errorf("wrong num of elements. want=%d, got=%d",
+					len(expected), len(arr.Elements))
+				continue
+			}
+			for i, expectedElement := range expected {
+				testStringObject(t, arr.Elements[i], expectedElement)
+			}
+		}
+	}
+}
+
+func TestHashLiteralOrder(t *testing.T) {
+	input := `var log = "";
+let f = fn(s) { log = log + s; s };
+let h = {f("b"): f("1"), f("a"): f("2")};
+log + " " + str(h)`
+
+	testStringObject(t, testEval(input), "b1a2 {b: 1, a: 2}")
+}
+
+func TestHashLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let {name, age} = {"name": "Monkey", "age": 3}; age`, 3},
+		{`let {age} = {"name": "Monkey", "age": 3}; age * 2`, 6},
+		{`let person = {"a": 1, "b": 2}; let {a, b} = person; a + b`, 3},
+		{`let {missing} = {"name": "Monkey"}; missing`, nil},
+		{`let {} = {"name": "Monkey"}; 5`, 5},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		integer, ok := test.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestMultipleLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let a, b = [1, 2]; a;", 1},
+		{"let a, b = [1, 2]; b;", 2},
+		{"let a, b, c = [1, 2, 3]; a + b + c;", 6},
+		{`
+let divmod = fn(x, y) {
+	let q = x / y;
+	return q, x - q * y;
+};
+let q, r = divmod(7, 2);
+q * 10 + r;`,
+			31,
+		},
+	}
+
+	for _, test := range tests {
+		testIntegerObject(t, testEval(test.input), test.expected)
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{`
+let sum = fn(n, acc) {
+	if (n == 0) { acc } else { sum(n - 1, acc + n) }
+};
+sum(1000000, 0);`,
+			500000500000,
+		},
+		{`
+let sum = fn(n, acc) {
+	if (n == 0) { return acc; }
+	return sum(n - 1, acc + n);
+};
+sum(100000, 0);`,
+			5000050000,
+		},
+		{`
+let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } };
+let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } };
+if (isEven(100001)) { 1 } else { 0 };`,
+			0,
+		},
+		{`
+let count = fn(n) { if (n == 0) { 0 } else { 1 + count(n - 1) } };
+count(100);`,
+			100,
+		},
+		{`
+let identity = fn(x) { x };
+return identity(5);`,
+			5,
+		},
+	}
+
+	for _, test := range tests {
+		testIntegerObject(t, testEval(test.input), test.expected)
+	}
+}
+
+func TestTryExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`try { 1 } catch (e) { 2 }`, 1},
+		{`try { throw 5; 1 } catch (e) { e * 2 }`, 10},
+		{`try { 5 + true; } catch (e) { e }`, "type mismatch: INTEGER + BOOLEAN"},
+		{`try { foo } catch (e) { e }`, "identifier not found: foo"},
+		{`try { throw "boom"; } catch (e) { "caught " + e }`, "caught boom"},
+		{`let x = try { 1 } finally { 2 }; x`, 1},
+		{`let f = fn() { try { return 1; } finally { return 2; } }; f()`, 2},
+		{`let e = 5; try { throw "x" } catch (e) { e }; e`, 5},
+		{`try { throw "x" } catch (e) { let y = e + "y"; }; y`, "xy"},
+		{`
+let log = [];
+let f = fn() {
+	try {
+		throw "inner";
+	} catch (e) {
+		let log = push(log, e);
+	} finally {
+		let log = push(log, "finally");
+	}
+	log;
+};
+f();`,
+			[]string{"inner", "finally"},
+		},
+		{`
+let fail = fn(x) { throw x; };
+let f = fn() { try { return fail(3); } catch (e) { e + 1 } };
+f();`,
+			4,
+		},
+		{`
+let fail = fn() { try { 1 + "a" } catch (e) { throw "rethrown: " + e; } };
+try { fail() } catch (e) { e }`,
+			"rethrown: type mismatch: INTEGER + STRING",
+		},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testStringObject(t, evaluated, expected)
+		case []string:
+			arr, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if len(arr.Elements) != len(expected) {
+				t.Errorf("wrong num of elements. want=%d, got=%d",
+					len(expected), len(arr.Elements))
+				continue
+			}
+			for i, expectedElement := range expected {
+				testStringObject(t, arr.Elements[i], expectedElement)
+			}
+		}
+	}
+}
+
+func testIntegerArray(t *testing.T, obj object.Object, expected []int64) bool {
+	arr, ok := obj.(*object.Array)
+	if !ok {
+		t.Errorf("object is not Array. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if len(arr.Elements) != len(expected) {
+		t.Errorf("wrong num of elements. want=%d, got=%d",
+			len(expected), len(arr.Elements))
+		return false
+	}
+
+	for i, expectedElement := range expected {
+		if !testIntegerObject(t, arr.Elements[i], expectedElement) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func testStringObject(t *testing.T, obj object.Object, expected string) bool {
+	result, ok := obj.(*object.String)
+	if !ok {
+		t.Errorf("object is not String. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%q, want=%q", result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"quote(5)", "5"},
+		{"quote(5 + 8)", "(5 + 8)"},
+		{"quote(foobar)", "foobar"},
+		{"quote(foobar + barfoo)", "(foobar + barfoo)"},
+		{"quote(unquote(4))", "4"},
+		{"quote(unquote(4 + 4))", "8"},
+		{"quote(8 + unquote(4 + 4))", "(8 + 8)"},
+		{"let foobar = 8; quote(unquote(foobar))", "8"},
+		{"quote(unquote(true == false))", "false"},
+		{"quote(unquote(quote(4 + 4)))", "(4 + 4)"},
+		{`quote(unquote("a" + "b") + 1.5)`, "(ab + 1.5)"},
+		{"let q = quote(4 + 4); quote(unquote(4 + 4) + unquote(q))", "(8 + (4 + 4))"},
+		{"let f = fn(x) { quote(unquote(x) * 2) }; f(1); f(3)", "(3 * 2)"},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		quote, ok := evaluated.(*object.Quote)
+		if !ok {
+			t.Errorf("expected *object.Quote for %q. got=%T (%+v)", test.input, evaluated, evaluated)
+			continue
+		}
+
+		if quote.Node.String() != test.expected {
+			t.Errorf("wrong quoted code for %q. expected=%q, got=%q", test.input, test.expected, quote.Node.String())
+		}
+	}
+}
+
+func TestMacroExpansion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let infixExpression = macro() { quote(1 + 2) }; infixExpression();", "(1 + 2)"},
+		{"let reverse = macro(a, b) { quote(unquote(b) - unquote(a)) }; reverse(2 + 2, 10 - 5);", "(10 - 5) - (2 + 2)"},
+		{`let unless = macro(condition, consequence, alternative) {
+	quote(if (!(unquote(condition))) { unquote(consequence) } else { unquote(alternative) })
+};
+unless(10 > 5, puts("not greater"), puts("greater"));`, `if (!(10 > 5)) { puts("not greater") } else { puts("greater") }`},
+		{"let double = macro(x) { quote(unquote(x) * 2) }; double(1) + double(a);", "(1 * 2) + (a * 2)"},
+		{"let number = 1; number;", "let number = 1; number;"},
+	}
+
+	for _, test := range tests {
+		env := object.NewEnvironment()
+		program := parser.New(lexer.New(test.input)).ParseProgram()
+		DefineMacros(program, env)
+		expanded, err := ExpandMacros(program, env)
+		if err != nil {
+			t.Errorf("expanding %q failed: %s", test.input, err.Message)
+			continue
+		}
+
+		expected := parser.New(lexer.New(test.expected)).ParseProgram()
+		if expanded.String() != expected.String() {
+			t.Errorf("wrong expansion of %q. expected=%q, got=%q", test.input, expected.String(), expanded.String())
+		}
+	}
+}
+
+func TestMacroErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let m = macro(x) { 1 }; m(2)", "macros must return code made with quote, got INTEGER"},
+		{"let m = macro(x) { }; m(2)", "macros must return code made with quote, got nothing"},
+		{"let m = macro(x) { quote(x) }; m(1, 2)", "wrong number of arguments to macro. got=2, want=1"},
+		{"let m = macro(x) { quote(unquote(fn() { 1 })) }; m(1)", "cannot unquote FUNCTION"},
+		{"let m = macro(x) { quote(unquote(nope)) }; m(1)", "identifier not found: nope"},
+	}
+
+	for _, test := range tests {
+		env := object.NewEnvironment()
+		program := parser.New(lexer.New(test.input)).ParseProgram()
+		DefineMacros(program, env)
+		_, err := ExpandMacros(program, env)
+		if err == nil {
+			t.Errorf("expected an error expanding %q", test.input)
+			continue
+		}
+		if err.Message != test.expected {
+			t.Errorf("wrong error for %q. expected=%q, got=%q", test.input, test.expected, err.Message)
+		}
+	}
+
+	evaluated := testEval("let m = fn() { macro(x) { x } }; m()")
+	if err, ok := evaluated.(*object.Error); !ok || err.Message != "macros must be bound with a let at the top of the program" {
+		t.Errorf("wrong result for a nested macro literal. got=%+v", evaluated)
+	}
+}
@@ -0,0 +1,203 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"os"
+)
+
+// fileBuiltins give scripts access to the filesystem. Failures are returned
+// as error values, so a missing file doesn't end the program.
+var fileBuiltins = map[string]*object.Builtin{
+	"read_file": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			path, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `read_file` must be STRING, got %s",
+					args[0].Type())
+			}
+
+			content, err := os.ReadFile(path.Value)
+			if err != nil {
+				return newErrorValue("read_file: %s", err)
+			}
+
+			return &object.String{Value: string(content)}
+		},
+	},
+
+	"exists": {
+		Fn: func(args ...object.Object) object.Object {
+			path, err := pathArgument("exists", args)
+			if err != nil {
+				return err
+			}
+
+			_, statErr := os.Stat(path)
+			return nativeBoolToBooleanObject(statErr == nil)
+		},
+	},
+
+	"is_dir": {
+		Fn: func(args ...object.Object) object.Object {
+			path, err := pathArgument("is_dir", args)
+			if err != nil {
+				return err
+			}
+
+			info, statErr := os.Stat(path)
+			return nativeBoolToBooleanObject(statErr == nil && info.IsDir())
+		},
+	},
+
+	// list_dir returns the names of the entries in a directory, sorted.
+	"list_dir": {
+		Fn: func(args ...object.Object) object.Object {
+			path, err := pathArgument("list_dir", args)
+			if err != nil {
+				return err
+			}
+
+			entries, readErr := os.ReadDir(path)
+			if readErr != nil {
+				return newErrorValue("list_dir: %s", readErr)
+			}
+
+			names := make([]object.Object, len(entries))
+			for i, entry := range entries {
+				names[i] = &object.String{Value: entry.Name()}
+			}
+
+			return &object.Array{Elements: names}
+		},
+	},
+}
+
+// fileWriteBuiltins change the filesystem, which the sandbox of the host can
+// forbid.
+var fileWriteBuiltins = map[string]hostBuiltin{
+	"write_file": func(host *object.Host, args ...object.Object) object.Object {
+		if host.Sandbox.DisableFileWrites {
+			return newError("write_file is disabled by the sandbox")
+		}
+
+		path, content, err := pathAndContent("write_file", args)
+		if err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return newErrorValue("write_file: %s", err)
+		}
+
+		return NULL
+	},
+
+	"append_file": func(host *object.Host, args ...object.Object) object.Object {
+		if host.Sandbox.DisableFileWrites {
+			return newError("append_file is disabled by the sandbox")
+		}
+
+		path, content, err := pathAndContent("append_file", args)
+		if err != nil {
+			return err
+		}
+
+		file, openErr := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if openErr != nil {
+			return newErrorValue("append_file: %s", openErr)
+		}
+		defer file.Close()
+
+		if _, writeErr := file.WriteString(content); writeErr != nil {
+			return newErrorValue("append_file: %s", writeErr)
+		}
+
+		return NULL
+	},
+
+	// mkdir creates a directory along with any missing parents.
+	"mkdir": func(host *object.Host, args ...object.Object) object.Object {
+		if host.Sandbox.DisableFileWrites {
+			return newError("mkdir is disabled by the sandbox")
+		}
+
+		path, err := pathArgument("mkdir", args)
+		if err != nil {
+			return err
+		}
+
+		if mkdirErr := os.MkdirAll(path, 0755); mkdirErr != nil {
+			return newErrorValue("mkdir: %s", mkdirErr)
+		}
+
+		return NULL
+	},
+
+	// remove deletes a file or an empty directory.
+	"remove": func(host *object.Host, args ...object.Object) object.Object {
+		if host.Sandbox.DisableFileWrites {
+			return newError("remove is disabled by the sandbox")
+		}
+
+		path, err := pathArgument("remove", args)
+		if err != nil {
+			return err
+		}
+
+		if removeErr := os.Remove(path); removeErr != nil {
+			return newErrorValue("remove: %s", removeErr)
+		}
+
+		return NULL
+	},
+}
+
+func init() {
+	for name, builtin := range fileBuiltins {
+		builtins[name] = builtin
+	}
+	for name, fn := range fileWriteBuiltins {
+		environmentBuiltins[name] = bindHost(fn)
+	}
+}
+
+func pathArgument(name string, args []object.Object) (string, *object.Error) {
+	if len(args) != 1 {
+		return "", newError("wrong number of arguments. got=%d, want=1",
+			len(args))
+	}
+
+	path, ok := args[0].(*object.String)
+	if !ok {
+		return "", newError("argument to `%s` must be STRING, got %s",
+			name, args[0].Type())
+	}
+
+	return path.Value, nil
+}
+
+func pathAndContent(name string, args []object.Object) (string, string, *object.Error) {
+	if len(args) != 2 {
+		return "", "", newError("wrong number of arguments. got=%d, want=2",
+			len(args))
+	}
+
+	path, ok := args[0].(*object.String)
+	if !ok {
+		return "", "", newError("first argument to `%s` must be STRING, got %s",
+			name, args[0].Type())
+	}
+
+	content, ok := args[1].(*object.String)
+	if !ok {
+		return "", "", newError("second argument to `%s` must be STRING, got %s",
+			name, args[1].Type())
+	}
+
+	return path.Value, content.Value, nil
+}
@@ -0,0 +1,268 @@
+package evaluator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"monkey/object"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTPTimeout bounds each request made by the HTTP client builtins, in
+// addition to any deadline on the context of the host.
+var HTTPTimeout = 30 * time.Second
+
+// httpBuiltins make requests within the context of the host of the
+// interpreter calling them.
+var httpBuiltins = map[string]hostBuiltin{
+	"http_get": func(host *object.Host, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError("wrong number of arguments. got=%d, want=1",
+				len(args))
+		}
+
+		url, ok := args[0].(*object.String)
+		if !ok {
+			return newError("argument to `http_get` must be STRING, got %s",
+				args[0].Type())
+		}
+
+		return doRequest(host.Context, "http_get", http.MethodGet, url.Value, "", nil)
+	},
+
+	// http_post takes an optional hash of request headers with STRING
+	// values.
+	"http_post": func(host *object.Host, args ...object.Object) object.Object {
+		if len(args) != 2 && len(args) != 3 {
+			return newError("wrong number of arguments. got=%d, want=2 or 3",
+				len(args))
+		}
+
+		url, ok := args[0].(*object.String)
+		if !ok {
+			return newError("first argument to `http_post` must be STRING, got %s",
+				args[0].Type())
+		}
+
+		body, ok := args[1].(*object.String)
+		if !ok {
+			return newError("second argument to `http_post` must be STRING, got %s",
+				args[1].Type())
+		}
+
+		var headers *object.Hash
+		if len(args) == 3 {
+			headers, ok = args[2].(*object.Hash)
+			if !ok {
+				return newError("third argument to `http_post` must be HASH, got %s",
+					args[2].Type())
+			}
+		}
+
+		return doRequest(host.Context, "http_post", http.MethodPost, url.Value, body.Value, headers)
+	},
+}
+
+func init() {
+	for name, fn := range httpBuiltins {
+		environmentBuiltins[name] = bindHost(fn)
+	}
+
+	// serve calls back into Monkey, so it can't be part of httpBuiltins
+	// without an initialization cycle.
+	environmentBuiltins["serve"] = bindHost(serveBuiltin)
+}
+
+// doRequest performs a request and returns the response as a hash with its
+// status code, body and headers. Error statuses are returned like any other
+// response; only failing to get a response at all is an error.
+func doRequest(ctx context.Context, name, method, url, body string, headers *object.Hash) object.Object {
+	req, err := http.NewRequestWithContext(ctx, method, url, strings.NewReader(body))
+	if err != nil {
+		return newError("%s: %s", name, err)
+	}
+
+	if headers != nil {
+		for _, pair := range headers.Ordered() {
+			key, keyOk := pair.Key.(*object.String)
+			value, valueOk := pair.Value.(*object.String)
+			if !keyOk || !valueOk {
+				return newError("%s: headers must map STRING to STRING, got %s: %s",
+					name, pair.Key.Type(), pair.Value.Type())
+			}
+			req.Header.Set(key.Value, value.Value)
+		}
+	}
+
+	client := &http.Client{Timeout: HTTPTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return newError("%s: %s", name, err)
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return newError("%s: %s", name, err)
+	}
+
+	responseHeaders := make(map[string]object.Object, len(resp.Header))
+	for key, values := range resp.Header {
+		responseHeaders[key] = &object.String{Value: strings.Join(values, ", ")}
+	}
+
+	return newHash(map[string]object.Object{
+		"status":  &object.Integer{Value: int64(resp.StatusCode)},
+		"body":    &object.String{Value: string(content)},
+		"headers": newHash(responseHeaders),
+	})
+}
+
+// serveBuiltin listens on a port and answers every request by calling a
+// Monkey handler with a request hash. It blocks until the context of host
+// is done.
+func serveBuiltin(host *object.Host, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	port, ok := args[0].(*object.Integer)
+	if !ok {
+		return newError("first argument to `serve` must be INTEGER, got %s",
+			args[0].Type())
+	}
+
+	if !isCallable(args[1]) {
+		return newError("second argument to `serve` must be FUNCTION, got %s",
+			args[1].Type())
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port.Value))
+	if err != nil {
+		return newError("serve: %s", err)
+	}
+
+	server := &http.Server{Handler: newMonkeyHandler(args[1])}
+	go func() {
+		<-host.Context.Done()
+		server.Close()
+	}()
+
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return newError("serve: %s", err)
+	}
+
+	return NULL
+}
+
+// monkeyHandler adapts a Monkey function to an http.Handler. The evaluator
+// isn't safe for concurrent use, so requests are handled one at a time.
+type monkeyHandler struct {
+	mu sync.Mutex
+	fn object.Object
+}
+
+func newMonkeyHandler(fn object.Object) *monkeyHandler {
+	return &monkeyHandler{fn: fn}
+}
+
+// ServeHTTP passes the handler a hash with the method, path, query, headers
+// and body of the request. The handler returns either a string, used as the
+// body of a 200 response, or a hash with an optional status, body and
+// headers.
+func (h *monkeyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := make(map[string]object.Object)
+	for key, values := range r.URL.Query() {
+		query[key] = &object.String{Value: strings.Join(values, ", ")}
+	}
+
+	headers := make(map[string]object.Object)
+	for key, values := range r.Header {
+		headers[key] = &object.String{Value: strings.Join(values, ", ")}
+	}
+
+	request := newHash(map[string]object.Object{
+		"method":  &object.String{Value: r.Method},
+		"path":    &object.String{Value: r.URL.Path},
+		"query":   newHash(query),
+		"headers": newHash(headers),
+		"body":    &object.String{Value: string(body)},
+	})
+
+	writeResponse(w, h.handle(request))
+}
+
+// handle applies the handler to request. A panic while evaluating it is
+// returned as an error, answered with a 500, and doesn't leave the lock
+// held for the requests that follow.
+func (h *monkeyHandler) handle(request object.Object) (response object.Object) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	defer func() {
+		if r := recover(); r != nil {
+			response = newError("handler panicked: %v", r)
+		}
+	}()
+
+	return applyFunction(h.fn, []object.Object{request})
+}
+
+func writeResponse(w http.ResponseWriter, response object.Object) {
+	switch response := response.(type) {
+	case *object.String:
+		io.WriteString(w, response.Value)
+	case *object.Hash:
+		status := http.StatusOK
+		var body string
+		for _, pair := range response.Pairs {
+			key, ok := pair.Key.(*object.String)
+			if !ok {
+				continue
+			}
+			switch value := pair.Value.(type) {
+			case *object.Integer:
+				if key.Value == "status" {
+					status = int(value.Value)
+				}
+			case *object.String:
+				if key.Value == "body" {
+					body = value.Value
+				}
+			case *object.Hash:
+				if key.Value == "headers" {
+					for _, header := range value.Pairs {
+						if name, ok := header.Key.(*object.String); ok {
+							w.Header().Set(name.Value, stringValue(header.Value))
+						}
+					}
+				}
+			}
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	case *object.Error:
+		http.Error(w, response.Message, http.StatusInternalServerError)
+	default:
+		http.Error(w, fmt.Sprintf("handler returned %s, want STRING or HASH", response.Type()),
+			http.StatusInternalServerError)
+	}
+}
+
+// stringValue returns the value of a string, or the Inspect output of any
+// other object.
+func stringValue(obj object.Object) string {
+	if str, ok := obj.(*object.String); ok {
+		return str.Value
+	}
+	return obj.Inspect()
+}
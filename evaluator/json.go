@@ -0,0 +1,231 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"monkey/object"
+	"strings"
+)
+
+var jsonBuiltins = map[string]*object.Builtin{
+	// json_parse maps JSON objects to hashes with the keys in document
+	// order, arrays to arrays and numbers to integers unless they have a
+	// fraction or exponent.
+	"json_parse": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `json_parse` must be STRING, got %s",
+					args[0].Type())
+			}
+
+			var raw json.RawMessage
+			decoder := json.NewDecoder(strings.NewReader(str.Value))
+			if err := decoder.Decode(&raw); err != nil {
+				return newError("json_parse: %s", err)
+			}
+			if decoder.More() {
+				return newError("json_parse: unexpected data after top-level value")
+			}
+
+			decoder = json.NewDecoder(bytes.NewReader(raw))
+			decoder.UseNumber()
+			value, err := fromJSON(decoder)
+			if err != nil {
+				return newError("json_parse: %s", err)
+			}
+			return value
+		},
+	},
+
+	// json_stringify takes an optional number of spaces to indent nested
+	// values by. Hash keys are written in the order of the hash.
+	"json_stringify": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2",
+					len(args))
+			}
+
+			indent := ""
+			if len(args) == 2 {
+				spaces, ok := args[1].(*object.Integer)
+				if !ok {
+					return newError("second argument to `json_stringify` must be INTEGER, got %s",
+						args[1].Type())
+				}
+				indent = strings.Repeat(" ", int(max(spaces.Value, 0)))
+			}
+
+			value, err := toJSON(args[0])
+			if err != nil {
+				return err
+			}
+
+			var out bytes.Buffer
+			encoder := json.NewEncoder(&out)
+			encoder.SetEscapeHTML(false)
+			encoder.SetIndent("", indent)
+			if err := encoder.Encode(value); err != nil {
+				return newError("json_stringify: %s", err)
+			}
+
+			return &object.String{Value: strings.TrimSuffix(out.String(), "\n")}
+		},
+	},
+}
+
+func init() {
+	for name, builtin := range jsonBuiltins {
+		builtins[name] = builtin
+	}
+}
+
+// fromJSON decodes the next value from decoder token by token, so that the
+// keys of objects can be set on hashes in the order they're written in. The
+// input is expected to be valid JSON already.
+func fromJSON(decoder *json.Decoder) (object.Object, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token := token.(type) {
+	case nil:
+		return NULL, nil
+	case bool:
+		return nativeBoolToBooleanObject(token), nil
+	case string:
+		return &object.String{Value: token}, nil
+	case json.Number:
+		if integer, err := token.Int64(); err == nil {
+			return &object.Integer{Value: integer}, nil
+		}
+		float, err := token.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s", token)
+		}
+		return &object.Float{Value: float}, nil
+	case json.Delim:
+		if token == '[' {
+			elements := []object.Object{}
+			for decoder.More() {
+				element, err := fromJSON(decoder)
+				if err != nil {
+					return nil, err
+				}
+				elements = append(elements, element)
+			}
+			if _, err := decoder.Token(); err != nil {
+				return nil, err
+			}
+			return &object.Array{Elements: elements}, nil
+		}
+
+		hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
+		for decoder.More() {
+			name, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := fromJSON(decoder)
+			if err != nil {
+				return nil, err
+			}
+			key := &object.String{Value: name.(string)}
+			hash.Set(key.HashKey(), object.HashPair{Key: key, Value: value})
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return hash, nil
+	default:
+		return nil, fmt.Errorf("unsupported value %v", token)
+	}
+}
+
+// A jsonObject is a hash to encode, with its pairs in order.
+type jsonObject []jsonField
+
+type jsonField struct {
+	key   string
+	value interface{}
+}
+
+// MarshalJSON writes fields in order, without escaping HTML
+// like json_stringify.
+func (fields jsonObject) MarshalJSON() ([]byte, error) {
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+
+	out.WriteString("{")
+	for i, field := range fields {
+		if i > 0 {
+			out.WriteString(",")
+		}
+		if err := encoder.Encode(field.key); err != nil {
+			return nil, err
+		}
+		out.Truncate(out.Len() - 1) // Encode ends every value with a newline
+		out.WriteString(":")
+		if err := encoder.Encode(field.value); err != nil {
+			return nil, err
+		}
+		out.Truncate(out.Len() - 1)
+	}
+	out.WriteString("}")
+	return out.Bytes(), nil
+}
+
+func toJSON(obj object.Object) (interface{}, *object.Error) {
+	switch obj := obj.(type) {
+	case *object.Null:
+		return nil, nil
+	case *object.Boolean:
+		return obj.Value, nil
+	case *object.Integer:
+		return obj.Value, nil
+	case *object.Float:
+		if math.IsNaN(obj.Value) || math.IsInf(obj.Value, 0) {
+			return nil, newError("json_stringify: unsupported value %s", obj.Inspect())
+		}
+		return obj.Value, nil
+	case *object.String:
+		return obj.Value, nil
+	case *object.Array:
+		elements := make([]interface{}, len(obj.Elements))
+		for i, element := range obj.Elements {
+			value, err := toJSON(element)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = value
+		}
+		return elements, nil
+	case *object.Hash:
+		fields := make(jsonObject, 0, len(obj.Pairs))
+		for _, pair := range obj.Ordered() {
+			key, ok := pair.Key.(*object.String)
+			if !ok {
+				return nil, newError("json_stringify: hash keys must be STRING, got %s",
+					pair.Key.Type())
+			}
+			value, err := toJSON(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, jsonField{key: key.Value, value: value})
+		}
+		return fields, nil
+	default:
+		return nil, newError("json_stringify: cannot encode %s", obj.Type())
+	}
+}
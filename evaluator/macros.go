@@ -0,0 +1,86 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+)
+
+// DefineMacros binds the macros defined with a let at the top of program in
+// env and removes their definitions from program, since macros only exist
+// while it's expanded.
+func DefineMacros(program *ast.Program, env *object.Environment) {
+	statements := make([]ast.Statement, 0, len(program.Statements))
+	for _, statement := range program.Statements {
+		let, ok := statement.(*ast.LetStatement)
+		if !ok {
+			statements = append(statements, statement)
+			continue
+		}
+		literal, ok := let.Value.(*ast.MacroLiteral)
+		if !ok {
+			statements = append(statements, statement)
+			continue
+		}
+
+		env.Set(let.Name.Value, &object.Macro{Parameters: literal.Parameters, Body: literal.Body, Env: env})
+	}
+	program.Statements = statements
+}
+
+// ExpandMacros replaces the calls in program of macros bound in env with the
+// code they return. Macros are called with their arguments quoted rather
+// than evaluated, and must return code made with quote. The error is that
+// of the first macro that fails or returns something else.
+func ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, *object.Error) {
+	var err *object.Error
+	expanded := ast.Modify(program, func(node ast.Node) ast.Node {
+		call, ok := node.(*ast.CallExpression)
+		if !ok || err != nil {
+			return node
+		}
+		macro, ok := macroCalled(call, env)
+		if !ok {
+			return node
+		}
+
+		if len(call.Arguments) != len(macro.Parameters) {
+			err = newError("wrong number of arguments to macro. got=%d, want=%d", len(call.Arguments), len(macro.Parameters))
+			at(call.Token, err)
+			return node
+		}
+		args := make([]object.Object, len(call.Arguments))
+		for i, argument := range call.Arguments {
+			args[i] = &object.Quote{Node: argument}
+		}
+
+		function := &object.Function{Parameters: macro.Parameters, Body: macro.Body, Env: macro.Env}
+		switch evaluated := applyFunction(function, args).(type) {
+		case *object.Quote:
+			return evaluated.Node
+		case *object.Error:
+			err = evaluated
+		case nil:
+			err = newError("macros must return code made with quote, got nothing")
+			at(call.Token, err)
+		default:
+			err = newError("macros must return code made with quote, got %s", evaluated.Type())
+			at(call.Token, err)
+		}
+		return node
+	})
+
+	return expanded, err
+}
+
+func macroCalled(call *ast.CallExpression, env *object.Environment) (*object.Macro, bool) {
+	identifier, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+	obj, ok := env.Get(identifier.Value)
+	if !ok {
+		return nil, false
+	}
+	macro, ok := obj.(*object.Macro)
+	return macro, ok
+}
@@ -0,0 +1,210 @@
+package evaluator
+
+import (
+	"math"
+	"monkey/object"
+)
+
+// mathBuiltins are merged into the builtins table on startup. They accept
+// integers and floats alike; floor, ceil and round return integers, while
+// sqrt and the transcendental functions always return floats.
+var mathBuiltins = map[string]*object.Builtin{
+	"abs": numberFunction("abs", func(value object.Object) object.Object {
+		switch value := value.(type) {
+		case *object.Integer:
+			if value.Value < 0 {
+				return &object.Integer{Value: -value.Value}
+			}
+			return value
+		default:
+			return &object.Float{Value: math.Abs(toFloat(value))}
+		}
+	}),
+
+	"floor": roundingFunction("floor", math.Floor),
+	"ceil":  roundingFunction("ceil", math.Ceil),
+	"round": roundingFunction("round", math.Round),
+
+	"sqrt": numberFunction("sqrt", func(value object.Object) object.Object {
+		if toFloat(value) < 0 {
+			return newError("square root of negative number: %s", value.Inspect())
+		}
+		return &object.Float{Value: math.Sqrt(toFloat(value))}
+	}),
+
+	"sin": floatFunction("sin", math.Sin),
+	"cos": floatFunction("cos", math.Cos),
+	"tan": floatFunction("tan", math.Tan),
+	"exp": floatFunction("exp", math.Exp),
+
+	"log":  logarithm("log", math.Log),
+	"log2": logarithm("log2", math.Log2),
+
+	"pow": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2",
+					len(args))
+			}
+
+			for _, arg := range args {
+				if !isNumber(arg) {
+					return newError("arguments to `pow` must be INTEGER or FLOAT, got %s",
+						arg.Type())
+				}
+			}
+
+			return evalInfixExpression("**", args[0], args[1])
+		},
+	},
+
+	"min": {
+		Fn: func(args ...object.Object) object.Object {
+			values, err := minMaxArguments("min", args)
+			if err != nil {
+				return err
+			}
+
+			return minMax(values, true)
+		},
+	},
+
+	"max": {
+		Fn: func(args ...object.Object) object.Object {
+			values, err := minMaxArguments("max", args)
+			if err != nil {
+				return err
+			}
+
+			return minMax(values, false)
+		},
+	},
+}
+
+// mathConstants are resolved like builtins when an identifier isn't bound.
+var mathConstants = map[string]object.Object{
+	"PI": &object.Float{Value: math.Pi},
+	"E":  &object.Float{Value: math.E},
+}
+
+func init() {
+	for name, builtin := range mathBuiltins {
+		builtins[name] = builtin
+	}
+}
+
+// numberFunction wraps fn as a builtin taking a single INTEGER or FLOAT
+// argument.
+func numberFunction(name string, fn func(object.Object) object.Object) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			if !isNumber(args[0]) {
+				return newError("argument to `%s` must be INTEGER or FLOAT, got %s",
+					name, args[0].Type())
+			}
+
+			return fn(args[0])
+		},
+	}
+}
+
+func floatFunction(name string, fn func(float64) float64) *object.Builtin {
+	return numberFunction(name, func(value object.Object) object.Object {
+		return &object.Float{Value: fn(toFloat(value))}
+	})
+}
+
+func roundingFunction(name string, fn func(float64) float64) *object.Builtin {
+	return numberFunction(name, func(value object.Object) object.Object {
+		if value.Type() == object.INTEGER_OBJ {
+			return value
+		}
+		return &object.Integer{Value: int64(fn(toFloat(value)))}
+	})
+}
+
+func logarithm(name string, fn func(float64) float64) *object.Builtin {
+	return numberFunction(name, func(value object.Object) object.Object {
+		if toFloat(value) <= 0 {
+			return newError("`%s` of non-positive number: %s", name, value.Inspect())
+		}
+		return &object.Float{Value: fn(toFloat(value))}
+	})
+}
+
+// numberArguments checks that args are all INTEGER or FLOAT.
+func numberArguments(name string, args []object.Object) ([]object.Object, *object.Error) {
+	for _, arg := range args {
+		if !isNumber(arg) {
+			return nil, newError("arguments to `%s` must be INTEGER or FLOAT, got %s",
+				name, arg.Type())
+		}
+	}
+
+	return args, nil
+}
+
+// minMaxArguments accepts either a single array, as in min([1, 2]), or the
+// values themselves, as in min(1, 2).
+func minMaxArguments(name string, args []object.Object) ([]object.Object, *object.Error) {
+	if len(args) == 0 {
+		return nil, newError("wrong number of arguments. got=0, want at least 1")
+	}
+
+	var values []object.Object
+	var err *object.Error
+	if args[0].Type() == object.ARRAY_OBJ && len(args) == 1 {
+		values, err = numberElements(name, args)
+	} else {
+		values, err = numberArguments(name, args)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) == 0 {
+		return nil, newError("`%s` of empty array", name)
+	}
+
+	return values, nil
+}
+
+// minMax returns the smallest of values, or the largest unless smallest is
+// set. Integers are compared as integers, but if any value is a float they
+// are all compared as floats and the result is a float.
+func minMax(values []object.Object, smallest bool) object.Object {
+	if !hasFloat(values) {
+		best := values[0].(*object.Integer)
+		for _, value := range values[1:] {
+			integer := value.(*object.Integer)
+			if smallest && integer.Value < best.Value || !smallest && integer.Value > best.Value {
+				best = integer
+			}
+		}
+		return best
+	}
+
+	best := toFloat(values[0])
+	for _, value := range values[1:] {
+		if smallest {
+			best = math.Min(best, toFloat(value))
+		} else {
+			best = math.Max(best, toFloat(value))
+		}
+	}
+	return &object.Float{Value: best}
+}
+
+func hasFloat(values []object.Object) bool {
+	for _, value := range values {
+		if value.Type() == object.FLOAT_OBJ {
+			return true
+		}
+	}
+	return false
+}
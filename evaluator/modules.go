@@ -0,0 +1,125 @@
+package evaluator
+
+import (
+	"monkey/diagnostic"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"monkey/std"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// modules caches imported modules by host and name, so that an interpreter
+// evaluates each one once. Imports of a module running at the same time
+// evaluate it each, and then all share the one done first.
+var modules = struct {
+	sync.Mutex
+	loaded map[moduleKey]*object.Hash
+}{loaded: make(map[moduleKey]*object.Hash)}
+
+// A moduleKey identifies a module imported by the interpreter with host.
+type moduleKey struct {
+	host *object.Host
+	name string
+}
+
+func init() {
+	environmentBuiltins["import"] = newImportBuiltin
+}
+
+// newImportBuiltin makes the import builtin, which evaluates a module in an
+// environment of its own, sharing the host of env, and returns a hash of
+// its top-level bindings. Names ending in .monkey are read from the
+// filesystem, anything else from the embedded standard library. A module
+// importing one of the modules whose imports led to it is an import cycle.
+func newImportBuiltin(env *object.Environment) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			name, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `import` must be STRING, got %s", args[0].Type())
+			}
+
+			imports := env.Imports()
+			if slices.Contains(imports, name.Value) {
+				return newError("import cycle involving %q", name.Value)
+			}
+
+			host := env.Host()
+			key := moduleKey{host: host, name: name.Value}
+			modules.Lock()
+			module, loaded := modules.loaded[key]
+			modules.Unlock()
+			if loaded {
+				return module
+			}
+
+			result := loadModule(name.Value, host, append(slices.Clip(imports), name.Value))
+			module, ok = result.(*object.Hash)
+			if !ok {
+				return result
+			}
+
+			modules.Lock()
+			defer modules.Unlock()
+			if first, loaded := modules.loaded[key]; loaded {
+				return first
+			}
+			modules.loaded[key] = module
+			return module
+		},
+	}
+}
+
+// loadModule evaluates the module called name with host, imports being the
+// names of the modules being imported that led to it, name last.
+func loadModule(name string, host *object.Host, imports []string) object.Object {
+	var source []byte
+	var err error
+	if strings.HasSuffix(name, std.Extension) {
+		source, err = os.ReadFile(name)
+	} else {
+		source, err = std.Modules.ReadFile(name + std.Extension)
+	}
+	if err != nil {
+		return newError("cannot import %q: no such module", name)
+	}
+
+	p := parser.New(lexer.New(string(source)))
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) != 0 {
+		return newError("cannot import %q: %s", name, syntaxErrors(p.Diagnostics()))
+	}
+
+	env := object.NewEnvironment()
+	env.SetHost(host)
+	env.SetImports(imports)
+	if result := Eval(program, env); isError(result) {
+		if err, ok := result.(*object.Error); ok && err.File == "" {
+			err.File = name
+			if !strings.HasSuffix(name, std.Extension) {
+				err.File = "std/" + name + std.Extension
+			}
+		}
+		return result
+	}
+
+	return newHash(env.Bindings())
+}
+
+// syntaxErrors joins the messages of diagnostics, the errors found while
+// parsing some source.
+func syntaxErrors(diagnostics []diagnostic.Diagnostic) string {
+	messages := make([]string, len(diagnostics))
+	for i, found := range diagnostics {
+		messages[i] = found.Message
+	}
+	return strings.Join(messages, "; ")
+}
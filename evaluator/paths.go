@@ -0,0 +1,65 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"path/filepath"
+)
+
+// pathBuiltins manipulate file paths using the separator of the host
+// system, so scripts don't have to build paths by hand.
+var pathBuiltins = map[string]*object.Builtin{
+	"path_join": {
+		Fn: func(args ...object.Object) object.Object {
+			elements := make([]string, len(args))
+			for i, arg := range args {
+				str, ok := arg.(*object.String)
+				if !ok {
+					return newError("arguments to `path_join` must be STRING, got %s",
+						arg.Type())
+				}
+				elements[i] = str.Value
+			}
+
+			return &object.String{Value: filepath.Join(elements...)}
+		},
+	},
+
+	"path_base": pathFunction("path_base", filepath.Base),
+	"path_dir":  pathFunction("path_dir", filepath.Dir),
+	"path_ext":  pathFunction("path_ext", filepath.Ext),
+
+	"path_abs": {
+		Fn: func(args ...object.Object) object.Object {
+			path, err := pathArgument("path_abs", args)
+			if err != nil {
+				return err
+			}
+
+			abs, absErr := filepath.Abs(path)
+			if absErr != nil {
+				return newErrorValue("path_abs: %s", absErr)
+			}
+
+			return &object.String{Value: abs}
+		},
+	},
+}
+
+func init() {
+	for name, builtin := range pathBuiltins {
+		builtins[name] = builtin
+	}
+}
+
+func pathFunction(name string, fn func(string) string) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			path, err := pathArgument(name, args)
+			if err != nil {
+				return err
+			}
+
+			return &object.String{Value: fn(path)}
+		},
+	}
+}
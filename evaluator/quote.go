@@ -0,0 +1,85 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+	"monkey/token"
+)
+
+// isCallOf reports whether node calls the function named name, like the
+// calls of quote and unquote that are treated as syntax rather than calls.
+func isCallOf(node ast.Node, name string) bool {
+	call, ok := node.(*ast.CallExpression)
+	if !ok {
+		return false
+	}
+	function, ok := call.Function.(*ast.Identifier)
+	return ok && function.Value == name
+}
+
+// quote returns the argument to node, a call of quote, as code rather than
+// its value. Calls of unquote within it are replaced by the code for what
+// their argument evaluates to in env.
+func quote(node *ast.CallExpression, env *object.Environment) object.Object {
+	if len(node.Arguments) != 1 {
+		return newError("wrong number of arguments to `quote`. got=%d, want=1", len(node.Arguments))
+	}
+
+	// The argument is copied since the same code is quoted again every
+	// time it's evaluated, with different values to unquote.
+	var err object.Object
+	quoted := ast.Modify(ast.Copy(node.Arguments[0]), func(node ast.Node) ast.Node {
+		if err != nil || !isCallOf(node, "unquote") {
+			return node
+		}
+
+		call := node.(*ast.CallExpression)
+		if len(call.Arguments) != 1 {
+			err = at(call.Token, newError("wrong number of arguments to `unquote`. got=%d, want=1", len(call.Arguments)))
+			return node
+		}
+
+		unquoted := Eval(call.Arguments[0], env)
+		if isError(unquoted) {
+			err = unquoted
+			return node
+		}
+
+		converted, ok := toNode(unquoted, call.Token)
+		if !ok {
+			err = at(call.Token, newError("cannot unquote %s", unquoted.Type()))
+			return node
+		}
+		return converted
+	})
+	if err != nil {
+		return err
+	}
+
+	return &object.Quote{Node: quoted}
+}
+
+// toNode returns the code for obj, positioned at tok.
+func toNode(obj object.Object, tok token.Token) (ast.Node, bool) {
+	literal := func(tokenType token.Type, value string) token.Token {
+		return token.Token{Type: tokenType, Literal: value, Line: tok.Line, Column: tok.Column}
+	}
+
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return &ast.IntegerLiteral{Token: literal(token.INT, obj.Inspect()), Value: obj.Value}, true
+	case *object.Float:
+		return &ast.FloatLiteral{Token: literal(token.FLOAT, obj.Inspect()), Value: obj.Value}, true
+	case *object.Boolean:
+		if obj.Value {
+			return &ast.Boolean{Token: literal(token.TRUE, "true"), Value: true}, true
+		}
+		return &ast.Boolean{Token: literal(token.FALSE, "false"), Value: false}, true
+	case *object.String:
+		return &ast.StringLiteral{Token: literal(token.STRING, obj.Value), Value: obj.Value}, true
+	case *object.Quote:
+		return ast.Copy(obj.Node), true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,106 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"regexp"
+	"sync"
+)
+
+// patterns caches compiled regular expressions by their source, since
+// scripts tend to apply the same pattern over and over in a loop.
+var patterns = struct {
+	sync.Mutex
+	compiled map[string]*regexp.Regexp
+}{compiled: make(map[string]*regexp.Regexp)}
+
+var regexpBuiltins = map[string]*object.Builtin{
+	"re_match": {
+		Fn: func(args ...object.Object) object.Object {
+			re, strs, err := regexpArguments("re_match", args, 2)
+			if err != nil {
+				return err
+			}
+
+			return nativeBoolToBooleanObject(re.MatchString(strs[0]))
+		},
+	},
+
+	"re_find_all": {
+		Fn: func(args ...object.Object) object.Object {
+			re, strs, err := regexpArguments("re_find_all", args, 2)
+			if err != nil {
+				return err
+			}
+
+			matches := re.FindAllString(strs[0], -1)
+			elements := make([]object.Object, len(matches))
+			for i, match := range matches {
+				elements[i] = &object.String{Value: match}
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	},
+
+	// re_replace expands $1 and ${name} in the replacement to the text of
+	// the matching group.
+	"re_replace": {
+		Fn: func(args ...object.Object) object.Object {
+			re, strs, err := regexpArguments("re_replace", args, 3)
+			if err != nil {
+				return err
+			}
+
+			return &object.String{Value: re.ReplaceAllString(strs[0], strs[1])}
+		},
+	},
+}
+
+func init() {
+	for name, builtin := range regexpBuiltins {
+		builtins[name] = builtin
+	}
+}
+
+// regexpArguments checks that args are want strings, compiles the first one
+// and returns it along with the remaining strings.
+func regexpArguments(name string, args []object.Object, want int) (*regexp.Regexp, []string, *object.Error) {
+	if len(args) != want {
+		return nil, nil, newError("wrong number of arguments. got=%d, want=%d",
+			len(args), want)
+	}
+
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		str, ok := arg.(*object.String)
+		if !ok {
+			return nil, nil, newError("arguments to `%s` must be STRING, got %s",
+				name, arg.Type())
+		}
+		strs[i] = str.Value
+	}
+
+	re, err := compilePattern(strs[0])
+	if err != nil {
+		return nil, nil, newError("%s: %s", name, err)
+	}
+
+	return re, strs[1:], nil
+}
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	patterns.Lock()
+	defer patterns.Unlock()
+
+	if re, ok := patterns.compiled[pattern]; ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patterns.compiled[pattern] = re
+
+	return re, nil
+}
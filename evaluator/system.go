@@ -0,0 +1,154 @@
+package evaluator
+
+import (
+	"bytes"
+	"monkey/object"
+	"os"
+	"os/exec"
+)
+
+var systemBuiltins = map[string]*object.Builtin{
+	// getenv returns null for variables that aren't set, so that an unset
+	// variable can be told apart from an empty one.
+	"getenv": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			name, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `getenv` must be STRING, got %s",
+					args[0].Type())
+			}
+
+			value, ok := os.LookupEnv(name.Value)
+			if !ok {
+				return NULL
+			}
+
+			return &object.String{Value: value}
+		},
+	},
+
+	"setenv": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2",
+					len(args))
+			}
+
+			for _, arg := range args {
+				if arg.Type() != object.STRING_OBJ {
+					return newError("arguments to `setenv` must be STRING, got %s",
+						arg.Type())
+				}
+			}
+
+			name := args[0].(*object.String).Value
+			value := args[1].(*object.String).Value
+			if err := os.Setenv(name, value); err != nil {
+				return newError("setenv: %s", err)
+			}
+
+			return NULL
+		},
+	},
+
+	// exit stops the program with the given status, which defaults to 0.
+	// Unlike os.Exit it only unwinds the evaluation, running finally blocks
+	// and deferred expressions on the way, and leaves it to the caller to
+	// act on the status.
+	"exit": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) > 1 {
+				return newError("wrong number of arguments. got=%d, want=0 or 1",
+					len(args))
+			}
+
+			if len(args) == 0 {
+				return &object.Exit{Code: 0}
+			}
+
+			code, ok := args[0].(*object.Integer)
+			if !ok {
+				return newError("argument to `exit` must be INTEGER, got %s",
+					args[0].Type())
+			}
+
+			return &object.Exit{Code: code.Value}
+		},
+	},
+}
+
+// processBuiltins reach the process through the host of the interpreter
+// calling them.
+var processBuiltins = map[string]hostBuiltin{
+	// exec runs a command without a shell and returns a hash with its
+	// stdout, stderr and exit status. A command that fails with a non-zero
+	// status is not an error; one that can't be started is.
+	"exec": func(host *object.Host, args ...object.Object) object.Object {
+		if host.Sandbox.DisableExec {
+			return newError("exec is disabled by the sandbox")
+		}
+
+		if len(args) < 1 {
+			return newError("wrong number of arguments. got=%d, want at least 1",
+				len(args))
+		}
+
+		strs := make([]string, len(args))
+		for i, arg := range args {
+			str, ok := arg.(*object.String)
+			if !ok {
+				return newError("arguments to `exec` must be STRING, got %s",
+					arg.Type())
+			}
+			strs[i] = str.Value
+		}
+
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(host.Context, strs[0], strs[1:]...)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		status := 0
+		if err := cmd.Run(); err != nil {
+			exitErr, ok := err.(*exec.ExitError)
+			if !ok {
+				return newError("exec: %s", err)
+			}
+			status = exitErr.ExitCode()
+		}
+
+		return newHash(map[string]object.Object{
+			"stdout": &object.String{Value: stdout.String()},
+			"stderr": &object.String{Value: stderr.String()},
+			"status": &object.Integer{Value: int64(status)},
+		})
+	},
+
+	"args": func(host *object.Host, args ...object.Object) object.Object {
+		if len(args) != 0 {
+			return newError("wrong number of arguments. got=%d, want=0",
+				len(args))
+		}
+
+		elements := make([]object.Object, len(host.Args))
+		for i, arg := range host.Args {
+			elements[i] = &object.String{Value: arg}
+		}
+
+		return &object.Array{Elements: elements}
+	},
+}
+
+func init() {
+	for name, builtin := range systemBuiltins {
+		builtins[name] = builtin
+	}
+	for name, fn := range processBuiltins {
+		environmentBuiltins[name] = bindHost(fn)
+	}
+}
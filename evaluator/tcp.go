@@ -0,0 +1,201 @@
+package evaluator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"monkey/object"
+	"net"
+)
+
+// defaultReadSize is how many bytes read returns at most when no limit is
+// given, and maxReadSize the most it returns whatever the limit.
+const (
+	defaultReadSize = 4096
+	maxReadSize     = 1 << 20
+)
+
+var tcpBuiltins = map[string]*object.Builtin{
+	// read returns at most the given number of bytes, 4096 by default and
+	// 1 MiB at most, and null once the other side has closed the connection.
+	"read": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2",
+					len(args))
+			}
+
+			conn, ok := args[0].(*object.Connection)
+			if !ok {
+				return newError("first argument to `read` must be CONNECTION, got %s",
+					args[0].Type())
+			}
+
+			size := int64(defaultReadSize)
+			if len(args) == 2 {
+				n, ok := args[1].(*object.Integer)
+				if !ok {
+					return newError("second argument to `read` must be INTEGER, got %s",
+						args[1].Type())
+				}
+				if n.Value <= 0 {
+					return newError("second argument to `read` must be positive, got %d",
+						n.Value)
+				}
+				size = min(n.Value, maxReadSize)
+			}
+
+			buffer := make([]byte, size)
+			n, err := conn.Conn.Read(buffer)
+			if err == io.EOF && n == 0 {
+				return NULL
+			}
+			if err != nil && err != io.EOF {
+				return newError("read: %s", err)
+			}
+
+			return &object.String{Value: string(buffer[:n])}
+		},
+	},
+
+	"write": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2",
+					len(args))
+			}
+
+			conn, ok := args[0].(*object.Connection)
+			if !ok {
+				return newError("first argument to `write` must be CONNECTION, got %s",
+					args[0].Type())
+			}
+
+			data, ok := args[1].(*object.String)
+			if !ok {
+				return newError("second argument to `write` must be STRING, got %s",
+					args[1].Type())
+			}
+
+			n, err := io.WriteString(conn.Conn, data.Value)
+			if err != nil {
+				return newError("write: %s", err)
+			}
+
+			return &object.Integer{Value: int64(n)}
+		},
+	},
+
+	"close": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			var err error
+			switch handle := args[0].(type) {
+			case *object.Connection:
+				err = handle.Conn.Close()
+			case *object.Listener:
+				err = handle.Listener.Close()
+			default:
+				return newError("argument to `close` must be CONNECTION or LISTENER, got %s",
+					args[0].Type())
+			}
+			if err != nil {
+				return newError("close: %s", err)
+			}
+
+			return NULL
+		},
+	},
+}
+
+// dialBuiltins connect and listen within the context of the host, so that
+// neither a dial nor a listener can outlast it.
+var dialBuiltins = map[string]hostBuiltin{
+	// tcp_listen closes the listener once the context of host is done, as
+	// serve does, which ends any accept on it.
+	"tcp_listen": func(host *object.Host, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError("wrong number of arguments. got=%d, want=1",
+				len(args))
+		}
+
+		port, ok := args[0].(*object.Integer)
+		if !ok {
+			return newError("argument to `tcp_listen` must be INTEGER, got %s",
+				args[0].Type())
+		}
+
+		var config net.ListenConfig
+		listener, err := config.Listen(host.Context, "tcp", fmt.Sprintf(":%d", port.Value))
+		if err != nil {
+			return newError("tcp_listen: %s", err)
+		}
+		context.AfterFunc(host.Context, func() { listener.Close() })
+
+		return &object.Listener{Listener: listener}
+	},
+
+	"accept": func(host *object.Host, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError("wrong number of arguments. got=%d, want=1",
+				len(args))
+		}
+
+		listener, ok := args[0].(*object.Listener)
+		if !ok {
+			return newError("argument to `accept` must be LISTENER, got %s",
+				args[0].Type())
+		}
+
+		conn, err := listener.Listener.Accept()
+		if err != nil {
+			if host.Context.Err() != nil {
+				return newError("accept: %s", host.Context.Err())
+			}
+			return newError("accept: %s", err)
+		}
+
+		return &object.Connection{Conn: conn}
+	},
+
+	"tcp_connect": func(host *object.Host, args ...object.Object) object.Object {
+		if len(args) != 2 {
+			return newError("wrong number of arguments. got=%d, want=2",
+				len(args))
+		}
+
+		hostname, ok := args[0].(*object.String)
+		if !ok {
+			return newError("first argument to `tcp_connect` must be STRING, got %s",
+				args[0].Type())
+		}
+
+		port, ok := args[1].(*object.Integer)
+		if !ok {
+			return newError("second argument to `tcp_connect` must be INTEGER, got %s",
+				args[1].Type())
+		}
+
+		var dialer net.Dialer
+		address := net.JoinHostPort(hostname.Value, fmt.Sprint(port.Value))
+		conn, err := dialer.DialContext(host.Context, "tcp", address)
+		if err != nil {
+			return newError("tcp_connect: %s", err)
+		}
+
+		return &object.Connection{Conn: conn}
+	},
+}
+
+func init() {
+	for name, builtin := range tcpBuiltins {
+		builtins[name] = builtin
+	}
+	for name, fn := range dialBuiltins {
+		environmentBuiltins[name] = bindHost(fn)
+	}
+}
@@ -0,0 +1,125 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"time"
+)
+
+// started is the reference point for clock.
+var started = time.Now()
+
+var timeBuiltins = map[string]*object.Builtin{
+	"now": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return newError("wrong number of arguments. got=%d, want=0",
+					len(args))
+			}
+
+			return &object.Integer{Value: time.Now().UnixMilli()}
+		},
+	},
+
+	// clock returns the milliseconds elapsed since the interpreter started
+	// from a monotonic clock, which makes it suitable for timing code.
+	"clock": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return newError("wrong number of arguments. got=%d, want=0",
+					len(args))
+			}
+
+			elapsed := time.Since(started)
+			return &object.Float{Value: float64(elapsed) / float64(time.Millisecond)}
+		},
+	},
+
+	// time_format and time_parse use Go's reference-time layouts, such as
+	// "2006-01-02 15:04:05", and interpret timestamps as UTC unix
+	// milliseconds, the same unit now returns.
+	"time_format": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2",
+					len(args))
+			}
+
+			ts, ok := args[0].(*object.Integer)
+			if !ok {
+				return newError("first argument to `time_format` must be INTEGER, got %s",
+					args[0].Type())
+			}
+
+			layout, ok := args[1].(*object.String)
+			if !ok {
+				return newError("second argument to `time_format` must be STRING, got %s",
+					args[1].Type())
+			}
+
+			formatted := time.UnixMilli(ts.Value).UTC().Format(layout.Value)
+			return &object.String{Value: formatted}
+		},
+	},
+
+	"time_parse": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2",
+					len(args))
+			}
+
+			for _, arg := range args {
+				if arg.Type() != object.STRING_OBJ {
+					return newError("arguments to `time_parse` must be STRING, got %s",
+						arg.Type())
+				}
+			}
+
+			value := args[0].(*object.String).Value
+			layout := args[1].(*object.String).Value
+
+			parsed, err := time.Parse(layout, value)
+			if err != nil {
+				return newError("time_parse: %s", err)
+			}
+
+			return &object.Integer{Value: parsed.UnixMilli()}
+		},
+	},
+}
+
+// blockingTimeBuiltins wait until the context of the host is done at the
+// latest.
+var blockingTimeBuiltins = map[string]hostBuiltin{
+	"sleep": func(host *object.Host, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError("wrong number of arguments. got=%d, want=1",
+				len(args))
+		}
+
+		ms, ok := args[0].(*object.Integer)
+		if !ok {
+			return newError("argument to `sleep` must be INTEGER, got %s",
+				args[0].Type())
+		}
+
+		timer := time.NewTimer(time.Duration(ms.Value) * time.Millisecond)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+			return NULL
+		case <-host.Context.Done():
+			return newError("sleep interrupted: %s", host.Context.Err())
+		}
+	},
+}
+
+func init() {
+	for name, builtin := range timeBuiltins {
+		builtins[name] = builtin
+	}
+	for name, fn := range blockingTimeBuiltins {
+		environmentBuiltins[name] = bindHost(fn)
+	}
+}
@@ -0,0 +1,48 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"sync"
+)
+
+// types holds one *object.Type per object type, so that type values can be
+// compared by identity like booleans and null. They're exposed as globals
+// under their names, e.g. `type(5) == INT`.
+var types = map[object.ObjectType]*object.Type{
+	object.INTEGER_OBJ:  {Name: "INT", Of: object.INTEGER_OBJ},
+	object.FLOAT_OBJ:    {Name: "FLOAT", Of: object.FLOAT_OBJ},
+	object.BOOLEAN_OBJ:  {Name: "BOOL", Of: object.BOOLEAN_OBJ},
+	object.NULL_OBJ:     {Name: "NULL", Of: object.NULL_OBJ},
+	object.STRING_OBJ:   {Name: "STRING", Of: object.STRING_OBJ},
+	object.ARRAY_OBJ:    {Name: "ARRAY", Of: object.ARRAY_OBJ},
+	object.HASH_OBJ:     {Name: "HASH", Of: object.HASH_OBJ},
+	object.FUNCTION_OBJ: {Name: "FUNCTION", Of: object.FUNCTION_OBJ},
+	object.BUILTIN_OBJ:  {Name: "BUILTIN", Of: object.BUILTIN_OBJ},
+	object.TYPE_OBJ:     {Name: "TYPE", Of: object.TYPE_OBJ},
+
+	object.ERROR_VALUE_OBJ: {Name: "ERROR", Of: object.ERROR_VALUE_OBJ},
+}
+
+// typesMu guards types, which typeOf adds to from concurrent HTTP handlers.
+var typesMu sync.Mutex
+
+var typesByName = func() map[string]*object.Type {
+	byName := make(map[string]*object.Type, len(types))
+	for _, t := range types {
+		byName[t.Name] = t
+	}
+	return byName
+}()
+
+func typeOf(obj object.Object) *object.Type {
+	typesMu.Lock()
+	defer typesMu.Unlock()
+
+	if t, ok := types[obj.Type()]; ok {
+		return t
+	}
+
+	t := &object.Type{Name: string(obj.Type()), Of: obj.Type()}
+	types[obj.Type()] = t
+	return t
+}
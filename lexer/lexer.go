@@ -9,10 +9,12 @@ type Lexer struct {
 	position     int // Current position in input (points to the current char)
 	readPosition int // Current reading position in input (points to after the current char)
 	char         byte
+	line         int // Line of the current char
+	column       int // Column of the current char
 }
 
 func New(input string) *Lexer {
-	lexer := &Lexer{input: input}
+	lexer := &Lexer{input: input, line: 1}
 	lexer.readChar()
 	return lexer
 }
@@ -22,6 +24,8 @@ func (lexer *Lexer) NextToken() token.Token {
 
 	lexer.skipWhitespace()
 
+	line, column := lexer.line, lexer.column
+
 	switch lexer.char {
 	case '=':
 		if lexer.peekChar() == '=' {
@@ -55,14 +59,63 @@ func (lexer *Lexer) NextToken() token.Token {
 		} else {
 			tok = newToken(token.BANG, lexer.char)
 		}
+	case '|':
+		if lexer.peekChar() == '>' {
+			char := lexer.char
+			lexer.readChar()
+			literal := string(char) + string(lexer.char)
+			tok = token.Token{Type: token.PIPE, Literal: literal}
+		} else {
+			tok = newToken(token.BAR, lexer.char)
+		}
+	case '.':
+		if lexer.peekChar() == '.' {
+			lexer.readChar()
+			if lexer.peekChar() == '=' {
+				lexer.readChar()
+				tok = token.Token{Type: token.RANGE_INCLUSIVE, Literal: "..="}
+			} else {
+				tok = token.Token{Type: token.RANGE, Literal: ".."}
+			}
+		} else {
+			tok = newToken(token.ILLEGAL, lexer.char)
+		}
+	case '?':
+		if lexer.peekChar() == '.' {
+			char := lexer.char
+			lexer.readChar()
+			literal := string(char) + string(lexer.char)
+			tok = token.Token{Type: token.OPTIONAL_CHAIN, Literal: literal}
+		} else if lexer.peekChar() == '?' {
+			char := lexer.char
+			lexer.readChar()
+			literal := string(char) + string(lexer.char)
+			tok = token.Token{Type: token.NULL_COALESCE, Literal: literal}
+		} else {
+			tok = newToken(token.ILLEGAL, lexer.char)
+		}
 	case '/':
 		tok = newToken(token.SLASH, lexer.char)
 	case '*':
-		tok = newToken(token.ASTERISK, lexer.char)
+		if lexer.peekChar() == '*' {
+			char := lexer.char
+			lexer.readChar()
+			literal := string(char) + string(lexer.char)
+			tok = token.Token{Type: token.POWER, Literal: literal}
+		} else {
+			tok = newToken(token.ASTERISK, lexer.char)
+		}
 	case '<':
 		tok = newToken(token.LT, lexer.char)
 	case '>':
-		tok = newToken(token.GT, lexer.char)
+		if lexer.peekChar() == '>' {
+			char := lexer.char
+			lexer.readChar()
+			literal := string(char) + string(lexer.char)
+			tok = token.Token{Type: token.COMPOSE, Literal: literal}
+		} else {
+			tok = newToken(token.GT, lexer.char)
+		}
 	case '{':
 		tok = newToken(token.LBRACE, lexer.char)
 	case '}':
@@ -81,10 +134,11 @@ func (lexer *Lexer) NextToken() token.Token {
 		if isLetter(lexer.char) {
 			tok.Literal = lexer.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Line, tok.Column = line, column
 			return tok
 		} else if isDigit(lexer.char) {
-			tok.Type = token.INT
-			tok.Literal = lexer.readNumber()
+			tok.Literal, tok.Type = lexer.readNumber()
+			tok.Line, tok.Column = line, column
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, lexer.char)
@@ -92,10 +146,16 @@ func (lexer *Lexer) NextToken() token.Token {
 	}
 	lexer.readChar()
 
+	tok.Line, tok.Column = line, column
 	return tok
 }
 
 func (lexer *Lexer) readChar() {
+	if lexer.char == '\n' {
+		lexer.line += 1
+		lexer.column = 0
+	}
+
 	if lexer.readPosition >= len(lexer.input) {
 		lexer.char = 0 // NULL character
 	} else {
@@ -103,6 +163,7 @@ func (lexer *Lexer) readChar() {
 	}
 	lexer.position = lexer.readPosition
 	lexer.readPosition += 1
+	lexer.column += 1
 }
 
 func (lexer *Lexer) readString() string {
@@ -116,9 +177,11 @@ func (lexer *Lexer) readString() string {
 	return lexer.input[position:lexer.position]
 }
 
+// readIdentifier reads a letter followed by any letters or digits, so names
+// like log2 are a single identifier.
 func (lexer *Lexer) readIdentifier() string {
 	position := lexer.position
-	for isLetter(lexer.char) {
+	for isLetter(lexer.char) || isDigit(lexer.char) {
 		lexer.readChar()
 	}
 	return lexer.input[position:lexer.position]
@@ -130,12 +193,23 @@ func (lexer *Lexer) skipWhitespace() {
 	}
 }
 
-func (lexer *Lexer) readNumber() string {
+// readNumber reads an integer or, if the digits are followed by a decimal
+// point and more digits, a float. A dot that isn't followed by a digit is
+// left alone so that ranges like 1..5 still lex.
+func (lexer *Lexer) readNumber() (string, token.Type) {
 	position := lexer.position
+	tokenType := token.Type(token.INT)
 	for isDigit(lexer.char) {
 		lexer.readChar()
 	}
-	return lexer.input[position:lexer.position]
+	if lexer.char == '.' && isDigit(lexer.peekChar()) {
+		tokenType = token.FLOAT
+		lexer.readChar()
+		for isDigit(lexer.char) {
+			lexer.readChar()
+		}
+	}
+	return lexer.input[position:lexer.position], tokenType
 }
 
 func (lexer *Lexer) peekChar() byte {
@@ -30,6 +30,14 @@ func TestNextToken(t *testing.T) {
 	"foo bar"
 	[1, 2];
 	{"foo": "bar"}
+	x |> f
+	1..10 1..=10
+	a?.b
+	a ?? b
+	2 ** 3
+	f >> g
+	3.14 1.5..2
+	log2 x1y
   `
 
 	expectedTokens := []struct {
@@ -122,6 +130,33 @@ func TestNextToken(t *testing.T) {
 		{token.COLON, ":"},
 		{token.STRING, "bar"},
 		{token.RBRACE, "}"},
+		{token.IDENT, "x"},
+		{token.PIPE, "|>"},
+		{token.IDENT, "f"},
+		{token.INT, "1"},
+		{token.RANGE, ".."},
+		{token.INT, "10"},
+		{token.INT, "1"},
+		{token.RANGE_INCLUSIVE, "..="},
+		{token.INT, "10"},
+		{token.IDENT, "a"},
+		{token.OPTIONAL_CHAIN, "?."},
+		{token.IDENT, "b"},
+		{token.IDENT, "a"},
+		{token.NULL_COALESCE, "??"},
+		{token.IDENT, "b"},
+		{token.INT, "2"},
+		{token.POWER, "**"},
+		{token.INT, "3"},
+		{token.IDENT, "f"},
+		{token.COMPOSE, ">>"},
+		{token.IDENT, "g"},
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "1.5"},
+		{token.RANGE, ".."},
+		{token.INT, "2"},
+		{token.IDENT, "log2"},
+		{token.IDENT, "x1y"},
 		{token.EOF, ""},
 	}
 
@@ -141,3 +176,41 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := `let x = 5;
+  x + "foo"
+`
+
+	expectedTokens := []struct {
+		expectedType   token.Type
+		expectedLine   int
+		expectedColumn int
+	}{
+		{token.LET, 1, 1},
+		{token.IDENT, 1, 5},
+		{token.ASSIGN, 1, 7},
+		{token.INT, 1, 9},
+		{token.SEMICOLON, 1, 10},
+		{token.IDENT, 2, 3},
+		{token.PLUS, 2, 5},
+		{token.STRING, 2, 7},
+		{token.EOF, 3, 1},
+	}
+
+	lexer := New(input)
+
+	for i, expectedToken := range expectedTokens {
+		tok := lexer.NextToken()
+
+		if tok.Type != expectedToken.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, expectedToken.expectedType, tok.Type)
+		}
+
+		if tok.Line != expectedToken.expectedLine || tok.Column != expectedToken.expectedColumn {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d",
+				i, expectedToken.expectedLine, expectedToken.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
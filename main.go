@@ -2,13 +2,76 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/user"
+	"strings"
 
+	"monkey/ast"
+	"monkey/checker"
+	"monkey/diagnostic"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/optimizer"
+	"monkey/parser"
 	"monkey/repl"
+	"monkey/transpile/golang"
+	"monkey/transpile/javascript"
+	"monkey/transpile/wasm"
 )
 
+// usage describes how to run a script or start the REPL.
+const usage = "usage: monkey [[-O0|-O1|-O2] script [args...]]"
+
 func main() {
+	if len(os.Args) > 2 && os.Args[1] == "lint" {
+		var options checker.Options
+		asJSON := false
+		paths := os.Args[2:]
+		for len(paths) > 1 && (paths[0] == "-shadow" || paths[0] == "-json") {
+			if paths[0] == "-shadow" {
+				options.Shadowing = true
+			} else {
+				asJSON = true
+			}
+			paths = paths[1:]
+		}
+		os.Exit(lintFiles(paths, options, asJSON))
+	}
+	if len(os.Args) == 3 && os.Args[1] == "transpile" {
+		os.Exit(transpileFile(os.Args[2], golang.Transpile))
+	}
+	if len(os.Args) == 4 && os.Args[1] == "transpile" && os.Args[2] == "-js" {
+		os.Exit(transpileFile(os.Args[3], javascript.Transpile))
+	}
+	if len(os.Args) == 4 && os.Args[1] == "transpile" && os.Args[2] == "-wasm" {
+		os.Exit(transpileFile(os.Args[3], func(program *ast.Program) (string, error) {
+			module, err := wasm.Transpile(program)
+			return string(module), err
+		}))
+	}
+	level, args := optimizer.O2, os.Args[1:]
+	if len(args) > 0 && strings.HasPrefix(args[0], "-O") {
+		var err error
+		if level, err = optimizer.ParseLevel(args[0]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(2)
+		}
+		args = args[1:]
+	}
+	if len(args) > 0 {
+		os.Exit(runFile(args[0], args[1:], level))
+	}
+	if len(os.Args) > 1 {
+		// The REPL evaluates each line as it comes, and optimizing one on its
+		// own could drop a binding a later line uses, so it takes no level.
+		fmt.Fprintln(os.Stderr, "optimization levels only apply to scripts")
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
 	current, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -18,3 +81,158 @@ func main() {
 	fmt.Printf("Feel free to type in commands.\n")
 	repl.Start(os.Stdin, os.Stdout)
 }
+
+// runFile evaluates the script at path, optimized at level, making args
+// available to it through the args builtin, and returns the process exit
+// status.
+func runFile(path string, args []string, level optimizer.Level) int {
+	source, program, ok := loadFile(path)
+	if !ok {
+		return 1
+	}
+
+	optimizer.Optimize(program, level)
+
+	env := object.NewEnvironment()
+	env.Host().Args = args
+	switch evaluated := evaluator.Eval(program, env).(type) {
+	case *object.Error:
+		var snippet string
+		if evaluated.File == "" {
+			evaluated.File, snippet = path, source
+		}
+		io.WriteString(os.Stderr, runtimeDiagnostic(evaluated).Text(snippet))
+		return 1
+	case *object.Exit:
+		return int(evaluated.Code)
+	default:
+		return 0
+	}
+}
+
+// runtimeDiagnostic describes err, an error that ended a program, as a
+// diagnostic with the code "runtime".
+func runtimeDiagnostic(err *object.Error) diagnostic.Diagnostic {
+	position := diagnostic.Position{Line: err.Line, Column: err.Column}
+	return diagnostic.Diagnostic{
+		Code:     "runtime",
+		Severity: diagnostic.Error,
+		Message:  err.Inspect(),
+		File:     err.File,
+		Span:     diagnostic.Span{Start: position, End: position},
+	}
+}
+
+// lintFiles reports the errors and warnings the checker finds in the scripts
+// at paths without running them, as text on stderr or, with asJSON, as a
+// JSON array on stdout. The exit status is 1 if there are errors, warnings
+// alone don't fail.
+func lintFiles(paths []string, options checker.Options, asJSON bool) int {
+	status := 0
+	all := []diagnostic.Diagnostic{}
+	for _, path := range paths {
+		source, program, diagnostics, ok := parseFile(path)
+		if !ok {
+			status = 1
+			continue
+		}
+		if len(diagnostics) == 0 {
+			for _, found := range checker.Diagnose(program, options) {
+				found.File = path
+				diagnostics = append(diagnostics, found)
+			}
+		}
+
+		for _, found := range diagnostics {
+			if found.Severity == diagnostic.Error {
+				status = 1
+			}
+			if !asJSON {
+				io.WriteString(os.Stderr, found.Text(source))
+			}
+		}
+		all = append(all, diagnostics...)
+	}
+
+	if asJSON {
+		encoded, err := diagnostic.JSON(all)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Println(string(encoded))
+	}
+	return status
+}
+
+// loadFile parses the script at path, expands its macros and checks it,
+// printing any errors found. It reports false if there are errors.
+func loadFile(path string) (string, *ast.Program, bool) {
+	source, program, diagnostics, ok := parseFile(path)
+	if !ok {
+		return "", nil, false
+	}
+	if len(diagnostics) == 0 {
+		macros := object.NewEnvironment()
+		evaluator.DefineMacros(program, macros)
+		if _, err := evaluator.ExpandMacros(program, macros); err != nil {
+			var snippet string
+			if err.File == "" {
+				err.File, snippet = path, source
+			}
+			io.WriteString(os.Stderr, runtimeDiagnostic(err).Text(snippet))
+			return "", nil, false
+		}
+
+		for _, found := range checker.Diagnose(program, checker.Options{}) {
+			if found.Severity == diagnostic.Error {
+				found.File = path
+				diagnostics = append(diagnostics, found)
+			}
+		}
+	}
+
+	for _, found := range diagnostics {
+		io.WriteString(os.Stderr, found.Text(source))
+	}
+	return source, program, len(diagnostics) == 0
+}
+
+// transpileFile prints the source of a program doing what the script at path
+// does, written by transpile: Go by default, an ES module with -js, or a
+// binary WebAssembly module with -wasm.
+func transpileFile(path string, transpile func(*ast.Program) (string, error)) int {
+	_, program, ok := loadFile(path)
+	if !ok {
+		return 1
+	}
+
+	source, err := transpile(program)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Print(source)
+	return 0
+}
+
+// parseFile reads and parses the script at path, returning the syntax errors
+// found as diagnostics. It prints the error and reports false if the file
+// can't be read.
+func parseFile(path string) (string, *ast.Program, []diagnostic.Diagnostic, bool) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return "", nil, nil, false
+	}
+	source := string(content)
+
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	diagnostics := p.Diagnostics()
+	for i := range diagnostics {
+		diagnostics[i].File = path
+	}
+
+	return source, program, diagnostics, true
+}
@@ -1,19 +1,47 @@
 package object
 
+import (
+	"bufio"
+	"context"
+	"io"
+	"math/rand"
+	"monkey/ast"
+	"os"
+	"time"
+)
+
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
 	return env
 }
 
+// NewCatchEnvironment returns an environment for a catch clause in outer
+// that binds name, the parameter of the clause, to val. The parameter stays
+// inside it, while the other names bound in the clause and the expressions
+// deferred in it go to outer, as they would in any other block.
+func NewCatchEnvironment(outer *Environment, name string, val Object) *Environment {
+	env := NewEnclosedEnvironment(outer)
+	env.store[name] = val
+	env.block = true
+	return env
+}
+
 func NewEnvironment() *Environment {
 	store := make(map[string]Object)
-	return &Environment{store: store, outer: nil}
+	mutable := make(map[string]bool)
+	return &Environment{store: store, mutable: mutable, outer: nil}
 }
 
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store    map[string]Object
+	mutable  map[string]bool // Names bound with var rather than let
+	outer    *Environment
+	deferred []ast.Expression
+	random   *rand.Rand // Only set on the outermost environment
+	host     *Host      // Only set on the outermost environment
+	imports  []string   // Only set on the outermost environment, see Imports
+	block    bool       // Whether new bindings and deferred expressions go to outer, see NewCatchEnvironment
 }
 
 func (env *Environment) Get(name string) (Object, bool) {
@@ -24,7 +52,175 @@ func (env *Environment) Get(name string) (Object, bool) {
 	return obj, ok
 }
 
+// Set binds name immutably, replacing any previous binding in env.
 func (env *Environment) Set(name string, val Object) Object {
+	if _, ok := env.store[name]; env.block && !ok {
+		return env.outer.Set(name, val)
+	}
 	env.store[name] = val
+	delete(env.mutable, name)
 	return val
 }
+
+// SetMutable binds name so that it can later be reassigned with Assign.
+func (env *Environment) SetMutable(name string, val Object) Object {
+	if _, ok := env.store[name]; env.block && !ok {
+		return env.outer.SetMutable(name, val)
+	}
+	env.store[name] = val
+	env.mutable[name] = true
+	return val
+}
+
+// Assign rebinds name in the innermost environment defining it, provided
+// it was bound with SetMutable. It reports whether name is defined at all
+// and whether it was mutable.
+func (env *Environment) Assign(name string, val Object) (found, mutable bool) {
+	if _, ok := env.store[name]; ok {
+		if !env.mutable[name] {
+			return true, false
+		}
+		env.store[name] = val
+		return true, true
+	}
+
+	if env.outer != nil {
+		return env.outer.Assign(name, val)
+	}
+
+	return false, false
+}
+
+// Mutable reports whether name is bound directly in env with SetMutable.
+func (env *Environment) Mutable(name string) bool {
+	return env.mutable[name]
+}
+
+// Defer schedules expression to be evaluated once the function call (or
+// program) owning this environment is done.
+func (env *Environment) Defer(expression ast.Expression) {
+	if env.block {
+		env.outer.Defer(expression)
+		return
+	}
+	env.deferred = append(env.deferred, expression)
+}
+
+// TakeDeferred returns the scheduled expressions in the order they have to
+// be evaluated in, last deferred first, and clears them.
+func (env *Environment) TakeDeferred() []ast.Expression {
+	deferred := make([]ast.Expression, 0, len(env.deferred))
+	for i := len(env.deferred) - 1; i >= 0; i-- {
+		deferred = append(deferred, env.deferred[i])
+	}
+	env.deferred = nil
+	return deferred
+}
+
+func (env *Environment) HasDeferred() bool {
+	return len(env.deferred) > 0
+}
+
+// Random returns the random number source of the outermost environment, so
+// that every scope of one interpreter draws from the same source while
+// separate interpreters don't share any state. It's seeded from the clock
+// until Seed is called.
+func (env *Environment) Random() *rand.Rand {
+	root := env.root()
+	if root.random == nil {
+		root.random = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return root.random
+}
+
+// Seed resets the random number source of the outermost environment.
+func (env *Environment) Seed(seed int64) {
+	env.root().random = rand.New(rand.NewSource(seed))
+}
+
+// A Host is what the programs of one interpreter can reach of the process
+// running them. Programs embedding the interpreter can point its streams
+// anywhere, give it a context with a deadline or that can be cancelled, so
+// that scripts can't hang forever, and switch capabilities off before
+// evaluating untrusted code.
+type Host struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	Args    []string        // The command-line arguments given after the script name
+	Context context.Context // Bounds builtins that block, such as sleep
+	Sandbox Sandbox
+
+	lines       *bufio.Reader // Buffers Stdin for reading it line by line
+	linesSource io.Reader     // The reader lines buffers
+}
+
+// Sandbox restricts what scripts may do to the host they run on. The zero
+// value allows everything.
+type Sandbox struct {
+	DisableExec       bool // Makes exec fail
+	DisableFileWrites bool // Makes write_file, append_file, mkdir and remove fail
+}
+
+// NewHost returns a host with the standard streams of the process, no
+// arguments, a context that's never done and nothing disabled.
+func NewHost() *Host {
+	return &Host{Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr, Context: context.Background()}
+}
+
+// Lines returns a buffered reader for Stdin, which is kept across calls so
+// that nothing read ahead is lost.
+func (host *Host) Lines() *bufio.Reader {
+	if host.lines == nil || host.linesSource != host.Stdin {
+		host.lines, host.linesSource = bufio.NewReader(host.Stdin), host.Stdin
+	}
+	return host.lines
+}
+
+// Host returns the host of the outermost environment, so that every scope
+// of one interpreter sees the same one. It's made with NewHost until
+// SetHost is called.
+func (env *Environment) Host() *Host {
+	root := env.root()
+	if root.host == nil {
+		root.host = NewHost()
+	}
+	return root.host
+}
+
+// SetHost makes host the host of the outermost environment, for instance
+// to share one between an interpreter and the modules it imports.
+func (env *Environment) SetHost(host *Host) {
+	env.root().host = host
+}
+
+// Imports returns the names of the modules being imported that led to
+// evaluating the outermost environment, the innermost last. It's empty for
+// the environment of a program that isn't a module.
+func (env *Environment) Imports() []string {
+	return env.root().imports
+}
+
+// SetImports sets the names of the modules being imported that led to
+// evaluating the outermost environment.
+func (env *Environment) SetImports(names []string) {
+	env.root().imports = names
+}
+
+func (env *Environment) root() *Environment {
+	for env.outer != nil {
+		env = env.outer
+	}
+	return env
+}
+
+// Bindings returns the names bound directly in env, leaving out those of
+// the environments enclosing it.
+func (env *Environment) Bindings() map[string]Object {
+	bindings := make(map[string]Object, len(env.store))
+	for name, val := range env.store {
+		bindings[name] = val
+	}
+	return bindings
+}
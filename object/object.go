@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
 	"monkey/ast"
+	"net"
+	"strconv"
 	"strings"
 )
 
@@ -13,15 +16,26 @@ type BuiltinFunction func(args ...Object) Object
 
 const (
 	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	TAIL_CALL_OBJ    = "TAIL_CALL"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
+	EXIT_OBJ         = "EXIT"
+	ERROR_VALUE_OBJ  = "ERROR_VALUE"
 	ERROR_OBJ        = "ERROR"
 	FUNCTION_OBJ     = "FUNCTION"
 	STRING_OBJ       = "STRING"
 	BUILTIN_OBJ      = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
 	HASH_OBJ         = "HASH"
+	TYPE_OBJ         = "TYPE"
+	CONNECTION_OBJ   = "CONNECTION"
+	LISTENER_OBJ     = "LISTENER"
+	QUOTE_OBJ        = "QUOTE"
+	MACRO_OBJ        = "MACRO"
 )
 
 type Object interface {
@@ -39,6 +53,25 @@ func (integer *Integer) HashKey() HashKey {
 	return HashKey{Type: integer.Type(), Value: uint64(integer.Value)}
 }
 
+type Float struct {
+	Value float64
+}
+
+func (float *Float) Type() ObjectType { return FLOAT_OBJ }
+
+// Inspect always includes a decimal point or exponent so that floats with
+// integral values can be told apart from integers.
+func (float *Float) Inspect() string {
+	out := strconv.FormatFloat(float.Value, 'g', -1, 64)
+	if !strings.ContainsAny(out, ".eIN") {
+		out += ".0"
+	}
+	return out
+}
+func (float *Float) HashKey() HashKey {
+	return HashKey{Type: float.Type(), Value: math.Float64bits(float.Value)}
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -70,13 +103,68 @@ type ReturnValue struct {
 func (returnValue *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (returnValue *ReturnValue) Inspect() string  { return returnValue.Value.Inspect() }
 
+// TailCall is a call in tail position that has not been applied yet. The
+// evaluator hands it back to the caller's applyFunction loop, so tail calls
+// run in constant Go stack space.
+type TailCall struct {
+	Function  Object
+	Arguments []Object
+}
+
+func (tailCall *TailCall) Type() ObjectType { return TAIL_CALL_OBJ }
+func (tailCall *TailCall) Inspect() string  { return "tail call" }
+
+// Break and Continue unwind the evaluation of blocks up to the loop they
+// target. An empty Label targets the innermost loop. The loop a Break ends
+// evaluates to its Value.
+type Break struct {
+	Label string
+	Value Object
+}
+
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+func (b *Break) Inspect() string  { return "break" }
+
+type Continue struct {
+	Label string
+}
+
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+func (c *Continue) Inspect() string  { return "continue" }
+
+// Exit is returned by the exit builtin and unwinds the whole program, like
+// an error that can't be caught.
+type Exit struct {
+	Code int64
+}
+
+func (e *Exit) Type() ObjectType { return EXIT_OBJ }
+func (e *Exit) Inspect() string  { return fmt.Sprintf("exit %d", e.Code) }
+
 type Error struct {
 	Message string
+	Value   Object // The thrown value, nil for errors raised by the runtime
+
+	// Where the error was raised, file being empty for the main program and
+	// line being 0 when the position isn't known.
+	File   string
+	Line   int
+	Column int
 }
 
 func (error *Error) Type() ObjectType { return ERROR_OBJ }
 func (error *Error) Inspect() string  { return "ERROR: " + error.Message }
 
+// ErrorValue is an error that is an ordinary value rather than something
+// aborting evaluation. It's created by the error builtin and returned by
+// builtins whose failures programs are expected to handle.
+type ErrorValue struct {
+	Message string
+}
+
+func (e *ErrorValue) Type() ObjectType { return ERROR_VALUE_OBJ }
+func (e *ErrorValue) Inspect() string  { return "error: " + e.Message }
+
 type Function struct {
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
@@ -102,6 +190,39 @@ func (fn *Function) Inspect() string {
 	return out.String()
 }
 
+// Quote is code as a value, as returned by the quote function.
+type Quote struct {
+	Node ast.Node
+}
+
+func (quote *Quote) Type() ObjectType { return QUOTE_OBJ }
+func (quote *Quote) Inspect() string  { return "QUOTE(" + quote.Node.String() + ")" }
+
+type Macro struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (macro *Macro) Type() ObjectType { return MACRO_OBJ }
+func (macro *Macro) Inspect() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, param := range macro.Parameters {
+		params = append(params, param.String())
+	}
+
+	out.WriteString("macro")
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") {\n")
+	out.WriteString(macro.Body.String())
+	out.WriteString("\n}")
+
+	return out.String()
+}
+
 type String struct {
 	Value string
 }
@@ -156,8 +277,45 @@ type HashPair struct {
 	Value Object
 }
 
+// Hash is ordered by insertion: its pairs are enumerated in the order their
+// keys were first set.
 type Hash struct {
 	Pairs map[HashKey]HashPair
+	Order []HashKey // Keys of Pairs in insertion order
+}
+
+// Set adds pair to the hash under key, keeping the position of the key if
+// it's already present.
+func (hash *Hash) Set(key HashKey, pair HashPair) {
+	if hash.Pairs == nil {
+		hash.Pairs = make(map[HashKey]HashPair)
+	}
+	if _, ok := hash.Pairs[key]; !ok {
+		hash.Order = append(hash.Order, key)
+	}
+	hash.Pairs[key] = pair
+}
+
+// Ordered returns the pairs of the hash in insertion order. Pairs put into
+// Pairs directly rather than with Set follow in no particular order.
+func (hash *Hash) Ordered() []HashPair {
+	pairs := make([]HashPair, 0, len(hash.Pairs))
+	listed := make(map[HashKey]bool, len(hash.Order))
+	for _, key := range hash.Order {
+		if pair, ok := hash.Pairs[key]; ok && !listed[key] {
+			pairs = append(pairs, pair)
+			listed[key] = true
+		}
+	}
+	if len(pairs) == len(hash.Pairs) {
+		return pairs
+	}
+	for key, pair := range hash.Pairs {
+		if !listed[key] {
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs
 }
 
 func (hash *Hash) Type() ObjectType { return HASH_OBJ }
@@ -165,7 +323,7 @@ func (hash *Hash) Inspect() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for _, pair := range hash.Pairs {
+	for _, pair := range hash.Ordered() {
 		pairs = append(pairs, fmt.Sprintf("%s: %s",
 			pair.Key.Inspect(), pair.Value.Inspect()))
 	}
@@ -176,3 +334,39 @@ func (hash *Hash) Inspect() string {
 
 	return out.String()
 }
+
+// Connection is a handle to an open network connection.
+type Connection struct {
+	Conn net.Conn
+}
+
+func (c *Connection) Type() ObjectType { return CONNECTION_OBJ }
+func (c *Connection) Inspect() string {
+	return fmt.Sprintf("connection(%s)", c.Conn.RemoteAddr())
+}
+
+// Listener is a handle to a socket accepting network connections.
+type Listener struct {
+	Listener net.Listener
+}
+
+func (l *Listener) Type() ObjectType { return LISTENER_OBJ }
+func (l *Listener) Inspect() string {
+	return fmt.Sprintf("listener(%s)", l.Listener.Addr())
+}
+
+// Type is the first-class value describing the type of an object, as
+// returned by the `type` builtin.
+type Type struct {
+	Name string
+	Of   ObjectType
+}
+
+func (t *Type) Type() ObjectType { return TYPE_OBJ }
+func (t *Type) Inspect() string  { return t.Name }
+func (t *Type) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(t.Of))
+
+	return HashKey{Type: t.Type(), Value: h.Sum64()}
+}
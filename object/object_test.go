@@ -20,3 +20,21 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestHashOrder(t *testing.T) {
+	hash := &Hash{}
+	for _, name := range []string{"c", "a", "b", "a"} {
+		key := &String{Value: name}
+		hash.Set(key.HashKey(), HashPair{Key: key, Value: &Integer{Value: int64(len(hash.Order))}})
+	}
+
+	if hash.Inspect() != "{c: 0, a: 3, b: 2}" {
+		t.Errorf("wrong order. got=%s", hash.Inspect())
+	}
+
+	extra := &String{Value: "d"}
+	hash.Pairs[extra.HashKey()] = HashPair{Key: extra, Value: &Integer{Value: 4}}
+	if pairs := hash.Ordered(); len(pairs) != 4 || pairs[3].Key != extra {
+		t.Errorf("pair added to Pairs directly is not last. got=%v", pairs)
+	}
+}
@@ -0,0 +1,34 @@
+package optimizer
+
+import "monkey/ast"
+
+// EliminateDeadCode drops the statements of a block that follow a return,
+// throw, break or continue, since evaluation never reaches them.
+func EliminateDeadCode(node ast.Node) ast.Node {
+	if quotes(node) {
+		return node
+	}
+	return ast.Modify(node, eliminateDeadCode)
+}
+
+func eliminateDeadCode(node ast.Node) ast.Node {
+	switch node := node.(type) {
+	case *ast.Program:
+		node.Statements = reachableStatements(node.Statements)
+	case *ast.BlockStatement:
+		node.Statements = reachableStatements(node.Statements)
+	}
+
+	return node
+}
+
+func reachableStatements(statements []ast.Statement) []ast.Statement {
+	for i, statement := range statements {
+		switch statement.(type) {
+		case *ast.ReturnStatement, *ast.ThrowStatement, *ast.BreakStatement, *ast.ContinueStatement:
+			return statements[:i+1]
+		}
+	}
+
+	return statements
+}
@@ -0,0 +1,28 @@
+package optimizer
+
+import "testing"
+
+func TestEliminateDeadCode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1; 2", "12"},
+		{"return 1; 2; 3", "return 1;"},
+		{"fn() { return 1; puts(2); }", "fn()return 1;"},
+		{"fn() { if (x) { return 1; } 2 }", "fn()ifx return 1;2"},
+		{`do { break; puts(1) } while (true)`, "do break; whiletrue"},
+		{`do { continue; puts(1) } while (true)`, "do continue; whiletrue"},
+		{`try { throw "boom"; 1 } catch (e) { e }`, "try throw boom; catch(e) e"},
+	}
+
+	for _, test := range tests {
+		program := parse(t, test.input)
+		eliminated := EliminateDeadCode(program)
+
+		if eliminated.String() != test.expected {
+			t.Errorf("wrong elimination in %q. expected=%q, got=%q",
+				test.input, test.expected, eliminated.String())
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Package optimizer rewrites programs into cheaper ones that evaluate to the
+// same result, before they're handed to the evaluator. Programs that call
+// quote are left as they are by every pass, since the code they quote is a
+// value that must stay as it's written.
+package optimizer
+
+import (
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/object"
+	"monkey/token"
+)
+
+// pureBuiltins are the builtins whose result only depends on their
+// arguments, so calling them on literals can be done ahead of time.
+var pureBuiltins = map[string]bool{
+	"len": true, "first": true, "last": true, "rest": true,
+	"upper": true, "lower": true, "trim": true, "ord": true, "chr": true,
+	"contains": true, "starts_with": true, "ends_with": true, "index_of": true,
+	"abs": true, "min": true, "max": true, "str": true, "int": true, "float": true,
+}
+
+// Fold replaces prefix and infix operators applied to literals with the
+// literal they evaluate to, so `2 * 3 + 4` becomes `10` and `"a" + "b"`
+// becomes `"ab"`, and does the same for calls of pure builtins like
+// `len("hello")`. Operations that fail at runtime are left as they are, so
+// their errors still happen when the program runs.
+func Fold(node ast.Node) ast.Node {
+	if quotes(node) {
+		return node
+	}
+	bindings, dynamic := bindingCounts(node)
+
+	return ast.Modify(node, func(node ast.Node) ast.Node {
+		switch node := node.(type) {
+		case *ast.PrefixExpression:
+			if isLiteral(node.Right) {
+				return evaluateScalar(node, node.Token)
+			}
+
+		case *ast.InfixExpression:
+			if isLiteral(node.Left) && isLiteral(node.Right) {
+				return evaluateScalar(node, node.Token)
+			}
+
+		case *ast.CallExpression:
+			function, ok := node.Function.(*ast.Identifier)
+			if !ok || !pureBuiltins[function.Value] || bindings[function.Value] > 0 || dynamic {
+				return node
+			}
+			for _, argument := range node.Arguments {
+				if !isConstant(argument) {
+					return node
+				}
+			}
+			return evaluate(node, function.Token)
+		}
+
+		return node
+	})
+}
+
+// quotes reports whether the program rooted at node calls quote.
+func quotes(node ast.Node) bool {
+	found := false
+	ast.Inspect(node, func(node ast.Node) bool {
+		if identifier, ok := node.(*ast.Identifier); ok && identifier.Value == "quote" {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// isConstant reports whether expression is a literal or an array of them.
+func isConstant(expression ast.Expression) bool {
+	if array, ok := expression.(*ast.ArrayLiteral); ok {
+		for _, element := range array.Elements {
+			if !isConstant(element) {
+				return false
+			}
+		}
+		return true
+	}
+	return isLiteral(expression)
+}
+
+func isLiteral(expression ast.Expression) bool {
+	switch expression.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.Boolean, *ast.StringLiteral:
+		return true
+	default:
+		return false
+	}
+}
+
+func literalToken(expression ast.Expression) token.Token {
+	switch literal := expression.(type) {
+	case *ast.IntegerLiteral:
+		return literal.Token
+	case *ast.FloatLiteral:
+		return literal.Token
+	case *ast.Boolean:
+		return literal.Token
+	case *ast.StringLiteral:
+		return literal.Token
+	default:
+		return token.Token{}
+	}
+}
+
+// evaluate returns the literal expression evaluates to, positioned at tok,
+// or expression itself when the result isn't something a literal can hold.
+func evaluate(expression ast.Expression, tok token.Token) ast.Node {
+	result := evaluator.Eval(expression, object.NewEnvironment())
+
+	if literal, ok := toLiteral(result, tok); ok {
+		return literal
+	}
+	return expression
+}
+
+// evaluateScalar is like evaluate but leaves expression as it is when it
+// evaluates to an array, so ranges like `1..1000` aren't expanded.
+func evaluateScalar(expression ast.Expression, tok token.Token) ast.Node {
+	if folded := evaluate(expression, tok); isLiteral(folded.(ast.Expression)) {
+		return folded
+	}
+	return expression
+}
+
+func toLiteral(obj object.Object, tok token.Token) (ast.Expression, bool) {
+	literal := func(tokenType token.Type, value string) token.Token {
+		return token.Token{Type: tokenType, Literal: value, Line: tok.Line, Column: tok.Column}
+	}
+
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return &ast.IntegerLiteral{Token: literal(token.INT, obj.Inspect()), Value: obj.Value}, true
+	case *object.Float:
+		return &ast.FloatLiteral{Token: literal(token.FLOAT, obj.Inspect()), Value: obj.Value}, true
+	case *object.Boolean:
+		if obj.Value {
+			return &ast.Boolean{Token: literal(token.TRUE, "true"), Value: true}, true
+		}
+		return &ast.Boolean{Token: literal(token.FALSE, "false"), Value: false}, true
+	case *object.String:
+		return &ast.StringLiteral{Token: literal(token.STRING, obj.Value), Value: obj.Value}, true
+	case *object.Array:
+		array := &ast.ArrayLiteral{Token: literal(token.LBRACKET, "["), Elements: []ast.Expression{}}
+		for _, element := range obj.Elements {
+			elementLiteral, ok := toLiteral(element, tok)
+			if !ok {
+				return nil, false
+			}
+			array.Elements = append(array.Elements, elementLiteral)
+		}
+		return array, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,101 @@
+package optimizer
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"testing"
+)
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2 * 3 + 4", "10"},
+		{"-5 + 2", "-3"},
+		{"2 ** 10 / 4", "256"},
+		{"1.5 * 2", "3.0"},
+		{"!true", "false"},
+		{"!(1 < 2)", "false"},
+		{"true == false", "false"},
+		{`"mon" + "key"`, "monkey"},
+		{`"a" in "abc"`, "true"},
+		{"let x = 2 * 3; x * 4", "let x = 6;(x * 4)"},
+		{"x + 1 + 2", "((x + 1) + 2)"},
+		{"fn() { return 1 + 1; }", "fn()return 2;"},
+		{"[1 + 1, 2 * 2][0 + 1]", "([2, 4][1])"},
+		{"if (1 > 2) { 3 } else { 4 - 1 }", "iffalse 3else 3"},
+		{"1 / 0", "(1 / 0)"},
+		{"1 ** -1", "(1 ** -1)"},
+		{"quote(1 + 2); 3 * 4", "quote((1 + 2))(3 * 4)"},
+		{`1 + "a"`, "(1 + a)"},
+		{"1..3", "(1 .. 3)"},
+	}
+
+	for _, test := range tests {
+		program := parse(t, test.input)
+		folded := Fold(program)
+
+		if folded.String() != test.expected {
+			t.Errorf("wrong folding of %q. expected=%q, got=%q",
+				test.input, test.expected, folded.String())
+		}
+	}
+}
+
+func TestFoldKeepsPositions(t *testing.T) {
+	program := parse(t, "let x = 1;\n  2 * 3")
+	Fold(program)
+
+	statement := program.Statements[1].(*ast.ExpressionStatement)
+	literal, ok := statement.Expression.(*ast.IntegerLiteral)
+	if !ok {
+		t.Fatalf("expression is not *ast.IntegerLiteral. got=%T", statement.Expression)
+	}
+	if literal.Token.Line != 2 || literal.Token.Column != 5 {
+		t.Errorf("wrong position. got=%d:%d", literal.Token.Line, literal.Token.Column)
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Diagnostics())
+	}
+
+	return program
+}
+
+func TestFoldPureBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`len("hello")`, "5"},
+		{"first([1, 2, 3])", "1"},
+		{"rest([1, 2 * 3, 3])", "[6, 3]"},
+		{`upper("a" + "b")`, "AB"},
+		{"max(1, 5, 3) * 2", "10"},
+		{`len("héllo") + 1`, "6"},
+		{"len(x)", "len(x)"},
+		{"len(1)", "len(1)"},
+		{"puts(1)", "puts(1)"},
+		{`let len = fn(x) { 0 }; len("abc")`, `let len = fn(x)0;len(abc)`},
+		{`let f = fn(first) { first([1]) }`, "let f = fn(first)first([1]);"},
+		{`eval("let len = 1;", true); len("abc")`, `eval(let len = 1;, true)len(abc)`},
+		{`first([])`, "first([])"},
+	}
+
+	for _, test := range tests {
+		folded := Fold(parse(t, test.input))
+
+		if folded.String() != test.expected {
+			t.Errorf("wrong folding of %q. expected=%q, got=%q",
+				test.input, test.expected, folded.String())
+		}
+	}
+}
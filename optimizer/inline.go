@@ -0,0 +1,221 @@
+package optimizer
+
+import (
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/token"
+)
+
+// DefaultInlineSize is the largest function body, counted in AST nodes, that
+// Inline splices into its callers by default.
+const DefaultInlineSize = 16
+
+// Inline replaces calls of small functions with the function's body, its
+// parameters replaced by the arguments. A function is inlined when:
+//
+//   - it's called directly, as in `fn(x) { x * 2 }(3)`, or bound once with
+//     let and called after the binding;
+//   - its body is a single expression of at most maxSize nodes, made of
+//     literals, operators, indexing, array literals and calls of builtins;
+//   - it doesn't capture anything, only referring to its parameters and to
+//     builtins the program doesn't shadow;
+//   - every argument is a literal or an identifier, so evaluating it once
+//     or several times makes no difference.
+func Inline(node ast.Node, maxSize int) ast.Node {
+	bindings, dynamic := bindingCounts(node)
+	if dynamic || quotes(node) {
+		return node
+	}
+
+	globals := make(map[string]bool)
+	for _, name := range evaluator.Globals() {
+		if bindings[name] == 0 {
+			globals[name] = true
+		}
+	}
+
+	inliner := &inliner{globals: globals, functions: make(map[string]*ast.LetStatement), maxSize: maxSize}
+	ast.Inspect(node, func(node ast.Node) bool {
+		if let, ok := node.(*ast.LetStatement); ok && bindings[let.Name.Value] == 1 && let.Token.Type == token.LET {
+			if _, ok := let.Value.(*ast.FunctionLiteral); ok {
+				inliner.functions[let.Name.Value] = let
+			}
+		}
+		return true
+	})
+
+	return ast.Modify(node, inliner.inline)
+}
+
+type inliner struct {
+	globals   map[string]bool              // Builtins no binding shadows
+	functions map[string]*ast.LetStatement // Functions bound once, by name
+	maxSize   int
+}
+
+func (inliner *inliner) inline(node ast.Node) ast.Node {
+	call, ok := node.(*ast.CallExpression)
+	if !ok {
+		return node
+	}
+
+	var function *ast.FunctionLiteral
+	switch callee := call.Function.(type) {
+	case *ast.FunctionLiteral:
+		function = callee
+	case *ast.Identifier:
+		let, ok := inliner.functions[callee.Value]
+		if !ok || !before(let.Token.Line, let.Token.Column, callee.Token.Line, callee.Token.Column) {
+			return node
+		}
+		function = let.Value.(*ast.FunctionLiteral)
+	default:
+		return node
+	}
+
+	if len(call.Arguments) != len(function.Parameters) {
+		return node
+	}
+	arguments := make(map[string]ast.Expression, len(function.Parameters))
+	for i, parameter := range function.Parameters {
+		if _, ok := call.Arguments[i].(*ast.Identifier); !ok && !isLiteral(call.Arguments[i]) {
+			return node
+		}
+		arguments[parameter.Value] = call.Arguments[i]
+	}
+
+	body, ok := singleExpression(function.Body)
+	if !ok || size(body) > inliner.maxSize {
+		return node
+	}
+
+	if inlined, ok := inliner.substitute(body, arguments); ok {
+		return inlined
+	}
+	return node
+}
+
+// singleExpression returns the expression body consists of, either on its
+// own or returned.
+func singleExpression(body *ast.BlockStatement) (ast.Expression, bool) {
+	if len(body.Statements) != 1 {
+		return nil, false
+	}
+
+	switch statement := body.Statements[0].(type) {
+	case *ast.ExpressionStatement:
+		return statement.Expression, true
+	case *ast.ReturnStatement:
+		return statement.ReturnValue, statement.ReturnValue != nil
+	default:
+		return nil, false
+	}
+}
+
+// substitute returns a copy of expression with the parameters replaced by
+// arguments, or false if expression contains something that can't be
+// inlined.
+func (inliner *inliner) substitute(expression ast.Expression, arguments map[string]ast.Expression) (ast.Expression, bool) {
+	switch expression := expression.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.Boolean, *ast.StringLiteral:
+		return expression, true
+
+	case *ast.Identifier:
+		if argument, ok := arguments[expression.Value]; ok {
+			return argument, true
+		}
+		return expression, inliner.globals[expression.Value]
+
+	case *ast.PrefixExpression:
+		right, ok := inliner.substitute(expression.Right, arguments)
+		return &ast.PrefixExpression{Token: expression.Token, Operator: expression.Operator, Right: right}, ok
+
+	case *ast.InfixExpression:
+		left, leftOk := inliner.substitute(expression.Left, arguments)
+		right, rightOk := inliner.substitute(expression.Right, arguments)
+		return &ast.InfixExpression{Token: expression.Token, Left: left, Operator: expression.Operator, Right: right},
+			leftOk && rightOk
+
+	case *ast.IndexExpression:
+		left, leftOk := inliner.substitute(expression.Left, arguments)
+		index, indexOk := inliner.substitute(expression.Index, arguments)
+		return &ast.IndexExpression{Token: expression.Token, Left: left, Index: index}, leftOk && indexOk
+
+	case *ast.ArrayLiteral:
+		elements, ok := inliner.substituteAll(expression.Elements, arguments)
+		return &ast.ArrayLiteral{Token: expression.Token, Elements: elements}, ok
+
+	case *ast.CallExpression:
+		function, ok := expression.Function.(*ast.Identifier)
+		if !ok || !inliner.globals[function.Value] {
+			return nil, false
+		}
+		args, ok := inliner.substituteAll(expression.Arguments, arguments)
+		return &ast.CallExpression{Token: expression.Token, Function: function, Arguments: args}, ok
+
+	default:
+		return nil, false
+	}
+}
+
+func (inliner *inliner) substituteAll(expressions []ast.Expression, arguments map[string]ast.Expression) ([]ast.Expression, bool) {
+	substituted := make([]ast.Expression, len(expressions))
+	for i, expression := range expressions {
+		var ok bool
+		if substituted[i], ok = inliner.substitute(expression, arguments); !ok {
+			return nil, false
+		}
+	}
+	return substituted, true
+}
+
+// bindingCounts returns how many times each name is bound in the program
+// rooted at node, and whether it uses eval, which could bind any name. The
+// parameter of a catch clause is bound in a scope of its own, so it counts
+// like the parameter of a function.
+func bindingCounts(node ast.Node) (map[string]int, bool) {
+	counts := make(map[string]int)
+	dynamic := false
+
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			counts[node.Name.Value]++
+		case *ast.HashLetStatement:
+			for _, name := range node.Names {
+				counts[name.Value]++
+			}
+		case *ast.MultipleLetStatement:
+			for _, name := range node.Names {
+				counts[name.Value]++
+			}
+		case *ast.FunctionLiteral:
+			for _, parameter := range node.Parameters {
+				counts[parameter.Value]++
+			}
+		case *ast.TryExpression:
+			if node.Parameter != nil {
+				counts[node.Parameter.Value]++
+			}
+		case *ast.Identifier:
+			dynamic = dynamic || node.Value == "eval"
+		}
+		return true
+	})
+
+	return counts, dynamic
+}
+
+// size returns the number of nodes in the tree rooted at node.
+func size(node ast.Node) int {
+	count := 0
+	ast.Inspect(node, func(ast.Node) bool {
+		count++
+		return true
+	})
+	return count
+}
+
+func before(line, column, otherLine, otherColumn int) bool {
+	return line < otherLine || line == otherLine && column < otherColumn
+}
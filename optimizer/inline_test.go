@@ -0,0 +1,58 @@
+package optimizer
+
+import (
+	"monkey/evaluator"
+	"monkey/object"
+	"testing"
+)
+
+func TestInline(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(x) { x * 2 }(3)", "(3 * 2)"},
+		{"let double = fn(x) { x * 2 }; double(a)", "let double = fn(x)(x * 2);(a * 2)"},
+		{"let sq = fn(x) { return x * x; }; sq(4)", "let sq = fn(x)return (x * x);;(4 * 4)"},
+		{`let f = fn(s) { len(s) + 1 }; f("abc")`, "let f = fn(s)(len(s) + 1);(len(abc) + 1)"},
+		{"let f = fn(a, b) { [b, a][0] }; f(1, 2)", "let f = fn(a, b)([b, a][0]);([2, 1][0])"},
+		{"let f = fn(x) { x }; f(g(1))", "let f = fn(x)x;f(g(1))"},
+		{"let f = fn(x) { x + y }; f(1)", "let f = fn(x)(x + y);f(1)"},
+		{"let f = fn(x) { let y = x; y }; f(1)", "let f = fn(x)let y = x;y;f(1)"},
+		{"let f = fn(x) { f(x) }; f(1)", "let f = fn(x)f(x);f(1)"},
+		{"let f = fn(x) { x }; f(1, 2)", "let f = fn(x)x;f(1, 2)"},
+		{"var f = fn(x) { x }; f(1)", "var f = fn(x)x;f(1)"},
+		{"let f = fn(x) { x }; let g = fn(f) { f(1) }; f(1)", "let f = fn(x)x;let g = fn(f)f(1);f(1)"},
+		{"let len = fn(x) { 0 }; let f = fn(s) { len(s) }; f(1)", "let len = fn(x)0;let f = fn(s)0;0"},
+		{"let f = fn(x) { if (x) { 1 } }; f(true)", "let f = fn(x)ifx 1;f(true)"},
+		{`let f = fn(x) { x }; eval("1"); f(1)`, "let f = fn(x)x;eval(1)f(1)"},
+		{"let f = fn(x) { x + x + x + x + x + x + x + x + x }; f(1)", "let f = fn(x)((((((((x + x) + x) + x) + x) + x) + x) + x) + x);f(1)"},
+	}
+
+	for _, test := range tests {
+		inlined := Inline(parse(t, test.input), DefaultInlineSize)
+
+		if inlined.String() != test.expected {
+			t.Errorf("wrong inlining of %q. expected=%q, got=%q",
+				test.input, test.expected, inlined.String())
+		}
+	}
+}
+
+func TestInlineKeepsResults(t *testing.T) {
+	inputs := []string{
+		"let double = fn(x) { x * 2 }; double(21)",
+		"let pick = fn(a, b) { [a, b][1] }; let v = 7; pick(v, v + 1)",
+		`let shout = fn(s) { upper(s) + "!" }; shout("hi")`,
+	}
+
+	for _, input := range inputs {
+		expected := evaluator.Eval(parse(t, input), object.NewEnvironment())
+		actual := evaluator.Eval(Inline(parse(t, input), DefaultInlineSize), object.NewEnvironment())
+
+		if expected.Inspect() != actual.Inspect() {
+			t.Errorf("inlining changed the result of %q. expected=%s, got=%s",
+				input, expected.Inspect(), actual.Inspect())
+		}
+	}
+}
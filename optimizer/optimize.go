@@ -0,0 +1,46 @@
+package optimizer
+
+import (
+	"fmt"
+	"monkey/ast"
+)
+
+// A Level is how much Optimize does to a program, trading the time spent
+// optimizing for the time saved evaluating.
+type Level int
+
+const (
+	O0 Level = iota // Nothing, for code that's run once, like REPL lines
+	O1              // The safe rewrites: dead code, constants, folding and peephole rules
+	O2              // Also inlining small functions into their callers
+)
+
+// ParseLevel returns the level named by a -O0, -O1 or -O2 flag.
+func ParseLevel(flag string) (Level, error) {
+	switch flag {
+	case "-O0":
+		return O0, nil
+	case "-O1":
+		return O1, nil
+	case "-O2":
+		return O2, nil
+	default:
+		return O0, fmt.Errorf("unknown optimization level: %s", flag)
+	}
+}
+
+// Optimize runs the passes level calls for on node, in the order that lets
+// each pass work on what the previous ones left.
+func Optimize(node ast.Node, level Level) ast.Node {
+	if level == O0 {
+		return node
+	}
+
+	node = EliminateDeadCode(node)
+	node = PropagateConstants(node)
+	if level >= O2 {
+		node = Inline(node, DefaultInlineSize)
+	}
+	node = Fold(node)
+	return Peephole(node, Rules)
+}
@@ -0,0 +1,77 @@
+package optimizer
+
+import (
+	"monkey/evaluator"
+	"monkey/object"
+	"testing"
+)
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		input    string
+		level    Level
+		expected string
+	}{
+		{"let f = fn(x) { x * 2 }; f(1 + 2)", O0, "let f = fn(x)(x * 2);f((1 + 2))"},
+		{"let f = fn(x) { x * 2 }; f(1 + 2)", O1, "let f = fn(x)(x * 2);f(3)"},
+		{"let f = fn(x) { x * 2 }; f(3)", O2, "let f = fn(x)(x * 2);6"},
+		{"let n = 2; puts(n * 3)", O1, "puts(6)"},
+		{"fn() { return 1; 2 }", O1, "fn()return 1;"},
+		{"fn() { return 1; 2 }", O0, "fn()return 1;2"},
+	}
+
+	for _, test := range tests {
+		optimized := Optimize(parse(t, test.input), test.level)
+
+		if optimized.String() != test.expected {
+			t.Errorf("wrong optimization of %q at O%d. expected=%q, got=%q",
+				test.input, test.level, test.expected, optimized.String())
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for flag, expected := range map[string]Level{"-O0": O0, "-O1": O1, "-O2": O2} {
+		level, err := ParseLevel(flag)
+		if err != nil || level != expected {
+			t.Errorf("wrong level for %q. expected=%d, got=%d (%v)", flag, expected, level, err)
+		}
+	}
+
+	if _, err := ParseLevel("-O3"); err == nil || err.Error() != "unknown optimization level: -O3" {
+		t.Errorf("wrong error for -O3. got=%v", err)
+	}
+}
+
+func TestOptimizeKeepsAssertSource(t *testing.T) {
+	tests := []struct {
+		input          string
+		expected       string
+		expectedLine   int
+		expectedColumn int
+	}{
+		{"let n = 3;\nassert(n > 5, \"big\")", "assertion failed: (n > 5): big", 2, 1},
+		{"assert(1 + 1 == 3)", "assertion failed: ((1 + 1) == 3)", 1, 1},
+		{"let f = fn(x) { x * 2 }; assert(f(2) < 0)", "assertion failed: (f(2) < 0)", 1, 26},
+	}
+
+	for _, level := range []Level{O1, O2} {
+		for _, test := range tests {
+			program := Optimize(parse(t, test.input), level)
+			evaluated := evaluator.Eval(program, object.NewEnvironment())
+
+			err, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error for %q at O%d. got=%T (%+v)", test.input, level, evaluated, evaluated)
+				continue
+			}
+			if err.Message != test.expected {
+				t.Errorf("wrong message for %q at O%d. expected=%q, got=%q", test.input, level, test.expected, err.Message)
+			}
+			if err.Line != test.expectedLine || err.Column != test.expectedColumn {
+				t.Errorf("wrong position for %q at O%d. expected=%d:%d, got=%d:%d", test.input, level,
+					test.expectedLine, test.expectedColumn, err.Line, err.Column)
+			}
+		}
+	}
+}
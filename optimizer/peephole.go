@@ -0,0 +1,114 @@
+package optimizer
+
+import (
+	"monkey/ast"
+	"monkey/token"
+)
+
+// A Rule is a local rewrite of a single node. Rewrite reports whether the
+// rule matched; it must only match nodes it actually changes, as rules are
+// reapplied to a node until none of them match.
+type Rule struct {
+	Name    string
+	Rewrite func(ast.Node) (ast.Node, bool)
+}
+
+// Rules are the rewrites applied by Peephole by default.
+var Rules = []Rule{
+	{Name: "constant-condition", Rewrite: constantCondition},
+	{Name: "negated-condition", Rewrite: negatedCondition},
+	{Name: "unused-literal", Rewrite: unusedLiteral},
+}
+
+// Peephole applies rules to every node of the tree rooted at node, children
+// first.
+func Peephole(node ast.Node, rules []Rule) ast.Node {
+	if quotes(node) {
+		return node
+	}
+	return ast.Modify(node, func(node ast.Node) ast.Node {
+		for matched := true; matched; {
+			matched = false
+			for _, rule := range rules {
+				if rewritten, ok := rule.Rewrite(node); ok {
+					node, matched = rewritten, true
+				}
+			}
+		}
+		return node
+	})
+}
+
+// constantCondition drops the branch of an if expression that a literal
+// condition never takes: `if (true) { a } else { b }` becomes
+// `if (true) { a }` and `if (false) { a } else { b }` becomes
+// `if (true) { b }`.
+func constantCondition(node ast.Node) (ast.Node, bool) {
+	ifExpression, ok := node.(*ast.IfExpression)
+	if !ok || !isLiteral(ifExpression.Condition) || ifExpression.Alternative == nil {
+		return node, false
+	}
+
+	branch := ifExpression.Consequence
+	if condition, ok := ifExpression.Condition.(*ast.Boolean); ok && !condition.Value {
+		branch = ifExpression.Alternative
+	}
+
+	tok := literalToken(ifExpression.Condition)
+	tok.Type, tok.Literal = token.TRUE, "true"
+	return &ast.IfExpression{
+		Token:       ifExpression.Token,
+		Condition:   &ast.Boolean{Token: tok, Value: true},
+		Consequence: branch,
+	}, true
+}
+
+// negatedCondition swaps the branches of `if (!c) { a } else { b }`, so
+// the negation isn't evaluated.
+func negatedCondition(node ast.Node) (ast.Node, bool) {
+	ifExpression, ok := node.(*ast.IfExpression)
+	if !ok || ifExpression.Alternative == nil {
+		return node, false
+	}
+
+	negation, ok := ifExpression.Condition.(*ast.PrefixExpression)
+	if !ok || negation.Operator != "!" {
+		return node, false
+	}
+
+	return &ast.IfExpression{
+		Token:       ifExpression.Token,
+		Condition:   negation.Right,
+		Consequence: ifExpression.Alternative,
+		Alternative: ifExpression.Consequence,
+	}, true
+}
+
+// unusedLiteral removes literal expression statements whose value is
+// discarded, i.e. all but the last statement of a block or program.
+func unusedLiteral(node ast.Node) (ast.Node, bool) {
+	var statements *[]ast.Statement
+	switch node := node.(type) {
+	case *ast.Program:
+		statements = &node.Statements
+	case *ast.BlockStatement:
+		statements = &node.Statements
+	default:
+		return node, false
+	}
+
+	kept := make([]ast.Statement, 0, len(*statements))
+	for i, statement := range *statements {
+		expressionStatement, ok := statement.(*ast.ExpressionStatement)
+		if ok && i < len(*statements)-1 && isLiteral(expressionStatement.Expression) {
+			continue
+		}
+		kept = append(kept, statement)
+	}
+
+	if len(kept) == len(*statements) {
+		return node, false
+	}
+	*statements = kept
+	return node, true
+}
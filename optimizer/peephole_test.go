@@ -0,0 +1,49 @@
+package optimizer
+
+import "testing"
+
+func TestPeepholeRules(t *testing.T) {
+	tests := []struct {
+		rule     string
+		input    string
+		expected string
+	}{
+		{"constant-condition", "if (true) { 1 } else { 2 }", "iftrue 1"},
+		{"constant-condition", "if (false) { 1 } else { 2 }", "iftrue 2"},
+		{"constant-condition", `if ("") { 1 } else { 2 }`, "iftrue 1"},
+		{"constant-condition", "if (false) { 1 }", "iffalse 1"},
+		{"constant-condition", "if (x) { 1 } else { 2 }", "ifx 1else 2"},
+		{"negated-condition", "if (!x) { 1 } else { 2 }", "ifx 2else 1"},
+		{"negated-condition", "if (!x) { 1 }", "if(!x) 1"},
+		{"unused-literal", "1; 2; x; 3", "x3"},
+		{"unused-literal", `fn() { "doc"; x }`, "fn()x"},
+		{"unused-literal", "fn() { 1 }", "fn()1"},
+	}
+
+	for _, test := range tests {
+		var rules []Rule
+		for _, rule := range Rules {
+			if rule.Name == test.rule {
+				rules = append(rules, rule)
+			}
+		}
+		if len(rules) == 0 {
+			t.Fatalf("no rule named %q", test.rule)
+		}
+
+		rewritten := Peephole(parse(t, test.input), rules)
+
+		if rewritten.String() != test.expected {
+			t.Errorf("wrong rewrite of %q by %s. expected=%q, got=%q",
+				test.input, test.rule, test.expected, rewritten.String())
+		}
+	}
+}
+
+func TestPeepholeReappliesRules(t *testing.T) {
+	rewritten := Peephole(parse(t, "if (!false) { 1 } else { 2 }"), Rules)
+
+	if rewritten.String() != "iftrue 1" {
+		t.Errorf("wrong rewrite. got=%q", rewritten.String())
+	}
+}
@@ -0,0 +1,131 @@
+package optimizer
+
+import (
+	"monkey/ast"
+	"monkey/token"
+)
+
+// PropagateConstants replaces the uses of names bound once with let to a
+// literal by the literal, so `let n = 2; n * 3` becomes `let n = 2; 2 * 3`
+// for Fold to finish. Only uses after the binding are replaced, leaving the
+// error of a use before it in place. Bindings that are no longer used are
+// removed, unless they end a block and so give its value.
+func PropagateConstants(node ast.Node) ast.Node {
+	bindings, dynamic := bindingCounts(node)
+	if dynamic || quotes(node) {
+		return node
+	}
+
+	assigned := make(map[string]bool)
+	ast.Inspect(node, func(node ast.Node) bool {
+		if assignment, ok := node.(*ast.AssignExpression); ok {
+			assigned[assignment.Name.Value] = true
+		}
+		return true
+	})
+
+	// The constants are grouped by the function body or program they're
+	// bound in, since only uses there and in nested functions refer to them.
+	// Only the lets that are statements of the body itself are constants: one
+	// in a nested block is bound only if the block runs.
+	constants := make(map[string]*ast.LetStatement)
+	scopes := make(map[ast.Node]map[string]*ast.LetStatement)
+	var collect func(scope ast.Node, statements []ast.Statement)
+	collect = func(scope ast.Node, statements []ast.Statement) {
+		direct := make(map[ast.Statement]bool, len(statements))
+		for _, statement := range statements {
+			direct[statement] = true
+		}
+
+		ast.Inspect(scope, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.FunctionLiteral:
+				collect(node.Body, node.Body.Statements)
+				return false
+			case *ast.LetStatement:
+				if direct[node] && node.Token.Type == token.LET && bindings[node.Name.Value] == 1 &&
+					!assigned[node.Name.Value] && isLiteral(node.Value) {
+					if scopes[scope] == nil {
+						scopes[scope] = make(map[string]*ast.LetStatement)
+					}
+					scopes[scope][node.Name.Value] = node
+					constants[node.Name.Value] = node
+				}
+			}
+			return true
+		})
+	}
+	switch node := node.(type) {
+	case *ast.Program:
+		collect(node, node.Statements)
+	case *ast.BlockStatement:
+		collect(node, node.Statements)
+	default:
+		collect(node, nil)
+	}
+	if len(constants) == 0 {
+		return node
+	}
+
+	remaining := make(map[string]int)
+	for scope, bound := range scopes {
+		ast.Modify(scope, func(node ast.Node) ast.Node {
+			identifier, ok := node.(*ast.Identifier)
+			if !ok {
+				return node
+			}
+			let, ok := bound[identifier.Value]
+			if !ok {
+				return node
+			}
+			if !before(let.Token.Line, let.Token.Column, identifier.Token.Line, identifier.Token.Column) {
+				remaining[identifier.Value]++
+				return node
+			}
+			return copyLiteral(let.Value, identifier.Token)
+		})
+	}
+
+	return ast.Modify(node, func(node ast.Node) ast.Node {
+		switch node := node.(type) {
+		case *ast.Program:
+			node.Statements = withoutUnused(node.Statements, constants, remaining)
+		case *ast.BlockStatement:
+			node.Statements = withoutUnused(node.Statements, constants, remaining)
+		}
+		return node
+	})
+}
+
+// copyLiteral returns a copy of literal positioned at tok.
+func copyLiteral(literal ast.Expression, tok token.Token) ast.Expression {
+	position := func(literalToken token.Token) token.Token {
+		literalToken.Line, literalToken.Column = tok.Line, tok.Column
+		return literalToken
+	}
+
+	switch literal := literal.(type) {
+	case *ast.IntegerLiteral:
+		return &ast.IntegerLiteral{Token: position(literal.Token), Value: literal.Value}
+	case *ast.FloatLiteral:
+		return &ast.FloatLiteral{Token: position(literal.Token), Value: literal.Value}
+	case *ast.Boolean:
+		return &ast.Boolean{Token: position(literal.Token), Value: literal.Value}
+	case *ast.StringLiteral:
+		return &ast.StringLiteral{Token: position(literal.Token), Value: literal.Value}
+	default:
+		return literal
+	}
+}
+
+func withoutUnused(statements []ast.Statement, constants map[string]*ast.LetStatement, remaining map[string]int) []ast.Statement {
+	kept := make([]ast.Statement, 0, len(statements))
+	for i, statement := range statements {
+		let, ok := statement.(*ast.LetStatement)
+		if ok && constants[let.Name.Value] == let && remaining[let.Name.Value] == 0 && i < len(statements)-1 {
+			continue
+		}
+		kept = append(kept, statement)
+	}
+	return kept
+}
@@ -0,0 +1,58 @@
+package optimizer
+
+import (
+	"monkey/evaluator"
+	"monkey/object"
+	"testing"
+)
+
+func TestPropagateConstants(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let n = 2; n * 3", "(2 * 3)"},
+		{`let s = "hi"; let t = true; len(s); !t`, "len(hi)(!true)"},
+		{"let n = 2; let f = fn(x) { x + n }; f", "let f = fn(x)(x + 2);f"},
+		{"n; let n = 2; n", "nlet n = 2;2"},
+		{"let n = 2", "let n = 2;"},
+		{"let n = 2; n = 3; n", "let n = 2;(n = 3)n"},
+		{"var n = 2; n", "var n = 2;n"},
+		{"let n = 2; let n = 3; n", "let n = 2;let n = 3;n"},
+		{"let n = [1]; n", "let n = [1];n"},
+		{"let f = fn(n) { n }; let n = 2; f(n)", "let f = fn(n)n;let n = 2;f(n)"},
+		{"let f = fn() { let len = 3; len }; len([1])", "let f = fn()3;len([1])"},
+		{`let n = 2; eval("n")`, "let n = 2;eval(n)"},
+		{"if (true) { let n = 2; n }; n", "iftrue let n = 2;nn"},
+		{"let f = fn() { if (true) { let n = 2; } n };", "let f = fn()iftrue let n = 2;n;"},
+		{`let e = 5; try { throw "x" } catch (e) { e }; e`, "let e = 5;try throw x; catch(e) ee"},
+	}
+
+	for _, test := range tests {
+		propagated := PropagateConstants(parse(t, test.input))
+
+		if propagated.String() != test.expected {
+			t.Errorf("wrong propagation in %q. expected=%q, got=%q",
+				test.input, test.expected, propagated.String())
+		}
+	}
+}
+
+func TestPropagateConstantsKeepsResults(t *testing.T) {
+	inputs := []string{
+		"let a = 4; let b = 5; a * b + a",
+		`let greeting = "hello"; let f = fn(name) { greeting + " " + name }; f("you")`,
+		"let limit = 3; var i = 0; do { i = i + 1 } while (i < limit); i",
+		"if (false) { let x = 1; 0 }; x",
+	}
+
+	for _, input := range inputs {
+		expected := evaluator.Eval(parse(t, input), object.NewEnvironment())
+		actual := evaluator.Eval(PropagateConstants(parse(t, input)), object.NewEnvironment())
+
+		if expected.Inspect() != actual.Inspect() {
+			t.Errorf("propagation changed the result of %q. expected=%s, got=%s",
+				input, expected.Inspect(), actual.Inspect())
+		}
+	}
+}
@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"monkey/ast"
+	"monkey/diagnostic"
 	"monkey/lexer"
 	"monkey/token"
 	"strconv"
@@ -11,35 +12,53 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN
+	PIPE
+	COALESCE
+	COMPOSE
 	EQUALS
 	LESSGREATER
+	RANGE
 	SUM
 	PRODUCT
 	PREFIX
+	POWER // Above PREFIX, so that -2 ** 2 == -(2 ** 2)
 	CALL
 	INDEX
 )
 
 var precedences = map[token.Type]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-	token.LBRACKET: INDEX,
+	token.ASSIGN:          ASSIGN,
+	token.PIPE:            PIPE,
+	token.NULL_COALESCE:   COALESCE,
+	token.COMPOSE:         COMPOSE,
+	token.EQ:              EQUALS,
+	token.NOT_EQ:          EQUALS,
+	token.LT:              LESSGREATER,
+	token.GT:              LESSGREATER,
+	token.IN:              LESSGREATER,
+	token.RANGE:           RANGE,
+	token.RANGE_INCLUSIVE: RANGE,
+	token.PLUS:            SUM,
+	token.MINUS:           SUM,
+	token.SLASH:           PRODUCT,
+	token.ASTERISK:        PRODUCT,
+	token.POWER:           POWER,
+	token.LPAREN:          CALL,
+	token.LBRACKET:        INDEX,
+	token.OPTIONAL_CHAIN:  INDEX,
 }
 
 type Parser struct {
-	l      *lexer.Lexer
-	errors []string
+	l           *lexer.Lexer
+	diagnostics []diagnostic.Diagnostic
 
 	currToken token.Token
 	peekToken token.Token
 
+	loopLabels []string // Labels of the enclosing loops, "" for unlabeled ones
+	inHashKey  bool     // Whether a hash literal's key is being parsed, where a colon ends it
+
 	prefixParseFns map[token.Type]prefixParseFn
 	infixParseFns  map[token.Type]infixParseFn
 }
@@ -50,11 +69,12 @@ type (
 )
 
 func New(l *lexer.Lexer) *Parser {
-	parser := &Parser{l: l, errors: []string{}}
+	parser := &Parser{l: l, diagnostics: []diagnostic.Diagnostic{}}
 
 	parser.prefixParseFns = make(map[token.Type]prefixParseFn)
 	parser.registerPrefix(token.IDENT, parser.parseIdentifier)
 	parser.registerPrefix(token.INT, parser.parseIntegerLiteral)
+	parser.registerPrefix(token.FLOAT, parser.parseFloatLiteral)
 	parser.registerPrefix(token.BANG, parser.parsePrefixExpression)
 	parser.registerPrefix(token.MINUS, parser.parsePrefixExpression)
 	parser.registerPrefix(token.TRUE, parser.parseBoolean)
@@ -62,21 +82,35 @@ func New(l *lexer.Lexer) *Parser {
 	parser.registerPrefix(token.LPAREN, parser.parseGroupedExpression)
 	parser.registerPrefix(token.IF, parser.parseIfExpression)
 	parser.registerPrefix(token.FUNCTION, parser.parseFunctionLiteral)
+	parser.registerPrefix(token.MACRO, parser.parseMacroLiteral)
+	parser.registerPrefix(token.BAR, parser.parseLambdaLiteral)
 	parser.registerPrefix(token.STRING, parser.parseStringLiteral)
 	parser.registerPrefix(token.LBRACKET, parser.parseArrayLiteral)
 	parser.registerPrefix(token.LBRACE, parser.parseHashLiteral)
+	parser.registerPrefix(token.TRY, parser.parseTryExpression)
+	parser.registerPrefix(token.DO, parser.parseDoWhileExpression)
+	parser.registerPrefix(token.WHILE, parser.parseWhileExpression)
 
 	parser.infixParseFns = make(map[token.Type]infixParseFn)
+	parser.registerInfix(token.ASSIGN, parser.parseAssignExpression)
 	parser.registerInfix(token.PLUS, parser.parseInfixExpression)
 	parser.registerInfix(token.MINUS, parser.parseInfixExpression)
 	parser.registerInfix(token.SLASH, parser.parseInfixExpression)
 	parser.registerInfix(token.ASTERISK, parser.parseInfixExpression)
+	parser.registerInfix(token.POWER, parser.parseInfixExpression)
 	parser.registerInfix(token.EQ, parser.parseInfixExpression)
 	parser.registerInfix(token.NOT_EQ, parser.parseInfixExpression)
 	parser.registerInfix(token.LT, parser.parseInfixExpression)
 	parser.registerInfix(token.GT, parser.parseInfixExpression)
+	parser.registerInfix(token.IN, parser.parseInfixExpression)
+	parser.registerInfix(token.RANGE, parser.parseInfixExpression)
+	parser.registerInfix(token.RANGE_INCLUSIVE, parser.parseInfixExpression)
+	parser.registerInfix(token.NULL_COALESCE, parser.parseInfixExpression)
+	parser.registerInfix(token.COMPOSE, parser.parseInfixExpression)
+	parser.registerInfix(token.PIPE, parser.parsePipeExpression)
 	parser.registerInfix(token.LPAREN, parser.parseCallExpression)
 	parser.registerInfix(token.LBRACKET, parser.parseIndexExpression)
+	parser.registerInfix(token.OPTIONAL_CHAIN, parser.parseOptionalChainExpression)
 
 	parser.nextToken()
 	parser.nextToken()
@@ -92,18 +126,36 @@ func (parser *Parser) registerInfix(tokenType token.Type, fn infixParseFn) {
 	parser.infixParseFns[tokenType] = fn
 }
 
-func (parser *Parser) Errors() []string {
-	return parser.errors
+// Diagnostics returns the errors found while parsing as diagnostics with the
+// code "syntax", each spanning the token it was found at.
+func (parser *Parser) Diagnostics() []diagnostic.Diagnostic {
+	return parser.diagnostics
+}
+
+func (parser *Parser) addError(tok token.Token, message string) {
+	width := len(tok.Literal)
+	if tok.Type == token.STRING {
+		width += 2 // The literal leaves out the quotes
+	}
+	parser.diagnostics = append(parser.diagnostics, diagnostic.Diagnostic{
+		Code:     "syntax",
+		Severity: diagnostic.Error,
+		Message:  message,
+		Span: diagnostic.Span{
+			Start: diagnostic.Position{Line: tok.Line, Column: tok.Column},
+			End:   diagnostic.Position{Line: tok.Line, Column: tok.Column + width},
+		},
+	})
 }
 
 func (parser *Parser) peekError(tokenType token.Type) {
 	message := fmt.Sprintf("expected next token to be %s, got %s instead", tokenType, parser.peekToken.Type)
-	parser.errors = append(parser.errors, message)
+	parser.addError(parser.peekToken, message)
 }
 
 func (parser *Parser) noPrefixParseFnError(tokenType token.Type) {
 	message := fmt.Sprintf("no prefix parse function for %s found", tokenType)
-	parser.errors = append(parser.errors, message)
+	parser.addError(parser.currToken, message)
 }
 
 func (parser *Parser) currPrecedence() int {
@@ -143,6 +195,29 @@ func (parser *Parser) peekTokenIs(tokenType token.Type) bool {
 	return parser.peekToken.Type == tokenType
 }
 
+// peekStartsStatement reports whether a newline before the peek token ends
+// the current statement, which makes semicolons at the end of a line
+// optional. That's only the case if the peek token could start a new
+// expression: a line starting with ( [ or - begins a new statement, while a
+// line starting with an infix-only operator such as |> continues the last.
+func (parser *Parser) peekStartsStatement() bool {
+	if parser.peekToken.Line <= parser.currToken.Line {
+		return false
+	}
+
+	_, ok := parser.prefixParseFns[parser.peekToken.Type]
+	return ok
+}
+
+// peekEndsStatement reports whether the statement ends after the current
+// token, either explicitly or at the end of the line or block.
+func (parser *Parser) peekEndsStatement() bool {
+	return parser.peekTokenIs(token.SEMICOLON) ||
+		parser.peekTokenIs(token.RBRACE) ||
+		parser.peekTokenIs(token.EOF) ||
+		parser.peekToken.Line > parser.currToken.Line
+}
+
 func (parser *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
@@ -177,16 +252,33 @@ func (parser *Parser) parseBlockStatement() *ast.BlockStatement {
 
 func (parser *Parser) parseStatement() ast.Statement {
 	switch parser.currToken.Type {
-	case token.LET:
+	case token.SEMICOLON:
+		// Empty statement, e.g. a stray `;` following a block or a newline.
+		return nil
+	case token.LET, token.VAR:
 		return parser.parseLetStatement()
 	case token.RETURN:
 		return parser.parseReturnStatement()
+	case token.THROW:
+		return parser.parseThrowStatement()
+	case token.ASSERT:
+		return parser.parseAssertStatement()
+	case token.DEFER:
+		return parser.parseDeferStatement()
+	case token.BREAK:
+		return parser.parseBreakStatement()
+	case token.CONTINUE:
+		return parser.parseContinueStatement()
 	default:
 		return parser.parseExpressionStatement()
 	}
 }
 
-func (parser *Parser) parseLetStatement() *ast.LetStatement {
+func (parser *Parser) parseLetStatement() ast.Statement {
+	if parser.peekTokenIs(token.LBRACE) {
+		return parser.parseHashLetStatement()
+	}
+
 	statement := &ast.LetStatement{Token: parser.currToken}
 
 	if !parser.expectPeek(token.IDENT) {
@@ -195,6 +287,63 @@ func (parser *Parser) parseLetStatement() *ast.LetStatement {
 
 	statement.Name = &ast.Identifier{Token: parser.currToken, Value: parser.currToken.Literal}
 
+	if parser.peekTokenIs(token.COMMA) {
+		return parser.parseMultipleLetStatement(statement)
+	}
+
+	if !parser.expectPeek(token.ASSIGN) {
+		return nil
+	}
+
+	parser.nextToken()
+
+	statement.Value = parser.parseExpression(LOWEST)
+
+	for parser.peekTokenIs(token.SEMICOLON) {
+		parser.nextToken()
+	}
+
+	return statement
+}
+
+func (parser *Parser) parseHashLetStatement() ast.Statement {
+	statement := &ast.HashLetStatement{Token: parser.currToken}
+
+	parser.nextToken()
+
+	statement.Names = parser.parseIdentifierList(token.RBRACE)
+	if statement.Names == nil {
+		return nil
+	}
+
+	if !parser.expectPeek(token.ASSIGN) {
+		return nil
+	}
+
+	parser.nextToken()
+
+	statement.Value = parser.parseExpression(LOWEST)
+
+	for parser.peekTokenIs(token.SEMICOLON) {
+		parser.nextToken()
+	}
+
+	return statement
+}
+
+func (parser *Parser) parseMultipleLetStatement(first *ast.LetStatement) ast.Statement {
+	statement := &ast.MultipleLetStatement{Token: first.Token}
+	statement.Names = []*ast.Identifier{first.Name}
+
+	for parser.peekTokenIs(token.COMMA) {
+		parser.nextToken()
+		if !parser.expectPeek(token.IDENT) {
+			return nil
+		}
+		name := &ast.Identifier{Token: parser.currToken, Value: parser.currToken.Literal}
+		statement.Names = append(statement.Names, name)
+	}
+
 	if !parser.expectPeek(token.ASSIGN) {
 		return nil
 	}
@@ -213,10 +362,276 @@ func (parser *Parser) parseLetStatement() *ast.LetStatement {
 func (parser *Parser) parseReturnStatement() *ast.ReturnStatement {
 	statement := &ast.ReturnStatement{Token: parser.currToken}
 
+	if parser.peekEndsStatement() {
+		if parser.peekTokenIs(token.SEMICOLON) {
+			parser.nextToken()
+		}
+		return statement
+	}
+
 	parser.nextToken()
 
 	statement.ReturnValue = parser.parseExpression(LOWEST)
 
+	// `return a, b;` returns both values packed into an array, ready to be
+	// unpacked again by a multiple let statement.
+	if parser.peekTokenIs(token.COMMA) {
+		tuple := &ast.ArrayLiteral{Token: statement.Token}
+		tuple.Elements = []ast.Expression{statement.ReturnValue}
+
+		for parser.peekTokenIs(token.COMMA) {
+			parser.nextToken()
+			parser.nextToken()
+			tuple.Elements = append(tuple.Elements, parser.parseExpression(LOWEST))
+		}
+
+		statement.ReturnValue = tuple
+	}
+
+	for parser.peekTokenIs(token.SEMICOLON) {
+		parser.nextToken()
+	}
+
+	return statement
+}
+
+func (parser *Parser) parseThrowStatement() *ast.ThrowStatement {
+	statement := &ast.ThrowStatement{Token: parser.currToken}
+
+	parser.nextToken()
+
+	statement.Value = parser.parseExpression(LOWEST)
+
+	for parser.peekTokenIs(token.SEMICOLON) {
+		parser.nextToken()
+	}
+
+	return statement
+}
+
+func (parser *Parser) parseDeferStatement() *ast.DeferStatement {
+	statement := &ast.DeferStatement{Token: parser.currToken}
+
+	parser.nextToken()
+
+	statement.Expression = parser.parseExpression(LOWEST)
+
+	for parser.peekTokenIs(token.SEMICOLON) {
+		parser.nextToken()
+	}
+
+	return statement
+}
+
+// parseLabeledLoop parses `label: do { ... } while (...)` or `label: while
+// (...) { ... }`, wherever an expression can be, so a labeled loop can also
+// be the value of a let.
+func (parser *Parser) parseLabeledLoop() ast.Expression {
+	label := &ast.Identifier{Token: parser.currToken, Value: parser.currToken.Literal}
+
+	parser.nextToken()
+
+	if !parser.peekTokenIs(token.DO) && !parser.peekTokenIs(token.WHILE) {
+		parser.peekError(token.DO)
+		return nil
+	}
+	parser.nextToken()
+
+	if parser.isLoopLabel(label.Value) {
+		message := fmt.Sprintf("loop label %s already defined", label.Value)
+		parser.addError(label.Token, message)
+		return nil
+	}
+
+	if parser.currTokenIs(token.WHILE) {
+		if loop := parser.parseWhileLoop(label); loop != nil {
+			return loop
+		}
+		return nil
+	}
+	if loop := parser.parseLoop(label); loop != nil {
+		return loop
+	}
+	return nil
+}
+
+func (parser *Parser) parseDoWhileExpression() ast.Expression {
+	loop := parser.parseLoop(nil)
+	if loop == nil {
+		return nil
+	}
+	return loop
+}
+
+func (parser *Parser) parseWhileExpression() ast.Expression {
+	loop := parser.parseWhileLoop(nil)
+	if loop == nil {
+		return nil
+	}
+	return loop
+}
+
+// parseWhileLoop parses `while (condition) { body }`, the loop that checks
+// its condition before running its body.
+func (parser *Parser) parseWhileLoop(label *ast.Identifier) *ast.WhileExpression {
+	expression := &ast.WhileExpression{Token: parser.currToken, Label: label}
+
+	if !parser.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	parser.nextToken()
+	expression.Condition = parser.parseExpression(LOWEST)
+
+	if !parser.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !parser.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	if label != nil {
+		parser.loopLabels = append(parser.loopLabels, label.Value)
+	} else {
+		parser.loopLabels = append(parser.loopLabels, "")
+	}
+	expression.Body = parser.parseBlockStatement()
+	parser.loopLabels = parser.loopLabels[:len(parser.loopLabels)-1]
+
+	return expression
+}
+
+func (parser *Parser) parseLoop(label *ast.Identifier) *ast.DoWhileExpression {
+	expression := &ast.DoWhileExpression{Token: parser.currToken, Label: label}
+
+	if !parser.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	if label != nil {
+		parser.loopLabels = append(parser.loopLabels, label.Value)
+	} else {
+		parser.loopLabels = append(parser.loopLabels, "")
+	}
+	expression.Body = parser.parseBlockStatement()
+	parser.loopLabels = parser.loopLabels[:len(parser.loopLabels)-1]
+
+	if !parser.expectPeek(token.WHILE) {
+		return nil
+	}
+
+	if !parser.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	parser.nextToken()
+	expression.Condition = parser.parseExpression(LOWEST)
+
+	if !parser.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	return expression
+}
+
+// parseBreakStatement parses `break [label] [value];`. An identifier right
+// after break is only taken as a label if one of the enclosing loops has
+// that label, otherwise it's the value the loop evaluates to.
+func (parser *Parser) parseBreakStatement() ast.Statement {
+	statement := &ast.BreakStatement{Token: parser.currToken}
+
+	if parser.peekTokenIs(token.IDENT) && parser.isLoopLabel(parser.peekToken.Literal) {
+		statement.Label = parser.parseLoopLabel()
+	}
+
+	if !parser.checkLoopLabel(statement.Token, statement.Label) {
+		return nil
+	}
+
+	if !parser.peekEndsStatement() {
+		parser.nextToken()
+		statement.Value = parser.parseExpression(LOWEST)
+	}
+
+	for parser.peekTokenIs(token.SEMICOLON) {
+		parser.nextToken()
+	}
+
+	return statement
+}
+
+func (parser *Parser) parseContinueStatement() ast.Statement {
+	statement := &ast.ContinueStatement{Token: parser.currToken}
+
+	statement.Label = parser.parseLoopLabel()
+	if !parser.checkLoopLabel(statement.Token, statement.Label) {
+		return nil
+	}
+
+	for parser.peekTokenIs(token.SEMICOLON) {
+		parser.nextToken()
+	}
+
+	return statement
+}
+
+func (parser *Parser) parseLoopLabel() *ast.Identifier {
+	if !parser.peekTokenIs(token.IDENT) || parser.peekEndsStatement() {
+		return nil
+	}
+
+	parser.nextToken()
+	return &ast.Identifier{Token: parser.currToken, Value: parser.currToken.Literal}
+}
+
+// checkLoopLabel reports break and continue statements that aren't inside a
+// loop, or that refer to a label none of the enclosing loops has.
+func (parser *Parser) checkLoopLabel(statement token.Token, label *ast.Identifier) bool {
+	if len(parser.loopLabels) == 0 {
+		message := fmt.Sprintf("%s outside of a loop", statement.Literal)
+		parser.addError(statement, message)
+		return false
+	}
+
+	if label == nil || parser.isLoopLabel(label.Value) {
+		return true
+	}
+
+	message := fmt.Sprintf("unknown loop label %s in %s", label.Value, statement.Literal)
+	parser.addError(label.Token, message)
+	return false
+}
+
+func (parser *Parser) isLoopLabel(name string) bool {
+	for _, enclosing := range parser.loopLabels {
+		if enclosing == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (parser *Parser) parseAssertStatement() ast.Statement {
+	statement := &ast.AssertStatement{Token: parser.currToken}
+
+	if !parser.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	arguments := parser.parseExpressionList(token.RPAREN)
+	if len(arguments) < 1 || len(arguments) > 2 {
+		message := fmt.Sprintf("wrong number of arguments to assert. got=%d, want=1 or 2", len(arguments))
+		parser.addError(statement.Token, message)
+		return nil
+	}
+
+	statement.Condition = arguments[0]
+	statement.Source = arguments[0].String()
+	if len(arguments) == 2 {
+		statement.Message = arguments[1]
+	}
+
 	for parser.peekTokenIs(token.SEMICOLON) {
 		parser.nextToken()
 	}
@@ -244,7 +659,7 @@ func (parser *Parser) parseExpression(precedence int) ast.Expression {
 	}
 	leftExp := prefixFn()
 
-	for !parser.peekTokenIs(token.SEMICOLON) && precedence < parser.peekPrecedence() {
+	for !parser.peekTokenIs(token.SEMICOLON) && !parser.peekStartsStatement() && precedence < parser.peekPrecedence() {
 		infixFn := parser.infixParseFns[parser.peekToken.Type]
 		if infixFn == nil {
 			return leftExp
@@ -279,12 +694,33 @@ func (parser *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	}
 
 	precedence := parser.currPrecedence()
+	// Exponentiation is right-associative: 2 ** 3 ** 2 == 2 ** (3 ** 2)
+	if parser.currTokenIs(token.POWER) {
+		precedence--
+	}
 	parser.nextToken()
 	expression.Right = parser.parseExpression(precedence)
 
 	return expression
 }
 
+func (parser *Parser) parseAssignExpression(left ast.Expression) ast.Expression {
+	name, ok := left.(*ast.Identifier)
+	if !ok {
+		parser.addError(parser.currToken, fmt.Sprintf("invalid assignment target %s", left.String()))
+		return nil
+	}
+
+	expression := &ast.AssignExpression{Token: parser.currToken, Name: name}
+
+	// Assignment is right-associative: a = b = c assigns c to both.
+	precedence := parser.currPrecedence() - 1
+	parser.nextToken()
+	expression.Value = parser.parseExpression(precedence)
+
+	return expression
+}
+
 func (parser *Parser) parseGroupedExpression() ast.Expression {
 	parser.nextToken()
 
@@ -330,6 +766,57 @@ func (parser *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
+func (parser *Parser) parseTryExpression() ast.Expression {
+	expression := &ast.TryExpression{Token: parser.currToken}
+
+	if !parser.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Block = parser.parseBlockStatement()
+
+	if parser.peekTokenIs(token.CATCH) {
+		parser.nextToken()
+
+		if !parser.expectPeek(token.LPAREN) {
+			return nil
+		}
+
+		if !parser.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		expression.Parameter = &ast.Identifier{Token: parser.currToken, Value: parser.currToken.Literal}
+
+		if !parser.expectPeek(token.RPAREN) {
+			return nil
+		}
+
+		if !parser.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		expression.Catch = parser.parseBlockStatement()
+	}
+
+	if parser.peekTokenIs(token.FINALLY) {
+		parser.nextToken()
+
+		if !parser.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		expression.Finally = parser.parseBlockStatement()
+	}
+
+	if expression.Catch == nil && expression.Finally == nil {
+		parser.addError(expression.Token, "expected catch or finally after try block")
+		return nil
+	}
+
+	return expression
+}
+
 func (parser *Parser) parseFunctionLiteral() ast.Expression {
 	literal := &ast.FunctionLiteral{Token: parser.currToken}
 
@@ -343,32 +830,91 @@ func (parser *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
+	// Loops outside of the function can't be broken out of from inside it.
+	loopLabels := parser.loopLabels
+	parser.loopLabels = nil
 	literal.Body = parser.parseBlockStatement()
+	parser.loopLabels = loopLabels
+
+	return literal
+}
+
+func (parser *Parser) parseMacroLiteral() ast.Expression {
+	literal := &ast.MacroLiteral{Token: parser.currToken}
+
+	if !parser.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	literal.Parameters = parser.parseFunctionParameters()
+
+	if !parser.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	loopLabels := parser.loopLabels
+	parser.loopLabels = nil
+	literal.Body = parser.parseBlockStatement()
+	parser.loopLabels = loopLabels
+
+	return literal
+}
+
+// parseLambdaLiteral parses the short `|x, y| x + y` syntax into the same
+// function literal as `fn(x, y) { x + y }`.
+func (parser *Parser) parseLambdaLiteral() ast.Expression {
+	fnToken := token.Token{
+		Type:    token.FUNCTION,
+		Literal: "fn",
+		Line:    parser.currToken.Line,
+		Column:  parser.currToken.Column,
+	}
+	literal := &ast.FunctionLiteral{Token: fnToken}
+
+	literal.Parameters = parser.parseIdentifierList(token.BAR)
+	if literal.Parameters == nil {
+		return nil
+	}
+
+	parser.nextToken()
+
+	body := &ast.ExpressionStatement{Token: parser.currToken}
+	body.Expression = parser.parseExpression(LOWEST)
+
+	literal.Body = &ast.BlockStatement{Token: parser.currToken, Statements: []ast.Statement{body}}
 
 	return literal
 }
 
 func (parser *Parser) parseFunctionParameters() []*ast.Identifier {
+	return parser.parseIdentifierList(token.RPAREN)
+}
+
+func (parser *Parser) parseIdentifierList(end token.Type) []*ast.Identifier {
 	identifiers := []*ast.Identifier{}
 
-	if parser.peekTokenIs(token.RPAREN) {
+	if parser.peekTokenIs(end) {
 		parser.nextToken()
 		return identifiers
 	}
 
-	parser.nextToken()
+	if !parser.expectPeek(token.IDENT) {
+		return nil
+	}
 
 	identifier := &ast.Identifier{Token: parser.currToken, Value: parser.currToken.Literal}
 	identifiers = append(identifiers, identifier)
 
 	for parser.peekTokenIs(token.COMMA) {
 		parser.nextToken()
-		parser.nextToken()
+		if !parser.expectPeek(token.IDENT) {
+			return nil
+		}
 		identifier := &ast.Identifier{Token: parser.currToken, Value: parser.currToken.Literal}
 		identifiers = append(identifiers, identifier)
 	}
 
-	if !parser.expectPeek(token.RPAREN) {
+	if !parser.expectPeek(end) {
 		return nil
 	}
 
@@ -381,6 +927,23 @@ func (parser *Parser) parseCallExpression(function ast.Expression) ast.Expressio
 	return expression
 }
 
+// parsePipeExpression desugars `x |> f` into `f(x)` and `x |> g(1)` into
+// `g(x, 1)`.
+func (parser *Parser) parsePipeExpression(left ast.Expression) ast.Expression {
+	pipeToken := parser.currToken
+
+	precedence := parser.currPrecedence()
+	parser.nextToken()
+	right := parser.parseExpression(precedence)
+
+	if call, ok := right.(*ast.CallExpression); ok {
+		arguments := append([]ast.Expression{left}, call.Arguments...)
+		return &ast.CallExpression{Token: call.Token, Function: call.Function, Arguments: arguments}
+	}
+
+	return &ast.CallExpression{Token: pipeToken, Function: right, Arguments: []ast.Expression{left}}
+}
+
 func (parser *Parser) parseIndexExpression(array ast.Expression) ast.Expression {
 	expression := &ast.IndexExpression{Token: parser.currToken, Left: array}
 
@@ -394,7 +957,24 @@ func (parser *Parser) parseIndexExpression(array ast.Expression) ast.Expression
 	return expression
 }
 
+func (parser *Parser) parseOptionalChainExpression(left ast.Expression) ast.Expression {
+	expression := &ast.OptionalChainExpression{Token: parser.currToken, Left: left}
+
+	if !parser.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	expression.Property = &ast.Identifier{Token: parser.currToken, Value: parser.currToken.Literal}
+
+	return expression
+}
+
+// parseIdentifier parses an identifier, or a labeled loop if it's followed
+// by a colon outside of a hash literal's key.
 func (parser *Parser) parseIdentifier() ast.Expression {
+	if parser.peekTokenIs(token.COLON) && !parser.inHashKey {
+		return parser.parseLabeledLoop()
+	}
 	return &ast.Identifier{Token: parser.currToken, Value: parser.currToken.Literal}
 }
 
@@ -404,7 +984,21 @@ func (parser *Parser) parseIntegerLiteral() ast.Expression {
 	value, err := strconv.ParseInt(parser.currToken.Literal, 0, 64)
 	if err != nil {
 		message := fmt.Sprintf("could not parse %q as integer", parser.currToken.Literal)
-		parser.errors = append(parser.errors, message)
+		parser.addError(parser.currToken, message)
+		return nil
+	}
+	literal.Value = value
+
+	return literal
+}
+
+func (parser *Parser) parseFloatLiteral() ast.Expression {
+	literal := &ast.FloatLiteral{Token: parser.currToken}
+
+	value, err := strconv.ParseFloat(parser.currToken.Literal, 64)
+	if err != nil {
+		message := fmt.Sprintf("could not parse %q as float", parser.currToken.Literal)
+		parser.addError(parser.currToken, message)
 		return nil
 	}
 	literal.Value = value
@@ -456,9 +1050,14 @@ func (parser *Parser) parseHashLiteral() ast.Expression {
 	hash := &ast.HashLiteral{Token: parser.currToken}
 	hash.Pairs = make(map[ast.Expression]ast.Expression)
 
+	inHashKey := parser.inHashKey
+	defer func() { parser.inHashKey = inHashKey }()
+
 	for !parser.peekTokenIs(token.RBRACE) {
 		parser.nextToken()
+		parser.inHashKey = true
 		key := parser.parseExpression(LOWEST)
+		parser.inHashKey = false
 
 		if !parser.expectPeek(token.COLON) {
 			return nil
@@ -468,6 +1067,7 @@ func (parser *Parser) parseHashLiteral() ast.Expression {
 		value := parser.parseExpression(LOWEST)
 
 		hash.Pairs[key] = value
+		hash.Order = append(hash.Order, key)
 
 		if !parser.peekTokenIs(token.RBRACE) && !parser.expectPeek(token.COMMA) {
 			return nil
@@ -3,7 +3,9 @@ package parser
 import (
 	"fmt"
 	"monkey/ast"
+	"monkey/diagnostic"
 	"monkey/lexer"
+	"reflect"
 	"testing"
 )
 
@@ -110,6 +112,38 @@ func TestIdentifierExpression(t *testing.T) {
 	}
 }
 
+func TestFloatLiteralExpression(t *testing.T) {
+	input := "3.25;"
+
+	l := lexer.New(input)
+	p := New(l)
+
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has not enough statements. got=%d", len(program.Statements))
+	}
+
+	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	literal, ok := statement.Expression.(*ast.FloatLiteral)
+	if !ok {
+		t.Fatalf("statement.Expression is not *ast.FloatLiteral. got=%T", statement.Expression)
+	}
+
+	if literal.Value != 3.25 {
+		t.Errorf("literal.Value is not %f. got=%f", 3.25, literal.Value)
+	}
+
+	if literal.TokenLiteral() != "3.25" {
+		t.Errorf("literal.TokenLiteral() is not %s. got=%s", "3.25", literal.TokenLiteral())
+	}
+}
+
 func TestIntegerLiteralExpression(t *testing.T) {
 	input := "5;"
 
@@ -373,6 +407,78 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"1..n + 1",
+			"(1 .. (n + 1))",
+		},
+		{
+			"0..=a * 2 == b",
+			"((0 ..= (a * 2)) == b)",
+		},
+		{
+			"user?.address?.city",
+			"((user?.address)?.city)",
+		},
+		{
+			"a + b?.c * d",
+			"(a + ((b?.c) * d))",
+		},
+		{
+			"a ?? b == c",
+			"(a ?? (b == c))",
+		},
+		{
+			"user?.name ?? f(x) + 1",
+			"((user?.name) ?? (f(x) + 1))",
+		},
+		{
+			"a ?? b |> f",
+			"f((a ?? b))",
+		},
+		{
+			"a + 1 in b == true",
+			"(((a + 1) in b) == true)",
+		},
+		{
+			"2 ** 3 ** 2",
+			"(2 ** (3 ** 2))",
+		},
+		{
+			"a * b ** c",
+			"(a * (b ** c))",
+		},
+		{
+			"-a ** b",
+			"(-(a ** b))",
+		},
+		{
+			"a = b = c + 1",
+			"(a = (b = (c + 1)))",
+		},
+		{
+			"a = b |> f",
+			"(a = f(b))",
+		},
+		{
+			"f >> g >> h",
+			"((f >> g) >> h)",
+		},
+		{
+			"x |> f >> g",
+			"(f >> g)(x)",
+		},
+		{
+			"x |> f",
+			"f(x)",
+		},
+		{
+			"x |> f |> g(1)",
+			"g(f(x), 1)",
+		},
+		{
+			"a + b |> f(c * d)",
+			"f((a + b), (c * d))",
+		},
 	}
 
 	for _, test := range tests {
@@ -530,6 +636,48 @@ func TestFunctionLiteralParsing(t *testing.T) {
 	testInfixExpression(t, bodyStatement.Expression, "x", "+", "y")
 }
 
+func TestMacroLiteralParsing(t *testing.T) {
+	input := `macro(x, y) { x + y; }`
+
+	l := lexer.New(input)
+	p := New(l)
+
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements doesn't contain %d statements. got=%d", 1, len(program.Statements))
+	}
+
+	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	macro, ok := statement.Expression.(*ast.MacroLiteral)
+	if !ok {
+		t.Fatalf("statement.Expression is not ast.MacroLiteral. got=%T", statement.Expression)
+	}
+
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("macro literal parameters wrong. want 2, got=%d", len(macro.Parameters))
+	}
+
+	testLiteralExpression(t, macro.Parameters[0], "x")
+	testLiteralExpression(t, macro.Parameters[1], "y")
+
+	if len(macro.Body.Statements) != 1 {
+		t.Fatalf("macro.Body.Statements doesn't contain %d statements. got=%d", 1, len(macro.Body.Statements))
+	}
+
+	bodyStatement, ok := macro.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("macro.Body.Statements[0] is not ast.ExpressionStatement. got=%T", macro.Body.Statements[0])
+	}
+
+	testInfixExpression(t, bodyStatement.Expression, "x", "+", "y")
+}
+
 func TestFunctionParameterParsing(t *testing.T) {
 	tests := []struct {
 		input          string
@@ -560,6 +708,54 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestLambdaLiteralParsing(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedParams  []string
+		expectedBody    string
+		expectedProgram string
+	}{
+		{"|x| x * 2", []string{"x"}, "(x * 2)", "fn(x)(x * 2)"},
+		{"|x, y| x + y", []string{"x", "y"}, "(x + y)", "fn(x, y)(x + y)"},
+		{"|| 42", []string{}, "42", "fn()42"},
+		{"map(arr, |x| x + 1)", nil, "", "map(arr, fn(x)(x + 1))"},
+	}
+
+	for _, test := range tests {
+		l := lexer.New(test.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != test.expectedProgram {
+			t.Errorf("expected=%q, got=%q", test.expectedProgram, program.String())
+		}
+
+		if test.expectedParams == nil {
+			continue
+		}
+
+		statement := program.Statements[0].(*ast.ExpressionStatement)
+		function, ok := statement.Expression.(*ast.FunctionLiteral)
+		if !ok {
+			t.Fatalf("statement.Expression is not ast.FunctionLiteral. got=%T", statement.Expression)
+		}
+
+		if len(function.Parameters) != len(test.expectedParams) {
+			t.Fatalf("length parameters wrong. want %d, got=%d",
+				len(test.expectedParams), len(function.Parameters))
+		}
+
+		for i, ident := range test.expectedParams {
+			testLiteralExpression(t, function.Parameters[i], ident)
+		}
+
+		if function.Body.String() != test.expectedBody {
+			t.Errorf("function.Body wrong. want=%q, got=%q", test.expectedBody, function.Body.String())
+		}
+	}
+}
+
 func TestCallExpressionParsing(t *testing.T) {
 	input := "add(1, 2 * 3, 4 + 5);"
 
@@ -720,14 +916,14 @@ func testIdentifier(t *testing.T, expression ast.Expression, value string) bool
 }
 
 func checkParserErrors(t *testing.T, p *Parser) {
-	errors := p.Errors()
-	if len(errors) == 0 {
+	diagnostics := p.Diagnostics()
+	if len(diagnostics) == 0 {
 		return
 	}
 
-	t.Errorf("parser has %d errors", len(errors))
-	for _, message := range errors {
-		t.Errorf("parser error: %q", message)
+	t.Errorf("parser has %d errors", len(diagnostics))
+	for _, found := range diagnostics {
+		t.Errorf("parser error: %q", found.Message)
 	}
 	t.FailNow()
 }
@@ -898,3 +1094,271 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		testFunc(value)
 	}
 }
+
+func TestHashLetStatements(t *testing.T) {
+	input := "let {name, age} = person;"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements doesn't contain 1 statements. got=%d", len(program.Statements))
+	}
+
+	statement, ok := program.Statements[0].(*ast.HashLetStatement)
+	if !ok {
+		t.Fatalf("statement not *ast.HashLetStatement. got=%T", program.Statements[0])
+	}
+
+	if len(statement.Names) != 2 {
+		t.Fatalf("statement.Names wrong length. got=%d", len(statement.Names))
+	}
+
+	testIdentifier(t, statement.Names[0], "name")
+	testIdentifier(t, statement.Names[1], "age")
+	testIdentifier(t, statement.Value, "person")
+
+	if program.String() != "let {name, age} = person;" {
+		t.Errorf("program.String() wrong. got=%q", program.String())
+	}
+}
+
+func TestMultipleLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a, b = divmod(7, 2);", "let a, b = divmod(7, 2);"},
+		{"let x, y, z = [1, 2, 3];", "let x, y, z = [1, 2, 3];"},
+		{"var x = 5;", "var x = 5;"},
+		{"var x, y = [1, 2];", "var x, y = [1, 2];"},
+		{"var {x, y} = h;", "var {x, y} = h;"},
+		{"return a, b + 1;", "return [a, (b + 1)];"},
+	}
+
+	for _, test := range tests {
+		l := lexer.New(test.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements doesn't contain 1 statements. got=%d", len(program.Statements))
+		}
+
+		if program.String() != test.expected {
+			t.Errorf("expected=%q, got=%q", test.expected, program.String())
+		}
+	}
+}
+
+func TestTryExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { x } catch (e) { y }", "try x catch(e) y"},
+		{"try { x } finally { z }", "try x finally z"},
+		{"try { x } catch (e) { y } finally { z }", "try x catch(e) y finally z"},
+		{"throw x + 1;", "throw (x + 1);"},
+		{"do { x } while (x < 10);", "do x while(x < 10)"},
+		{"outer: do { do { break outer; } while (y); continue; } while (x);", "outer: do do break outer; whileycontinue; whilex"},
+		{"let x = do { break 5; } while (true);", "let x = do break 5; whiletrue;"},
+		{"outer: do { break outer x + 1; } while (true);", "outer: do break outer (x + 1); whiletrue"},
+		{"while (x < 10) { x }", "while(x < 10) x"},
+		{"outer: while (x) { while (y) { break outer; } continue; }", "outer: whilex whiley break outer;continue;"},
+		{"let x = while (true) { break 5; };", "let x = whiletrue break 5;;"},
+		{"let x = outer: while (true) { do { break outer 5; } while (true); };", "let x = outer: whiletrue do break outer 5; whiletrue;"},
+		{"let x = outer: do { break outer; } while (x);", "let x = outer: do break outer; whilex;"},
+		{"f(outer: do { break; } while (x))", "f(outer: do break; whilex)"},
+		{"{outer: 1, a: do { break 2; } while (x)}", "{outer: 1, a: do break 2; whilex}"},
+		{"assert(x == 1);", "assert((x == 1));"},
+		{"assert(x, \"message\");", "assert(x, message);"},
+	}
+
+	for _, test := range tests {
+		l := lexer.New(test.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != test.expected {
+			t.Errorf("expected=%q, got=%q", test.expected, program.String())
+		}
+	}
+}
+
+func TestTryExpressionWithoutHandler(t *testing.T) {
+	l := lexer.New("try { x }")
+	p := New(l)
+	p.ParseProgram()
+
+	diagnostics := p.Diagnostics()
+	if len(diagnostics) != 1 {
+		t.Fatalf("wrong number of errors. got=%d (%v)", len(diagnostics), diagnostics)
+	}
+
+	if diagnostics[0].Message != "expected catch or finally after try block" {
+		t.Errorf("wrong error message. got=%q", diagnostics[0].Message)
+	}
+}
+
+func TestLoopControlErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"break;", "break outside of a loop"},
+		{"continue;", "continue outside of a loop"},
+		{"do { fn() { break; } } while (true);", "break outside of a loop"},
+		{"do { continue outer; } while (true);", "unknown loop label outer in continue"},
+		{"outer: do { continue inner; } while (true);", "unknown loop label inner in continue"},
+		{"outer: do { outer: do { 1 } while (true); } while (true);", "loop label outer already defined"},
+		{"outer: while (true) { outer: while (true) { 1 } }", "loop label outer already defined"},
+		{"while (true) { continue outer; }", "unknown loop label outer in continue"},
+		{"while (true) { fn() { break; } }", "break outside of a loop"},
+	}
+
+	for _, test := range tests {
+		l := lexer.New(test.input)
+		p := New(l)
+		p.ParseProgram()
+
+		diagnostics := p.Diagnostics()
+		if len(diagnostics) == 0 {
+			t.Errorf("expected parser errors for %q, got none", test.input)
+			continue
+		}
+
+		if diagnostics[0].Message != test.expectedError {
+			t.Errorf("wrong error for %q. want=%q, got=%q", test.input, test.expectedError, diagnostics[0].Message)
+		}
+	}
+}
+
+func TestInvalidAssignmentTarget(t *testing.T) {
+	l := lexer.New("a[0] = 1;")
+	p := New(l)
+	p.ParseProgram()
+
+	diagnostics := p.Diagnostics()
+	if len(diagnostics) == 0 {
+		t.Fatalf("expected parser errors, got none")
+	}
+
+	if diagnostics[0].Message != "invalid assignment target (a[0])" {
+		t.Errorf("wrong error message. got=%q", diagnostics[0].Message)
+	}
+	if start := diagnostics[0].Span.Start; start.Line != 1 || start.Column != 6 {
+		t.Errorf("wrong error position. got=%d:%d", start.Line, start.Column)
+	}
+}
+
+func TestNewlineTerminatedStatements(t *testing.T) {
+	tests := []struct {
+		input              string
+		expectedStatements int
+		expected           string
+	}{
+		{"let x = 5\nlet y = x\n", 2, "let x = 5;let y = x;"},
+		{"let f = g\n(1 + 2)", 2, "let f = g;(1 + 2)"},
+		{"a\n[1, 2]", 2, "a[1, 2]"},
+		{"a\n-b", 2, "a(-b)"},
+		{"a(b)\n(c)", 2, "a(b)c"},
+		{"a +\nb", 1, "(a + b)"},
+		{"[1, 2]\n|> len", 1, "len([1, 2])"},
+		{"a\n== b", 1, "(a == b)"},
+		{"f(a,\nb)", 1, "f(a, b)"},
+		{"let x = 1;;\n;let y = 2", 2, "let x = 1;let y = 2;"},
+		{"fn() {\nreturn\n}", 1, "fn()return ;"},
+		{"do {\nbreak\nx\n} while (true)", 1, "do break;x whiletrue"},
+	}
+
+	for _, test := range tests {
+		l := lexer.New(test.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != test.expectedStatements {
+			t.Errorf("wrong number of statements for %q. want=%d, got=%d",
+				test.input, test.expectedStatements, len(program.Statements))
+		}
+
+		if program.String() != test.expected {
+			t.Errorf("expected=%q, got=%q", test.expected, program.String())
+		}
+	}
+}
+
+func TestSyntaxErrorPositions(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedLine   int
+		expectedColumn int
+		expectedEnd    int
+	}{
+		{"let = 1;", 1, 5, 6},
+		{"let x = 1;\n  let y = ;", 2, 11, 12},
+		{"break;", 1, 1, 6},
+		{"do { continue outer; } while (true);", 1, 15, 20},
+		{"try { x }", 1, 1, 4},
+		{`let "a" = 1;`, 1, 5, 8},
+	}
+
+	for _, test := range tests {
+		p := New(lexer.New(test.input))
+		p.ParseProgram()
+
+		diagnostics := p.Diagnostics()
+		if len(diagnostics) == 0 {
+			t.Errorf("expected parser errors for %q, got none", test.input)
+			continue
+		}
+
+		span := diagnostics[0].Span
+		if span.Start.Line != test.expectedLine || span.Start.Column != test.expectedColumn {
+			t.Errorf("wrong position for %q. expected=%d:%d, got=%d:%d", test.input,
+				test.expectedLine, test.expectedColumn, span.Start.Line, span.Start.Column)
+		}
+		if span.End.Line != test.expectedLine || span.End.Column != test.expectedEnd {
+			t.Errorf("wrong end for %q. expected=%d:%d, got=%d:%d", test.input,
+				test.expectedLine, test.expectedEnd, span.End.Line, span.End.Column)
+		}
+	}
+}
+
+func TestHashLiteralSourceOrder(t *testing.T) {
+	p := New(lexer.New(`{"b": 1, "a": 2, "c": 3}`))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if program.String() != "{b: 1, a: 2, c: 3}" {
+		t.Errorf("pairs not in source order. got=%q", program.String())
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	p := New(lexer.New("let x = 1;\nlet = 2;"))
+	p.ParseProgram()
+
+	diagnostics := p.Diagnostics()
+	if len(diagnostics) == 0 {
+		t.Fatalf("expected diagnostics, got none")
+	}
+
+	expected := diagnostic.Diagnostic{
+		Code:     "syntax",
+		Severity: diagnostic.Error,
+		Message:  "expected next token to be IDENT, got = instead",
+		Span: diagnostic.Span{
+			Start: diagnostic.Position{Line: 2, Column: 5},
+			End:   diagnostic.Position{Line: 2, Column: 6},
+		},
+	}
+	if !reflect.DeepEqual(diagnostics[0], expected) {
+		t.Errorf("wrong diagnostic.\nexpected=%+v\ngot=%+v", expected, diagnostics[0])
+	}
+}
@@ -0,0 +1,80 @@
+package repl
+
+import (
+	"math"
+	"monkey/object"
+	"strconv"
+	"strings"
+)
+
+// literal returns Monkey source that evaluates to a value equal to obj, for
+// integers, floats, strings, booleans and the arrays and hashes holding
+// them. It reports false for anything else, like functions, null and floats
+// that aren't finite, which have no literal.
+func literal(obj object.Object) (string, bool) {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		if obj.Value == math.MinInt64 {
+			return "", false // Its magnitude doesn't fit in an integer literal
+		}
+		return strconv.FormatInt(obj.Value, 10), true
+	case *object.Float:
+		if math.IsNaN(obj.Value) || math.IsInf(obj.Value, 0) {
+			return "", false
+		}
+		out := strconv.FormatFloat(obj.Value, 'f', -1, 64)
+		if !strings.Contains(out, ".") {
+			out += ".0"
+		}
+		return out, true
+	case *object.Boolean:
+		return strconv.FormatBool(obj.Value), true
+	case *object.String:
+		return stringLiteral(obj.Value), true
+	case *object.Array:
+		elements := make([]string, len(obj.Elements))
+		for i, element := range obj.Elements {
+			var ok bool
+			if elements[i], ok = literal(element); !ok {
+				return "", false
+			}
+		}
+		return "[" + strings.Join(elements, ", ") + "]", true
+	case *object.Hash:
+		pairs := []string{}
+		for _, pair := range obj.Ordered() {
+			key, ok := literal(pair.Key)
+			if !ok {
+				return "", false
+			}
+			value, ok := literal(pair.Value)
+			if !ok {
+				return "", false
+			}
+			pairs = append(pairs, key+": "+value)
+		}
+		return "{" + strings.Join(pairs, ", ") + "}", true
+	}
+	return "", false
+}
+
+// stringLiteral quotes value. String literals have no escapes, so the
+// characters that would end one, or the line it's saved on, are spliced in
+// with chr.
+func stringLiteral(value string) string {
+	parts := []string{}
+	start := 0
+	for i := 0; i < len(value); i++ {
+		if char := value[i]; char == '"' || char == '\n' || char == 0 {
+			if i > start || len(parts) == 0 {
+				parts = append(parts, `"`+value[start:i]+`"`)
+			}
+			parts = append(parts, "chr("+strconv.Itoa(int(char))+")")
+			start = i + 1
+		}
+	}
+	if start < len(value) || len(parts) == 0 {
+		parts = append(parts, `"`+value[start:]+`"`)
+	}
+	return strings.Join(parts, " + ")
+}
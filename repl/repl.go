@@ -1,11 +1,15 @@
 package repl
 
 import (
-	"bufio"
 	"fmt"
 	"io"
+	"monkey/ast"
+	"monkey/diagnostic"
 	"monkey/evaluator"
 	"monkey/object"
+	"os"
+	"slices"
+	"strings"
 
 	"monkey/lexer"
 	"monkey/parser"
@@ -13,37 +17,275 @@ import (
 
 const PROMPT = ">> "
 
+// Start reads lines from in and evaluates them in a new session, writing
+// the results to out, until in ends or a line calls exit. The lines
+// `:save path` and `:load path` save the session to a file and restore
+// one saved before. The session reads stdin from in as well, through the
+// same buffer, so that read_line takes the next line typed, and writes
+// stdout and stderr to out.
 func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
-	env := object.NewEnvironment()
+	session := NewSession()
+	host := session.env.Host()
+	host.Stdin, host.Stdout, host.Stderr = in, out, out
+	lines := host.Lines()
 
 	for {
 		fmt.Fprintf(out, PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
+		line, err := lines.ReadString('\n')
+		if err != nil && line == "" {
 			return
 		}
 
-		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		switch {
+		case strings.HasPrefix(line, ":save "):
+			if err := session.Save(strings.TrimSpace(strings.TrimPrefix(line, ":save "))); err != nil {
+				fmt.Fprintln(out, err)
+			}
+		case strings.HasPrefix(line, ":load "):
+			if err := session.Load(strings.TrimSpace(strings.TrimPrefix(line, ":load "))); err != nil {
+				fmt.Fprintln(out, err)
+			}
+		default:
+			if exited, _ := session.eval(line, out); exited {
+				return
+			}
+		}
+	}
+}
+
+// A Session is the state of a REPL: the bindings and macros defined so far,
+// along with the lines that defined them. Values like closures can't be
+// written out, so those are saved as the lines that defined them and
+// restored by evaluating the lines again.
+type Session struct {
+	env    *object.Environment
+	macros *object.Environment
+	lines  []line
+}
+
+// A line is one evaluated without errors, along with what it changed.
+type line struct {
+	source string
+	bound  []string // Names of the bindings it defined or assigned
+	macros bool     // Whether it defined a macro
+}
+
+// NewSession returns a session where nothing is defined yet.
+func NewSession() *Session {
+	return &Session{env: object.NewEnvironment(), macros: object.NewEnvironment()}
+}
+
+// Eval evaluates line in session, writing its result or errors to out, and
+// reports whether it succeeded.
+func (session *Session) Eval(line string, out io.Writer) bool {
+	exited, ok := session.eval(line, out)
+	return ok && !exited
+}
+
+// eval evaluates line, remembering it if it succeeds, and reports whether
+// it called exit.
+func (session *Session) eval(line string, out io.Writer) (exited bool, ok bool) {
+	p := parser.New(lexer.New(line))
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) != 0 {
+		printParserErrors(out, line, p.Diagnostics())
+		return false, false
+	}
+
+	bindings, macros := session.env.Bindings(), len(session.macros.Bindings())
+	evaluator.DefineMacros(program, session.macros)
+	expanded, err := evaluator.ExpandMacros(program, session.macros)
+	if err != nil {
+		io.WriteString(out, diagnostic.Render("", line, err.Line, err.Column, err.Inspect()))
+		return false, false
+	}
+
+	evaluated := evaluator.Eval(expanded, session.env)
+	if _, ok := evaluated.(*object.Exit); ok {
+		return true, false
+	}
+	if err, ok := evaluated.(*object.Error); ok {
+		if err.File == "" {
+			io.WriteString(out, diagnostic.Render("", line, err.Line, err.Column, err.Inspect()))
+		} else {
+			io.WriteString(out, err.Inspect())
+			io.WriteString(out, "\n")
+		}
+		return false, false
+	}
+	if evaluated != nil {
+		io.WriteString(out, evaluated.Inspect())
+		io.WriteString(out, "\n")
+	}
 
-		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+	session.remember(line, bindings, macros)
+	return false, true
+}
+
+// remember records source as evaluated, along with the bindings it changed
+// compared to those from before and whether it added to the macros.
+func (session *Session) remember(source string, before map[string]object.Object, macros int) {
+	evaluated := line{source: source, macros: len(session.macros.Bindings()) != macros}
+	for name, value := range session.env.Bindings() {
+		if previous, ok := before[name]; !ok || previous != value {
+			evaluated.bound = append(evaluated.bound, name)
+		}
+	}
+	session.lines = append(session.lines, evaluated)
+}
+
+// Save writes session to path as lines of source. Bindings holding
+// integers, floats, strings, booleans or arrays and hashes of them are
+// written as let or var statements with their current values. Macros and
+// every other binding are written as the lines that last defined them, in
+// the order they were evaluated, after the values so that they can use
+// them. The values the lines change are written again after them.
+func (session *Session) Save(path string) error {
+	values := make(map[string]string)
+	replayed := make(map[int]bool)
+	for i, line := range session.lines {
+		if line.macros {
+			replayed[i] = true
+		}
+	}
+
+	bindings := session.env.Bindings()
+	for name, value := range bindings {
+		if source, ok := literal(value); ok {
+			values[name] = source
 			continue
 		}
+		for i := len(session.lines) - 1; i >= 0; i-- {
+			if slices.Contains(session.lines[i].bound, name) {
+				replayed[i] = true
+				break
+			}
+		}
+	}
 
-		evaluated := evaluator.Eval(program, env)
-		if evaluated != nil {
-			io.WriteString(out, evaluated.Inspect())
-			io.WriteString(out, "\n")
+	var saved strings.Builder
+	restore := func(names []string) {
+		slices.Sort(names)
+		for _, name := range names {
+			keyword := "let"
+			if session.env.Mutable(name) {
+				keyword = "var"
+			}
+			fmt.Fprintf(&saved, "%s %s = %s;\n", keyword, name, values[name])
+		}
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	restore(names)
+	changed := []string{}
+	for i, line := range session.lines {
+		if !replayed[i] {
+			continue
+		}
+		saved.WriteString(line.source)
+		saved.WriteString("\n")
+		for _, name := range line.bound {
+			if _, ok := values[name]; ok && !slices.Contains(changed, name) {
+				changed = append(changed, name)
+			}
 		}
 	}
+	restore(changed)
+
+	return os.WriteFile(path, []byte(saved.String()), 0o644)
+}
+
+// replayable are the builtins a line can call when a session is loaded.
+// Evaluating the others again could repeat what they did outside the
+// session, block, or give a different result, as reading input, the clock
+// or rand would. What the replayable ones print is discarded. Calls in the
+// bodies of functions are allowed, since defining a function doesn't run
+// them.
+var replayable = map[string]bool{
+	"abs": true, "base64_decode": true, "base64_encode": true, "bool": true,
+	"ceil": true, "chars": true, "chr": true, "clone": true, "concat": true,
+	"contains": true, "cos": true, "csv_parse": true, "csv_stringify": true,
+	"delete": true, "each": true, "ends_with": true, "entries": true,
+	"enumerate": true, "eprintln": true, "error": true, "error_message": true,
+	"exp": true, "filter": true, "first": true, "flatten": true, "float": true,
+	"floor": true, "format": true, "has_key": true, "hex_decode": true,
+	"hex_encode": true, "hmac_sha256": true, "index_of": true, "int": true,
+	"is_error": true, "join": true, "json_parse": true, "json_stringify": true,
+	"keys": true, "last": true, "len": true, "log": true, "log2": true,
+	"lower": true, "map": true, "max": true, "md5": true, "merge": true,
+	"min": true, "ord": true, "parse_float": true, "parse_int": true,
+	"path_base": true, "path_dir": true, "path_ext": true, "path_join": true,
+	"pow": true, "pp": true, "prints": true, "push": true, "puts": true,
+	"range": true, "re_find_all": true, "re_match": true, "re_replace": true,
+	"reduce": true, "repeat": true, "replace": true, "rest": true,
+	"reverse": true, "round": true, "sha1": true, "sha256": true, "sin": true,
+	"sort": true, "split": true, "sqrt": true, "starts_with": true, "str": true,
+	"substring": true, "sum": true, "tan": true, "time_format": true,
+	"time_parse": true, "trim": true, "trim_left": true, "trim_right": true,
+	"type": true, "unique": true, "upper": true, "values": true, "zip": true,
+}
+
+// Load evaluates the lines saved at path in session, as if they were typed
+// in, discarding what they print to stdout and stderr. It stops at the
+// first line that fails or that calls a builtin that isn't replayable.
+func (session *Session) Load(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	host := session.env.Host()
+	stdout, stderr := host.Stdout, host.Stderr
+	host.Stdout, host.Stderr = io.Discard, io.Discard
+	defer func() { host.Stdout, host.Stderr = stdout, stderr }()
+
+	for i, line := range strings.Split(string(content), "\n") {
+		if line == "" {
+			continue
+		}
+		if name, ok := sideEffect(line); ok {
+			return fmt.Errorf("%s:%d: refusing to replay a call to %s", path, i+1, name)
+		}
+		var output strings.Builder
+		if exited, ok := session.eval(line, &output); exited {
+			return fmt.Errorf("%s:%d: exit called while loading the session", path, i+1)
+		} else if !ok {
+			return fmt.Errorf("%s:%d: %s", path, i+1, strings.TrimSpace(output.String()))
+		}
+	}
+	return nil
+}
+
+// sideEffect returns the name of the first builtin that isn't replayable
+// that line calls outside of a function body.
+func sideEffect(line string) (string, bool) {
+	program := parser.New(lexer.New(line)).ParseProgram()
+	globals := evaluator.Globals()
+	name := ""
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FunctionLiteral, *ast.MacroLiteral:
+			return false
+		case *ast.CallExpression:
+			identifier, ok := node.Function.(*ast.Identifier)
+			if !ok || replayable[identifier.Value] || name != "" {
+				break
+			}
+			if _, builtin := slices.BinarySearch(globals, identifier.Value); builtin {
+				name = identifier.Value
+			}
+		}
+		return name == ""
+	})
+	return name, name != ""
 }
 
-func printParserErrors(out io.Writer, errors []string) {
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
+func printParserErrors(out io.Writer, line string, diagnostics []diagnostic.Diagnostic) {
+	for _, found := range diagnostics {
+		io.WriteString(out, found.Text(line))
 	}
 }
@@ -0,0 +1,175 @@
+package repl
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	session := NewSession()
+	for _, line := range []string{"var count = 1", "let add = fn(x) { count = count + x }", "nope", "add(2)"} {
+		session.Eval(line, io.Discard)
+	}
+
+	path := filepath.Join(t.TempDir(), "session.mkys")
+	if err := session.Save(path); err != nil {
+		t.Fatalf("saving failed: %s", err)
+	}
+
+	restored := NewSession()
+	if err := restored.Load(path); err != nil {
+		t.Fatalf("loading failed: %s", err)
+	}
+
+	var out strings.Builder
+	if !restored.Eval("add(10)", &out) || out.String() != "13\n" {
+		t.Errorf("wrong result after loading. expected=%q, got=%q", "13\n", out.String())
+	}
+}
+
+func TestSaveValues(t *testing.T) {
+	dir := t.TempDir()
+	session := NewSession()
+	for _, line := range []string{
+		`let n = 2; let xs = [1, 2.5, -3.0]`,
+		`let s = "say " + chr(34) + "hi" + chr(34)`,
+		`let h = {"b": 1, "a": [true, false]}`,
+		`var k = 1; let get = fn() { k }`,
+		`write_file("` + filepath.Join(dir, "out") + `", "x")`,
+		`k = 7`,
+	} {
+		if !session.Eval(line, io.Discard) {
+			t.Fatalf("evaluating %q failed", line)
+		}
+	}
+
+	path := filepath.Join(dir, "session.mkys")
+	if err := session.Save(path); err != nil {
+		t.Fatalf("saving failed: %s", err)
+	}
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `let h = {"b": 1, "a": [true, false]};
+var k = 7;
+let n = 2;
+let s = "say " + chr(34) + "hi" + chr(34);
+let xs = [1, 2.5, -3.0];
+var k = 1; let get = fn() { k }
+var k = 7;
+`
+	if string(saved) != expected {
+		t.Fatalf("wrong session saved.\nexpected=%q\ngot=%q", expected, string(saved))
+	}
+
+	restored := NewSession()
+	if err := restored.Load(path); err != nil {
+		t.Fatalf("loading failed: %s", err)
+	}
+	var out strings.Builder
+	restored.Eval(`[get(), len(s), h["a"], xs]`, &out)
+	if out.String() != "[7, 8, [true, false], [1, 2.5, -3.0]]\n" {
+		t.Errorf("wrong values after loading. got=%q", out.String())
+	}
+}
+
+func TestLoadRefusesSideEffects(t *testing.T) {
+	dir := t.TempDir()
+	written := filepath.Join(dir, "written")
+	path := filepath.Join(dir, "session.mkys")
+	source := `let save = fn() { write_file("` + written + `", "x") }` + "\n" +
+		`save(); write_file("` + written + `", "x")` + "\n"
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := NewSession().Load(path)
+	if err == nil || err.Error() != path+":2: refusing to replay a call to write_file" {
+		t.Errorf("wrong error. got=%v", err)
+	}
+	if _, err := os.Stat(written); err == nil {
+		t.Errorf("the refused line was evaluated")
+	}
+}
+
+func TestLoadRefusesInput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.mkys")
+	if err := os.WriteFile(path, []byte("let n = len(\"ab\")\nlet name = read_line()\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	session := NewSession()
+	host := session.env.Host()
+	host.Stdin = strings.NewReader("typed\n")
+
+	err := session.Load(path)
+	if err == nil || err.Error() != path+":2: refusing to replay a call to read_line" {
+		t.Errorf("wrong error. got=%v", err)
+	}
+	if line, _ := host.Lines().ReadString('\n'); line != "typed\n" {
+		t.Errorf("loading read from stdin. got=%q", line)
+	}
+}
+
+func TestLoadDiscardsOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.mkys")
+	if err := os.WriteFile(path, []byte(`let shout = fn() { eprintln("loud"); puts("quiet"); 1 }; let x = shout()`+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr strings.Builder
+	session := NewSession()
+	host := session.env.Host()
+	host.Stdout, host.Stderr = &stdout, &stderr
+
+	if err := session.Load(path); err != nil {
+		t.Fatalf("loading failed: %s", err)
+	}
+	if stdout.Len() != 0 || stderr.Len() != 0 {
+		t.Errorf("loading printed. stdout=%q, stderr=%q", stdout.String(), stderr.String())
+	}
+	if host.Stdout != &stdout || host.Stderr != &stderr {
+		t.Errorf("the streams weren't restored after loading")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.mkys")
+	if err := os.WriteFile(path, []byte("let x = 1\nnope\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := NewSession().Load(path)
+	if err == nil || !strings.HasPrefix(err.Error(), path+":2: ") || !strings.Contains(err.Error(), "identifier not found: nope") {
+		t.Errorf("wrong error. got=%v", err)
+	}
+
+	if err := NewSession().Load(filepath.Join(t.TempDir(), "missing.mkys")); err == nil {
+		t.Errorf("loading a missing file didn't fail")
+	}
+}
+
+func TestStartReadsStdinFromInput(t *testing.T) {
+	var out strings.Builder
+	Start(strings.NewReader("let name = read_line()\nAda\nname\n"), &out)
+
+	expected := PROMPT + PROMPT + "Ada\n" + PROMPT
+	if out.String() != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestStartWritesOutputToOut(t *testing.T) {
+	var out strings.Builder
+	Start(strings.NewReader("puts(1); eprintln(2); 3\n"), &out)
+
+	expected := PROMPT + "1\n2\n3\n" + PROMPT
+	if out.String() != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, out.String())
+	}
+}
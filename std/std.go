@@ -0,0 +1,17 @@
+// Package std holds the standard library modules, which are written in
+// Monkey and embedded into the binary. Each module is a file named after it,
+// e.g. strings.monkey is imported with import("strings"):
+//
+//   - strings: is_empty, pad_left, pad_right, capitalize, reversed, words
+//   - lists: take, drop, find, any, all, count
+//   - maps: from_entries, map_values, pick, omit
+//   - testing: equal, assert_equal, run
+package std
+
+import "embed"
+
+//go:embed *.monkey
+var Modules embed.FS
+
+// Extension is the file extension of Monkey source files.
+const Extension = ".monkey"
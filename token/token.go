@@ -9,6 +9,7 @@ const (
 	// Identifiers and literals
 	IDENT  = "IDENT"
 	INT    = "INT"
+	FLOAT  = "FLOAT"
 	STRING = "STRING"
 
 	// Operators
@@ -17,6 +18,7 @@ const (
 	MINUS    = "-"
 	BANG     = "!"
 	ASTERISK = "*"
+	POWER    = "**"
 	SLASH    = "/"
 
 	LT = "<"
@@ -25,7 +27,17 @@ const (
 	EQ     = "=="
 	NOT_EQ = "!="
 
+	PIPE    = "|>"
+	COMPOSE = ">>"
+
+	OPTIONAL_CHAIN = "?."
+	NULL_COALESCE  = "??"
+
+	RANGE           = ".."
+	RANGE_INCLUSIVE = "..="
+
 	// Delimiters
+	BAR       = "|"
 	COMMA     = ","
 	COLON     = ":"
 	SEMICOLON = ";"
@@ -40,26 +52,54 @@ const (
 	// Keywords
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
+	VAR      = "VAR"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	THROW    = "THROW"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	ASSERT   = "ASSERT"
+	DEFER    = "DEFER"
+	IN       = "IN"
+	DO       = "DO"
+	WHILE    = "WHILE"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
+	MACRO    = "MACRO"
 )
 
 type Token struct {
 	Type    Type
 	Literal string
+	Line    int // 1-based line of the token's first character
+	Column  int // 1-based column of the token's first character
 }
 
 var keywords = map[string]Type{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"var":      VAR,
+	"true":     TRUE,
+	"false":    FALSE,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"throw":    THROW,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
+	"assert":   ASSERT,
+	"defer":    DEFER,
+	"in":       IN,
+	"do":       DO,
+	"while":    WHILE,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"macro":    MACRO,
 }
 
 func LookupIdent(ident string) Type {
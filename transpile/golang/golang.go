@@ -0,0 +1,406 @@
+// Package golang transpiles Monkey programs into Go programs that run
+// without the interpreter, so scripts can be compiled ahead of time into
+// native binaries.
+//
+// The generated program is a single main package with a small runtime for
+// Monkey's values and the builtins puts, len, first, last, rest, push, str
+// and exit. Programs using anything else, like try, defer or hash
+// destructuring, are reported as unsupported rather than transpiled.
+package golang
+
+import (
+	_ "embed"
+	"fmt"
+	"go/format"
+	"monkey/ast"
+	"monkey/transpile"
+	"strconv"
+	"strings"
+)
+
+//go:embed runtime.go
+var runtime string
+
+// Transpile returns the Go source of a program doing what program does.
+func Transpile(program *ast.Program) (string, error) {
+	transpiler := &transpiler{out: &strings.Builder{}}
+	transpiler.pushScope(nil, program)
+	transpiler.write("func run() Value {\n")
+	transpiler.declare()
+	transpiler.statements(program.Statements, discard, "")
+	transpiler.write("return nil\n}\n")
+	if transpiler.err != nil {
+		return "", transpiler.err
+	}
+
+	header := strings.TrimPrefix(runtime, "//go:build ignore\n\n")
+	header = header[strings.Index(header, "package main"):]
+	source := "// Code generated by monkey from a Monkey program. DO NOT EDIT.\n\n" +
+		header + "\n" + transpiler.out.String()
+
+	formatted, err := format.Source([]byte(source))
+	if err != nil {
+		return "", fmt.Errorf("golang: generated invalid Go: %w", err)
+	}
+	return string(formatted), nil
+}
+
+// A mode is what's done with the value of the statements being written.
+type mode int
+
+const (
+	discard  mode = iota // The value is dropped
+	returned             // The value is returned from the enclosing Go function
+	assigned             // The value is assigned to a target variable
+)
+
+// A loop is a while or do-while loop being written, with the Monkey label
+// breaks and continues can target it by and the variable a break's value
+// goes to.
+type loop struct {
+	label  string
+	target string
+}
+
+type transpiler struct {
+	out    *strings.Builder
+	scopes []*transpile.Scope
+	loops  []loop
+	temps  int
+	err    error
+}
+
+func (transpiler *transpiler) write(format string, a ...interface{}) {
+	fmt.Fprintf(transpiler.out, format, a...)
+}
+
+func (transpiler *transpiler) unsupported(node ast.Node) string {
+	if transpiler.err == nil {
+		transpiler.err = fmt.Errorf("golang: %s is not supported: %s", strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast."), node)
+	}
+	return "nil"
+}
+
+func (transpiler *transpiler) temp() string {
+	transpiler.temps++
+	return fmt.Sprintf("t%d", transpiler.temps)
+}
+
+// pushScope starts the scope of a function with parameters and body, or of
+// the whole program.
+func (transpiler *transpiler) pushScope(parameters []*ast.Identifier, body ast.Node) {
+	transpiler.scopes = append(transpiler.scopes, transpile.NewScope(parameters, body))
+}
+
+func (transpiler *transpiler) popScope() {
+	transpiler.scopes = transpiler.scopes[:len(transpiler.scopes)-1]
+}
+
+// declare declares the Go variables for the names bound in the innermost
+// scope.
+func (transpiler *transpiler) declare() {
+	for _, name := range transpiler.scopes[len(transpiler.scopes)-1].Names {
+		transpiler.write("var %s Value\n_ = %s\n", variable(name), variable(name))
+	}
+}
+
+// resolve returns the scope name is bound in, reporting false for the names
+// of builtins.
+func (transpiler *transpiler) resolve(name string) (*transpile.Scope, bool) {
+	for i := len(transpiler.scopes) - 1; i >= 0; i-- {
+		if transpiler.scopes[i].Binds(name) {
+			return transpiler.scopes[i], true
+		}
+	}
+	return nil, false
+}
+
+func variable(name string) string {
+	return "v_" + name
+}
+
+func (transpiler *transpiler) statements(statements []ast.Statement, mode mode, target string) {
+	for i, statement := range statements {
+		if i < len(statements)-1 {
+			transpiler.statement(statement, discard, "")
+		} else {
+			transpiler.statement(statement, mode, target)
+		}
+	}
+	if len(statements) == 0 {
+		transpiler.result("nil", mode, target)
+	}
+}
+
+// result writes what's done with value in mode.
+func (transpiler *transpiler) result(value string, mode mode, target string) {
+	switch mode {
+	case discard:
+		transpiler.write("_ = %s\n", value)
+	case returned:
+		transpiler.write("return %s\n", value)
+	case assigned:
+		transpiler.write("%s = %s\n", target, value)
+	}
+}
+
+func (transpiler *transpiler) statement(statement ast.Statement, mode mode, target string) {
+	switch statement := statement.(type) {
+	case *ast.ExpressionStatement:
+		transpiler.valued(statement.Expression, mode, target)
+
+	case *ast.LetStatement:
+		transpiler.valued(statement.Value, assigned, variable(statement.Name.Value))
+		if mode != discard {
+			transpiler.result("nil", mode, target)
+		}
+
+	case *ast.ReturnStatement:
+		if statement.ReturnValue == nil {
+			transpiler.write("return nil\n")
+		} else {
+			transpiler.write("return %s\n", transpiler.expression(statement.ReturnValue))
+		}
+
+	case *ast.BreakStatement:
+		loop, ok := transpiler.loop(statement.Label)
+		if !ok {
+			transpiler.unsupported(statement)
+			return
+		}
+		if statement.Value != nil {
+			if loop.target != "" {
+				transpiler.write("%s = %s\n", loop.target, transpiler.expression(statement.Value))
+			} else {
+				transpiler.write("_ = %s\n", transpiler.expression(statement.Value))
+			}
+		}
+		transpiler.write("break %s\n", labelOf(statement.Label))
+
+	case *ast.ContinueStatement:
+		if _, ok := transpiler.loop(statement.Label); !ok {
+			transpiler.unsupported(statement)
+			return
+		}
+		transpiler.write("continue %s\n", labelOf(statement.Label))
+
+	default:
+		transpiler.unsupported(statement)
+	}
+}
+
+// valued writes expression as a statement, doing what mode says with its
+// value. If expressions and loops are written as Go statements, so the
+// returns, breaks and continues in them work.
+func (transpiler *transpiler) valued(expression ast.Expression, mode mode, target string) {
+	switch expression := expression.(type) {
+	case *ast.IfExpression:
+		transpiler.write("if truthy(%s) {\n", transpiler.expression(expression.Condition))
+		transpiler.statements(expression.Consequence.Statements, mode, target)
+		transpiler.write("} else {\n")
+		if expression.Alternative != nil {
+			transpiler.statements(expression.Alternative.Statements, mode, target)
+		} else if mode != discard {
+			transpiler.result("nil", mode, target)
+		}
+		transpiler.write("}\n")
+
+	case *ast.DoWhileExpression, *ast.WhileExpression:
+		result := target
+		switch mode {
+		case returned:
+			result = transpiler.temp()
+			transpiler.write("var %s Value\n", result)
+		case assigned:
+			transpiler.write("%s = nil\n", result)
+		}
+
+		labeled, condition, body, pretest := transpile.Loop(expression)
+		name := ""
+		if labeled != nil {
+			name = labeled.Value
+			if targeted(body, name) {
+				transpiler.write("%s:\n", label(name))
+			}
+		}
+		transpiler.loops = append(transpiler.loops, loop{label: name, target: result})
+		if pretest {
+			transpiler.write("for truthy(%s) {\n", transpiler.expression(condition))
+		} else {
+			transpiler.write("for ok := true; ok; ok = truthy(%s) {\n", transpiler.expression(condition))
+		}
+		transpiler.statements(body.Statements, discard, "")
+		transpiler.write("}\n")
+		transpiler.loops = transpiler.loops[:len(transpiler.loops)-1]
+
+		if mode == returned {
+			transpiler.write("return %s\n", result)
+		}
+
+	default:
+		transpiler.result(transpiler.expression(expression), mode, target)
+	}
+}
+
+// loop returns the loop targeted by a break or continue with label, which
+// is nil for the innermost loop.
+func (transpiler *transpiler) loop(label *ast.Identifier) (loop, bool) {
+	for i := len(transpiler.loops) - 1; i >= 0; i-- {
+		if label == nil || transpiler.loops[i].label == label.Value {
+			return transpiler.loops[i], true
+		}
+	}
+	return loop{}, false
+}
+
+func label(name string) string {
+	return "l_" + name
+}
+
+// labelOf returns the Go label for name, the label of a break or continue
+// that's nil for the innermost loop.
+func labelOf(name *ast.Identifier) string {
+	if name == nil {
+		return ""
+	}
+	return label(name.Value)
+}
+
+// targeted reports whether a break or continue in body targets the loop
+// labeled name, since Go doesn't allow unused labels.
+func targeted(body ast.Node, name string) bool {
+	found := false
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.BreakStatement:
+			found = found || node.Label != nil && node.Label.Value == name
+		case *ast.ContinueStatement:
+			found = found || node.Label != nil && node.Label.Value == name
+		}
+		return !found
+	})
+	return found
+}
+
+// expression returns the Go expression for expression.
+func (transpiler *transpiler) expression(expression ast.Expression) string {
+	switch expression := expression.(type) {
+	case *ast.IntegerLiteral:
+		return fmt.Sprintf("int64(%d)", expression.Value)
+	case *ast.FloatLiteral:
+		return fmt.Sprintf("float64(%s)", strconv.FormatFloat(expression.Value, 'g', -1, 64))
+	case *ast.Boolean:
+		return strconv.FormatBool(expression.Value)
+	case *ast.StringLiteral:
+		return strconv.Quote(expression.Value)
+
+	case *ast.Identifier:
+		if _, ok := transpiler.resolve(expression.Value); ok {
+			return variable(expression.Value)
+		}
+		return fmt.Sprintf("builtin(%q)", expression.Value)
+
+	case *ast.PrefixExpression:
+		switch expression.Operator {
+		case "!":
+			return fmt.Sprintf("not(%s)", transpiler.expression(expression.Right))
+		case "-":
+			return fmt.Sprintf("negate(%s)", transpiler.expression(expression.Right))
+		}
+
+	case *ast.InfixExpression:
+		left, right := transpiler.expression(expression.Left), transpiler.expression(expression.Right)
+		switch expression.Operator {
+		case "+", "-", "*", "/", "**", "<", ">", "==", "!=", "..", "..=":
+			return fmt.Sprintf("infix(%q, %s, %s)", expression.Operator, left, right)
+		case "??":
+			return fmt.Sprintf("coalesce(%s, func() Value { return %s })", left, right)
+		}
+
+	case *ast.AssignExpression:
+		value := transpiler.expression(expression.Value)
+		name := expression.Name.Value
+		s, ok := transpiler.resolve(name)
+		switch {
+		case !ok:
+			return fmt.Sprintf("failAssigning(%s, %q)", value, fmt.Sprintf("identifier not found: %s at %d:%d",
+				name, expression.Token.Line, expression.Token.Column))
+		case !s.Mutable[name]:
+			return fmt.Sprintf("failAssigning(%s, %q)", value, fmt.Sprintf("cannot assign to immutable binding %s at %d:%d",
+				name, expression.Token.Line, expression.Token.Column))
+		}
+		return fmt.Sprintf("assign(&%s, %s)", variable(name), value)
+
+	case *ast.CallExpression:
+		arguments := []string{transpiler.expression(expression.Function)}
+		for _, argument := range expression.Arguments {
+			arguments = append(arguments, transpiler.expression(argument))
+		}
+		return fmt.Sprintf("call(%s)", strings.Join(arguments, ", "))
+
+	case *ast.IndexExpression:
+		return fmt.Sprintf("index(%s, %s)", transpiler.expression(expression.Left), transpiler.expression(expression.Index))
+
+	case *ast.ArrayLiteral:
+		elements := []string{}
+		for _, element := range expression.Elements {
+			elements = append(elements, transpiler.expression(element))
+		}
+		return fmt.Sprintf("array(%s)", strings.Join(elements, ", "))
+
+	case *ast.HashLiteral:
+		pairs := []string{}
+		for _, key := range expression.Keys() {
+			pairs = append(pairs, transpiler.expression(key), transpiler.expression(expression.Pairs[key]))
+		}
+		return fmt.Sprintf("hash(%s)", strings.Join(pairs, ", "))
+
+	case *ast.FunctionLiteral:
+		return transpiler.function(expression)
+
+	case *ast.IfExpression, *ast.DoWhileExpression, *ast.WhileExpression:
+		if transpile.Escapes(expression) {
+			break
+		}
+		// The loops outside can't be broken out of from the function
+		// literal the expression is written in.
+		loops, out := transpiler.loops, transpiler.out
+		transpiler.loops, transpiler.out = nil, &strings.Builder{}
+		transpiler.write("func() Value {\n")
+		transpiler.valued(expression, returned, "")
+		transpiler.write("return nil\n}()")
+		written := transpiler.out.String()
+		transpiler.loops, transpiler.out = loops, out
+		return written
+	}
+
+	return transpiler.unsupported(expression)
+}
+
+func (transpiler *transpiler) function(literal *ast.FunctionLiteral) string {
+	loops, out := transpiler.loops, transpiler.out
+	transpiler.loops, transpiler.out = nil, &strings.Builder{}
+
+	// Functions are inspected the same way as in the interpreter.
+	params := []string{}
+	for _, parameter := range literal.Parameters {
+		params = append(params, parameter.String())
+	}
+	inspect := fmt.Sprintf("fn(%s) {\n%s\n}", strings.Join(params, ", "), literal.Body.String())
+
+	transpiler.write("function(%q, func(args ...Value) Value {\n", inspect)
+	transpiler.pushScope(literal.Parameters, literal.Body)
+	for i, parameter := range literal.Parameters {
+		transpiler.write("%s := argument(args, %d)\n_ = %s\n", variable(parameter.Value), i, variable(parameter.Value))
+	}
+	transpiler.declare()
+	transpiler.statements(literal.Body.Statements, returned, "")
+	transpiler.write("return nil\n})")
+	transpiler.popScope()
+
+	written := transpiler.out.String()
+	transpiler.loops, transpiler.out = loops, out
+	return written
+}
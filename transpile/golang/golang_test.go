@@ -0,0 +1,158 @@
+package golang
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Diagnostics())
+	}
+	return program
+}
+
+func TestTranspile(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go isn't installed")
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`var i = 0;
+var count = 0;
+outer: while (true) {
+	i = i + 1;
+	var j = 0;
+	while (j < 5) {
+		j = j + 1;
+		if (j == 3) { continue outer; }
+		if (i == 3) { break outer; }
+		count = count + 1;
+	}
+}
+var n = 0;
+while (false) { n = 1; }
+puts(count, n)`,
+			"4\n0\n",
+		},
+		{"var k = 0; puts(while (true) { k = k + 1; if (k == 4) { break k * 10; } })", "40\n"},
+		{
+			"let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) }; puts(fib(15))",
+			"610\n",
+		},
+		{
+			`let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } };
+let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } };
+let make = fn() { var count = 0; fn() { count = count + 1 } };
+let counter = make();
+counter();
+puts(even(10), odd(4), counter(), make)`,
+			"true\nfalse\n2\nfn() {\nvar count = 0;fn()(count = (count + 1))\n}\n",
+		},
+		{
+			`var i = 0;
+outer: do {
+	do { i = i + 1; if (i > 3) { break outer; } continue; } while (true);
+} while (true);
+let v = do { break i * 2; } while (true);
+let w = if (v > 5) { "big" } else { "small" };
+puts(i, v, w, 1 .. 4, 2 ** 10, 7 / 2, 1.5 * 2, -(3), !true, first([]) ?? "none")`,
+			"4\n8\nbig\n[1, 2, 3]\n1024\n3\n3.0\n-3\nfalse\nnone\n",
+		},
+		{
+			`let h = {"b": 1, "a": [1, 2.5, "x"], true: len};
+puts(h, h["a"][1], h["a"][5], h[true]("héllo"), push(rest([1, 2, 3]), 4), str(h["b"]) + "!")`,
+			"{b: 1, a: [1, 2.5, x], true: builtin function}\n2.5\nnull\n5\n[2, 3, 4]\n1!\n",
+		},
+	}
+
+	dir := t.TempDir()
+	for i, test := range tests {
+		source, err := Transpile(parse(t, test.input))
+		if err != nil {
+			t.Errorf("transpiling %q failed: %s", test.input, err)
+			continue
+		}
+
+		path := filepath.Join(dir, "main"+strings.Repeat("_", i)+".go")
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		output, err := exec.Command("go", "run", path).CombinedOutput()
+		if err != nil {
+			t.Errorf("running the Go for %q failed: %s\n%s", test.input, err, output)
+			continue
+		}
+		if string(output) != test.expected {
+			t.Errorf("wrong output for %q. expected=%q, got=%q", test.input, test.expected, output)
+		}
+	}
+}
+
+func TestTranspileErrors(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go isn't installed")
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 1; x = 2", "ERROR: cannot assign to immutable binding x at 1:14\n"},
+		{"puts(1 + true)", "ERROR: type mismatch: INTEGER + BOOLEAN\n"},
+		{`"a" == "a"`, "ERROR: unknown operator: STRING == STRING\n"},
+		{"nope(1)", "ERROR: identifier not found: nope\n"},
+		{"{[1]: 2}", "ERROR: unusable as hash key: ARRAY\n"},
+		{"let zero = 0; puts(1 / zero)", "ERROR: division by zero\n"},
+	}
+
+	dir := t.TempDir()
+	for i, test := range tests {
+		source, err := Transpile(parse(t, test.input))
+		if err != nil {
+			t.Errorf("transpiling %q failed: %s", test.input, err)
+			continue
+		}
+
+		path := filepath.Join(dir, "main"+strings.Repeat("_", i)+".go")
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		output, _ := exec.Command("go", "run", path).CombinedOutput()
+		if !strings.HasPrefix(string(output), test.expected) {
+			t.Errorf("wrong output for %q. expected=%q, got=%q", test.input, test.expected, output)
+		}
+	}
+}
+
+func TestTranspileUnsupported(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`try { 1 } catch (e) { 2 }`, "golang: TryExpression is not supported"},
+		{"let {a} = {};", "golang: HashLetStatement is not supported"},
+		{"fn() { defer puts(1); }", "golang: DeferStatement is not supported"},
+		{`1 in [1]`, "golang: InfixExpression is not supported"},
+		{"let f = fn(x) { puts(if (x) { return 1; }) }", "golang: IfExpression is not supported"},
+	}
+
+	for _, test := range tests {
+		_, err := Transpile(parse(t, test.input))
+		if err == nil || !strings.HasPrefix(err.Error(), test.expected) {
+			t.Errorf("wrong error for %q. expected=%q, got=%v", test.input, test.expected, err)
+		}
+	}
+}
@@ -0,0 +1,411 @@
+//go:build ignore
+
+// The runtime the Go programs generated from Monkey ones are built on. It's
+// embedded into every generated program, ahead of the program's run function.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Value is a Monkey value: int64, float64, bool, string, *Array, *Hash,
+// *Function or nil for null.
+type Value interface{}
+
+type Array struct {
+	Elements []Value
+}
+
+// Hash is ordered by insertion like Monkey's hashes.
+type Hash struct {
+	Keys  []Value
+	Pairs map[Value]Value
+}
+
+type Function struct {
+	Inspect string
+	Fn      func(args ...Value) Value
+}
+
+// runtimeError is what a program panics with when it fails, like an error
+// ending evaluation in the interpreter.
+type runtimeError string
+
+// exitCode is what a program panics with when it calls exit.
+type exitCode int64
+
+func main() {
+	defer func() {
+		switch r := recover().(type) {
+		case nil:
+		case runtimeError:
+			fmt.Fprintln(os.Stderr, "ERROR: "+string(r))
+			os.Exit(1)
+		case exitCode:
+			os.Exit(int(r))
+		default:
+			panic(r)
+		}
+	}()
+
+	run()
+}
+
+func fail(format string, a ...interface{}) Value {
+	panic(runtimeError(fmt.Sprintf(format, a...)))
+}
+
+func typeName(value Value) string {
+	switch value.(type) {
+	case int64:
+		return "INTEGER"
+	case float64:
+		return "FLOAT"
+	case bool:
+		return "BOOLEAN"
+	case string:
+		return "STRING"
+	case *Array:
+		return "ARRAY"
+	case *Hash:
+		return "HASH"
+	case *Function:
+		return "FUNCTION"
+	default:
+		return "NULL"
+	}
+}
+
+func inspect(value Value) string {
+	switch value := value.(type) {
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case float64:
+		out := strconv.FormatFloat(value, 'g', -1, 64)
+		if !strings.ContainsAny(out, ".eIN") {
+			out += ".0"
+		}
+		return out
+	case bool:
+		return strconv.FormatBool(value)
+	case string:
+		return value
+	case *Array:
+		elements := make([]string, len(value.Elements))
+		for i, element := range value.Elements {
+			elements[i] = inspect(element)
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	case *Hash:
+		pairs := make([]string, len(value.Keys))
+		for i, key := range value.Keys {
+			pairs[i] = inspect(key) + ": " + inspect(value.Pairs[key])
+		}
+		return "{" + strings.Join(pairs, ", ") + "}"
+	case *Function:
+		return value.Inspect
+	default:
+		return "null"
+	}
+}
+
+func truthy(value Value) bool {
+	switch value := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return value
+	default:
+		return true
+	}
+}
+
+func array(elements ...Value) Value {
+	return &Array{Elements: elements}
+}
+
+func hashable(key Value) bool {
+	switch key.(type) {
+	case int64, float64, bool, string:
+		return true
+	default:
+		return false
+	}
+}
+
+// hash returns the hash of keysAndValues, a key followed by its value for
+// each pair.
+func hash(keysAndValues ...Value) Value {
+	hash := &Hash{Pairs: make(map[Value]Value)}
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key := keysAndValues[i]
+		if !hashable(key) {
+			fail("unusable as hash key: %s", typeName(key))
+		}
+		if _, ok := hash.Pairs[key]; !ok {
+			hash.Keys = append(hash.Keys, key)
+		}
+		hash.Pairs[key] = keysAndValues[i+1]
+	}
+	return hash
+}
+
+func function(inspect string, fn func(args ...Value) Value) Value {
+	return &Function{Inspect: inspect, Fn: fn}
+}
+
+func argument(args []Value, index int) Value {
+	if index < len(args) {
+		return args[index]
+	}
+	return fail("wrong number of arguments. got=%d, want=%d", len(args), index+1)
+}
+
+func call(function Value, args ...Value) Value {
+	fn, ok := function.(*Function)
+	if !ok {
+		return fail("not a function: %s", typeName(function))
+	}
+	return fn.Fn(args...)
+}
+
+func assign(target *Value, value Value) Value {
+	*target = value
+	return value
+}
+
+// failAssigning fails with message once the value being assigned has been
+// evaluated.
+func failAssigning(value Value, message string) Value {
+	return fail("%s", message)
+}
+
+func not(value Value) Value {
+	return !truthy(value)
+}
+
+func negate(value Value) Value {
+	switch value := value.(type) {
+	case int64:
+		return -value
+	case float64:
+		return -value
+	default:
+		return fail("unknown operator: -%s", typeName(value))
+	}
+}
+
+func coalesce(left Value, right func() Value) Value {
+	if left != nil {
+		return left
+	}
+	return right()
+}
+
+func toFloat(value Value) (float64, bool) {
+	switch value := value.(type) {
+	case int64:
+		return float64(value), true
+	case float64:
+		return value, true
+	default:
+		return 0, false
+	}
+}
+
+func infix(operator string, left, right Value) Value {
+	leftInteger, leftIsInteger := left.(int64)
+	rightInteger, rightIsInteger := right.(int64)
+	leftFloat, leftIsNumber := toFloat(left)
+	rightFloat, rightIsNumber := toFloat(right)
+	leftString, leftIsString := left.(string)
+	rightString, rightIsString := right.(string)
+
+	switch {
+	case leftIsInteger && rightIsInteger:
+		switch operator {
+		case "+":
+			return leftInteger + rightInteger
+		case "-":
+			return leftInteger - rightInteger
+		case "*":
+			return leftInteger * rightInteger
+		case "/":
+			if rightInteger == 0 {
+				return fail("division by zero")
+			}
+			return leftInteger / rightInteger
+		case "**":
+			if rightInteger < 0 {
+				return fail("negative exponent: %d", rightInteger)
+			}
+			result := int64(1)
+			for base, exponent := leftInteger, rightInteger; exponent > 0; exponent >>= 1 {
+				if exponent&1 == 1 {
+					result *= base
+				}
+				base *= base
+			}
+			return result
+		case "<":
+			return leftInteger < rightInteger
+		case ">":
+			return leftInteger > rightInteger
+		case "==":
+			return leftInteger == rightInteger
+		case "!=":
+			return leftInteger != rightInteger
+		case "..", "..=":
+			end := rightInteger
+			if operator == "..=" {
+				end++
+			}
+			elements := []Value{}
+			for i := leftInteger; i < end; i++ {
+				elements = append(elements, i)
+			}
+			return &Array{Elements: elements}
+		}
+	case leftIsNumber && rightIsNumber:
+		switch operator {
+		case "+":
+			return leftFloat + rightFloat
+		case "-":
+			return leftFloat - rightFloat
+		case "*":
+			return leftFloat * rightFloat
+		case "/":
+			return leftFloat / rightFloat
+		case "**":
+			return math.Pow(leftFloat, rightFloat)
+		case "<":
+			return leftFloat < rightFloat
+		case ">":
+			return leftFloat > rightFloat
+		case "==":
+			return leftFloat == rightFloat
+		case "!=":
+			return leftFloat != rightFloat
+		}
+	case leftIsString && rightIsString:
+		if operator == "+" {
+			return leftString + rightString
+		}
+	case operator == "==":
+		return left == right
+	case operator == "!=":
+		return left != right
+	case typeName(left) != typeName(right):
+		return fail("type mismatch: %s %s %s", typeName(left), operator, typeName(right))
+	}
+	return fail("unknown operator: %s %s %s", typeName(left), operator, typeName(right))
+}
+
+func index(left, index Value) Value {
+	switch left := left.(type) {
+	case *Array:
+		i, ok := index.(int64)
+		if !ok {
+			break
+		}
+		if i < 0 || i >= int64(len(left.Elements)) {
+			return nil
+		}
+		return left.Elements[i]
+	case *Hash:
+		if !hashable(index) {
+			return fail("unusable as hash key: %s", typeName(index))
+		}
+		return left.Pairs[index]
+	}
+	return fail("index operator not supported: %s", typeName(left))
+}
+
+var builtins = map[string]Value{
+	"puts": function("builtin function", func(args ...Value) Value {
+		for _, arg := range args {
+			fmt.Println(inspect(arg))
+		}
+		return nil
+	}),
+	"len": function("builtin function", func(args ...Value) Value {
+		if len(args) != 1 {
+			return fail("wrong number of arguments. got=%d, want=1", len(args))
+		}
+		switch arg := args[0].(type) {
+		case *Array:
+			return int64(len(arg.Elements))
+		case string:
+			return int64(utf8.RuneCountInString(arg))
+		case *Hash:
+			return int64(len(arg.Pairs))
+		default:
+			return fail("argument to `len` not supported, got %s", typeName(arg))
+		}
+	}),
+	"first": function("builtin function", func(args ...Value) Value {
+		elements := arrayArgument("first", args)
+		if len(elements) > 0 {
+			return elements[0]
+		}
+		return nil
+	}),
+	"last": function("builtin function", func(args ...Value) Value {
+		elements := arrayArgument("last", args)
+		if len(elements) > 0 {
+			return elements[len(elements)-1]
+		}
+		return nil
+	}),
+	"rest": function("builtin function", func(args ...Value) Value {
+		elements := arrayArgument("rest", args)
+		if len(elements) > 0 {
+			return &Array{Elements: append([]Value{}, elements[1:]...)}
+		}
+		return nil
+	}),
+	"push": function("builtin function", func(args ...Value) Value {
+		if len(args) != 2 {
+			return fail("wrong number of arguments. got=%d, want=2", len(args))
+		}
+		elements := arrayArgument("push", args[:1])
+		return &Array{Elements: append(append([]Value{}, elements...), args[1])}
+	}),
+	"str": function("builtin function", func(args ...Value) Value {
+		if len(args) != 1 {
+			return fail("wrong number of arguments. got=%d, want=1", len(args))
+		}
+		return inspect(args[0])
+	}),
+	"exit": function("builtin function", func(args ...Value) Value {
+		code := int64(0)
+		if len(args) > 0 {
+			code, _ = args[0].(int64)
+		}
+		panic(exitCode(code))
+	}),
+}
+
+func arrayArgument(name string, args []Value) []Value {
+	if len(args) != 1 {
+		fail("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	array, ok := args[0].(*Array)
+	if !ok {
+		fail("argument to `%s` must be ARRAY, got %s", name, typeName(args[0]))
+	}
+	return array.Elements
+}
+
+func builtin(name string) Value {
+	if builtin, ok := builtins[name]; ok {
+		return builtin
+	}
+	return fail("identifier not found: %s", name)
+}
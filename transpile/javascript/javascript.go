@@ -0,0 +1,424 @@
+// Package javascript transpiles Monkey programs into JavaScript modules, so
+// scripts can run in browsers and under Node.js.
+//
+// The generated module holds a small runtime for Monkey's values and the
+// builtins puts, len, first, last, rest, push, str and exit, and exports the
+// program's top-level bindings. Programs using anything else, like try,
+// defer or hash destructuring, are reported as unsupported rather than
+// transpiled.
+package javascript
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"monkey/ast"
+	"monkey/transpile"
+	"strconv"
+	"strings"
+)
+
+//go:embed runtime.js
+var runtime string
+
+// Transpile returns the source of a JavaScript module doing what program
+// does when it's imported.
+func Transpile(program *ast.Program) (string, error) {
+	transpiler := &transpiler{out: &strings.Builder{}}
+	transpiler.scopes = []*transpile.Scope{transpile.NewScope(nil, program)}
+
+	transpiler.line("// Code generated by monkey from a Monkey program. DO NOT EDIT.")
+	transpiler.line("")
+	transpiler.out.WriteString(runtime)
+	transpiler.line("")
+	transpiler.declare()
+	transpiler.line("program: try {")
+	transpiler.indent++
+	transpiler.statements(program.Statements, discard, "")
+	transpiler.indent--
+	transpiler.line("} catch (error) {")
+	transpiler.line("  report(error);")
+	transpiler.line("}")
+
+	exports := []string{}
+	for _, name := range transpiler.scopes[0].Names {
+		exports = append(exports, fmt.Sprintf("%s as %s", variable(name), name))
+	}
+	if len(exports) != 0 {
+		transpiler.line("")
+		transpiler.line("export { %s };", strings.Join(exports, ", "))
+	}
+
+	if transpiler.err != nil {
+		return "", transpiler.err
+	}
+	return transpiler.out.String(), nil
+}
+
+// A mode is what's done with the value of the statements being written.
+type mode int
+
+const (
+	discard  mode = iota // The value is dropped
+	returned             // The value is returned from the enclosing function
+	assigned             // The value is assigned to a target variable
+)
+
+// A loop is a while or do-while loop being written, with the Monkey label
+// breaks and continues can target it by and the variable a break's value
+// goes to.
+type loop struct {
+	label  string
+	target string
+}
+
+type transpiler struct {
+	out       *strings.Builder
+	indent    int
+	scopes    []*transpile.Scope
+	loops     []loop
+	functions int // How many functions the code being written is nested in
+	temps     int
+	err       error
+}
+
+// line writes a line of code at the current indentation.
+func (transpiler *transpiler) line(format string, a ...interface{}) {
+	if format != "" {
+		transpiler.out.WriteString(strings.Repeat("  ", transpiler.indent))
+	}
+	fmt.Fprintf(transpiler.out, format, a...)
+	transpiler.out.WriteString("\n")
+}
+
+func (transpiler *transpiler) unsupported(node ast.Node) string {
+	if transpiler.err == nil {
+		transpiler.err = fmt.Errorf("javascript: %s is not supported: %s", strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast."), node)
+	}
+	return "null"
+}
+
+func (transpiler *transpiler) temp() string {
+	transpiler.temps++
+	return fmt.Sprintf("t%d", transpiler.temps)
+}
+
+// declare declares the variables for the names bound in the innermost scope.
+func (transpiler *transpiler) declare() {
+	for _, name := range transpiler.scopes[len(transpiler.scopes)-1].Names {
+		transpiler.line("let %s = null;", variable(name))
+	}
+}
+
+// resolve returns the scope name is bound in, reporting false for the names
+// of builtins.
+func (transpiler *transpiler) resolve(name string) (*transpile.Scope, bool) {
+	for i := len(transpiler.scopes) - 1; i >= 0; i-- {
+		if transpiler.scopes[i].Binds(name) {
+			return transpiler.scopes[i], true
+		}
+	}
+	return nil, false
+}
+
+// variable returns the JavaScript variable for name, prefixed so it can't
+// be a reserved word or clash with the runtime.
+func variable(name string) string {
+	return "m_" + name
+}
+
+// loop returns the loop targeted by a break or continue with label, which
+// is nil for the innermost loop.
+func (transpiler *transpiler) loop(label *ast.Identifier) (loop, bool) {
+	for i := len(transpiler.loops) - 1; i >= 0; i-- {
+		if label == nil || transpiler.loops[i].label == label.Value {
+			return transpiler.loops[i], true
+		}
+	}
+	return loop{}, false
+}
+
+func label(name *ast.Identifier) string {
+	if name == nil {
+		return ""
+	}
+	return " l_" + name.Value
+}
+
+func (transpiler *transpiler) statements(statements []ast.Statement, mode mode, target string) {
+	for i, statement := range statements {
+		if i < len(statements)-1 {
+			transpiler.statement(statement, discard, "")
+		} else {
+			transpiler.statement(statement, mode, target)
+		}
+	}
+	if len(statements) == 0 && mode != discard {
+		transpiler.result("null", mode, target)
+	}
+}
+
+// result writes what's done with value in mode.
+func (transpiler *transpiler) result(value string, mode mode, target string) {
+	switch mode {
+	case discard:
+		transpiler.line("%s;", value)
+	case returned:
+		transpiler.line("return %s;", value)
+	case assigned:
+		transpiler.line("%s = %s;", target, value)
+	}
+}
+
+func (transpiler *transpiler) statement(statement ast.Statement, mode mode, target string) {
+	switch statement := statement.(type) {
+	case *ast.ExpressionStatement:
+		transpiler.valued(statement.Expression, mode, target)
+
+	case *ast.LetStatement:
+		transpiler.valued(statement.Value, assigned, variable(statement.Name.Value))
+		if mode != discard {
+			transpiler.result("null", mode, target)
+		}
+
+	case *ast.ReturnStatement:
+		value := "null"
+		if statement.ReturnValue != nil {
+			value = transpiler.expression(statement.ReturnValue)
+		}
+		if transpiler.functions == 0 {
+			// Returning from the program ends it.
+			transpiler.line("%s;", value)
+			transpiler.line("break program;")
+		} else {
+			transpiler.line("return %s;", value)
+		}
+
+	case *ast.BreakStatement:
+		loop, ok := transpiler.loop(statement.Label)
+		if !ok {
+			transpiler.unsupported(statement)
+			return
+		}
+		if statement.Value != nil {
+			if loop.target != "" {
+				transpiler.line("%s = %s;", loop.target, transpiler.expression(statement.Value))
+			} else {
+				transpiler.line("%s;", transpiler.expression(statement.Value))
+			}
+		}
+		transpiler.line("break%s;", label(statement.Label))
+
+	case *ast.ContinueStatement:
+		if _, ok := transpiler.loop(statement.Label); !ok {
+			transpiler.unsupported(statement)
+			return
+		}
+		transpiler.line("continue%s;", label(statement.Label))
+
+	default:
+		transpiler.unsupported(statement)
+	}
+}
+
+// valued writes expression as a statement, doing what mode says with its
+// value. If expressions and loops are written as JavaScript statements, so
+// the returns, breaks and continues in them work.
+func (transpiler *transpiler) valued(expression ast.Expression, mode mode, target string) {
+	switch expression := expression.(type) {
+	case *ast.IfExpression:
+		transpiler.line("if (truthy(%s)) {", transpiler.expression(expression.Condition))
+		transpiler.indent++
+		transpiler.statements(expression.Consequence.Statements, mode, target)
+		transpiler.indent--
+		if expression.Alternative != nil || mode != discard {
+			transpiler.line("} else {")
+			transpiler.indent++
+			if expression.Alternative != nil {
+				transpiler.statements(expression.Alternative.Statements, mode, target)
+			} else {
+				transpiler.result("null", mode, target)
+			}
+			transpiler.indent--
+		}
+		transpiler.line("}")
+
+	case *ast.DoWhileExpression, *ast.WhileExpression:
+		// A loop that isn't broken out of with a value evaluates to null.
+		result := target
+		switch mode {
+		case returned:
+			result = transpiler.temp()
+			transpiler.line("let %s = null;", result)
+		case assigned:
+			transpiler.line("%s = null;", result)
+		}
+
+		label, condition, body, pretest := transpile.Loop(expression)
+		name, prefix := "", ""
+		if label != nil {
+			name, prefix = label.Value, "l_"+label.Value+": "
+		}
+		transpiler.loops = append(transpiler.loops, loop{label: name, target: result})
+		if pretest {
+			transpiler.line("%swhile (truthy(%s)) {", prefix, transpiler.expression(condition))
+		} else {
+			transpiler.line("%sdo {", prefix)
+		}
+		transpiler.indent++
+		transpiler.statements(body.Statements, discard, "")
+		transpiler.indent--
+		if pretest {
+			transpiler.line("}")
+		} else {
+			transpiler.line("} while (truthy(%s));", transpiler.expression(condition))
+		}
+		transpiler.loops = transpiler.loops[:len(transpiler.loops)-1]
+
+		if mode == returned {
+			transpiler.line("return %s;", result)
+		}
+
+	default:
+		transpiler.result(transpiler.expression(expression), mode, target)
+	}
+}
+
+// nested returns the code write writes, indented one level deeper than the
+// current line, without its first line's indentation.
+func (transpiler *transpiler) nested(write func()) string {
+	out := transpiler.out
+	transpiler.out = &strings.Builder{}
+	write()
+	written := strings.TrimLeft(transpiler.out.String(), " ")
+	transpiler.out = out
+	return strings.TrimSuffix(written, "\n")
+}
+
+// expression returns the JavaScript expression for expression.
+func (transpiler *transpiler) expression(expression ast.Expression) string {
+	switch expression := expression.(type) {
+	case *ast.IntegerLiteral:
+		return fmt.Sprintf("%dn", expression.Value)
+	case *ast.FloatLiteral:
+		return strconv.FormatFloat(expression.Value, 'g', -1, 64)
+	case *ast.Boolean:
+		return strconv.FormatBool(expression.Value)
+	case *ast.StringLiteral:
+		quoted, _ := json.Marshal(expression.Value)
+		return string(quoted)
+
+	case *ast.Identifier:
+		if _, ok := transpiler.resolve(expression.Value); ok {
+			return variable(expression.Value)
+		}
+		return fmt.Sprintf("builtin(%q)", expression.Value)
+
+	case *ast.PrefixExpression:
+		switch expression.Operator {
+		case "!":
+			return fmt.Sprintf("not(%s)", transpiler.expression(expression.Right))
+		case "-":
+			return fmt.Sprintf("negate(%s)", transpiler.expression(expression.Right))
+		}
+
+	case *ast.InfixExpression:
+		left, right := transpiler.expression(expression.Left), transpiler.expression(expression.Right)
+		switch expression.Operator {
+		case "+", "-", "*", "/", "**", "<", ">", "==", "!=", "..", "..=":
+			return fmt.Sprintf("infix(%q, %s, %s)", expression.Operator, left, right)
+		case "??":
+			return fmt.Sprintf("coalesce(%s, () => %s)", left, right)
+		}
+
+	case *ast.AssignExpression:
+		value := transpiler.expression(expression.Value)
+		name := expression.Name.Value
+		scope, ok := transpiler.resolve(name)
+		switch {
+		case !ok:
+			return fmt.Sprintf("failAssigning(%s, %q)", value, fmt.Sprintf("identifier not found: %s at %d:%d",
+				name, expression.Token.Line, expression.Token.Column))
+		case !scope.Mutable[name]:
+			return fmt.Sprintf("failAssigning(%s, %q)", value, fmt.Sprintf("cannot assign to immutable binding %s at %d:%d",
+				name, expression.Token.Line, expression.Token.Column))
+		}
+		return fmt.Sprintf("(%s = %s)", variable(name), value)
+
+	case *ast.CallExpression:
+		arguments := []string{transpiler.expression(expression.Function)}
+		for _, argument := range expression.Arguments {
+			arguments = append(arguments, transpiler.expression(argument))
+		}
+		return fmt.Sprintf("call(%s)", strings.Join(arguments, ", "))
+
+	case *ast.IndexExpression:
+		return fmt.Sprintf("index(%s, %s)", transpiler.expression(expression.Left), transpiler.expression(expression.Index))
+
+	case *ast.ArrayLiteral:
+		elements := []string{}
+		for _, element := range expression.Elements {
+			elements = append(elements, transpiler.expression(element))
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+
+	case *ast.HashLiteral:
+		pairs := []string{}
+		for _, key := range expression.Keys() {
+			pairs = append(pairs, transpiler.expression(key), transpiler.expression(expression.Pairs[key]))
+		}
+		return fmt.Sprintf("hash(%s)", strings.Join(pairs, ", "))
+
+	case *ast.FunctionLiteral:
+		return transpiler.function(expression)
+
+	case *ast.IfExpression, *ast.DoWhileExpression, *ast.WhileExpression:
+		if transpile.Escapes(expression) {
+			break
+		}
+		// The loops outside can't be broken out of from the function the
+		// expression is written in.
+		loops := transpiler.loops
+		transpiler.loops = nil
+		defer func() { transpiler.loops = loops }()
+		return transpiler.nested(func() {
+			transpiler.line("(() => {")
+			transpiler.indent++
+			transpiler.valued(expression, returned, "")
+			transpiler.indent--
+			transpiler.line("})()")
+		})
+	}
+
+	return transpiler.unsupported(expression)
+}
+
+func (transpiler *transpiler) function(literal *ast.FunctionLiteral) string {
+	// Functions are inspected the same way as in the interpreter.
+	params := []string{}
+	for _, parameter := range literal.Parameters {
+		params = append(params, parameter.String())
+	}
+	inspect, _ := json.Marshal(fmt.Sprintf("fn(%s) {\n%s\n}", strings.Join(params, ", "), literal.Body.String()))
+
+	loops := transpiler.loops
+	transpiler.loops = nil
+	defer func() { transpiler.loops = loops }()
+	return transpiler.nested(func() {
+		transpiler.line("fn(%s, (...args) => {", inspect)
+		transpiler.indent++
+		transpiler.functions++
+		transpiler.scopes = append(transpiler.scopes, transpile.NewScope(literal.Parameters, literal.Body))
+
+		for i, parameter := range literal.Parameters {
+			transpiler.line("let %s = argument(args, %d);", variable(parameter.Value), i)
+		}
+		transpiler.declare()
+		transpiler.statements(literal.Body.Statements, returned, "")
+
+		transpiler.scopes = transpiler.scopes[:len(transpiler.scopes)-1]
+		transpiler.functions--
+		transpiler.indent--
+		transpiler.line("})")
+	})
+}
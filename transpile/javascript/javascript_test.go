@@ -0,0 +1,164 @@
+package javascript
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Diagnostics())
+	}
+	return program
+}
+
+// run transpiles input into a module in dir and returns what running it with
+// Node.js prints.
+func run(t *testing.T, dir, name, input string) (string, bool) {
+	source, err := Transpile(parse(t, input))
+	if err != nil {
+		t.Errorf("transpiling %q failed: %s", input, err)
+		return "", false
+	}
+
+	path := filepath.Join(dir, name+".mjs")
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output, _ := exec.Command("node", path).CombinedOutput()
+	return string(output), true
+}
+
+func TestTranspile(t *testing.T) {
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skip("node isn't installed")
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`var i = 0;
+var count = 0;
+outer: while (true) {
+	i = i + 1;
+	var j = 0;
+	while (j < 5) {
+		j = j + 1;
+		if (j == 3) { continue outer; }
+		if (i == 3) { break outer; }
+		count = count + 1;
+	}
+}
+var n = 0;
+while (false) { n = 1; }
+puts(count, n)`,
+			"4\n0\n",
+		},
+		{"var k = 0; puts(while (true) { k = k + 1; if (k == 4) { break k * 10; } })", "40\n"},
+		{
+			"let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) }; puts(fib(15))",
+			"610\n",
+		},
+		{
+			`let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } };
+let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } };
+let make = fn() { var count = 0; fn() { count = count + 1 } };
+let counter = make();
+counter();
+puts(even(10), odd(4), counter(), make)`,
+			"true\nfalse\n2\nfn() {\nvar count = 0;fn()(count = (count + 1))\n}\n",
+		},
+		{
+			`var i = 0;
+outer: do {
+	do { i = i + 1; if (i > 3) { break outer; } continue; } while (true);
+} while (true);
+let v = do { break i * 2; } while (true);
+let w = if (v > 5) { "big" } else { "small" };
+puts(i, v, w, 1 .. 4, 2 ** 10, 7 / 2, 1.5 * 2, 1000000.0, -(3), !true, first([]) ?? "none")`,
+			"4\n8\nbig\n[1, 2, 3]\n1024\n3\n3.0\n1e+06\n-3\nfalse\nnone\n",
+		},
+		{
+			`let h = {"b": 1, "a": [1, 2.5, "x"], true: len};
+puts(h, h["a"][1], h["a"][5], h[true]("héllo"), push(rest([1, 2, 3]), 4), str(h["b"]) + "!")`,
+			"{b: 1, a: [1, 2.5, x], true: builtin function}\n2.5\nnull\n5\n[2, 3, 4]\n1!\n",
+		},
+		{"puts(9223372036854775807 + 1); return 1; puts(2)", "-9223372036854775808\n"},
+	}
+
+	dir := t.TempDir()
+	for i, test := range tests {
+		output, ok := run(t, dir, "main"+strings.Repeat("_", i), test.input)
+		if ok && output != test.expected {
+			t.Errorf("wrong output for %q. expected=%q, got=%q", test.input, test.expected, output)
+		}
+	}
+}
+
+func TestTranspileErrors(t *testing.T) {
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skip("node isn't installed")
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 1; x = 2", "ERROR: cannot assign to immutable binding x at 1:14\n"},
+		{"puts(1 + true)", "ERROR: type mismatch: INTEGER + BOOLEAN\n"},
+		{`"a" == "a"`, "ERROR: unknown operator: STRING == STRING\n"},
+		{"nope(1)", "ERROR: identifier not found: nope\n"},
+		{"{[1]: 2}", "ERROR: unusable as hash key: ARRAY\n"},
+		{"let zero = 0; puts(1 / zero)", "ERROR: division by zero\n"},
+	}
+
+	dir := t.TempDir()
+	for i, test := range tests {
+		output, ok := run(t, dir, "main"+strings.Repeat("_", i), test.input)
+		if ok && output != test.expected {
+			t.Errorf("wrong output for %q. expected=%q, got=%q", test.input, test.expected, output)
+		}
+	}
+}
+
+func TestTranspileExports(t *testing.T) {
+	source, err := Transpile(parse(t, "let answer = 42; var count = 0; let f = fn() { let inner = 1; inner };"))
+	if err != nil {
+		t.Fatalf("transpiling failed: %s", err)
+	}
+
+	expected := "export { m_answer as answer, m_count as count, m_f as f };\n"
+	if !strings.HasSuffix(source, expected) {
+		t.Errorf("wrong exports. expected suffix %q, got=%q", expected, source[strings.LastIndex(source, "\n\n"):])
+	}
+}
+
+func TestTranspileUnsupported(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`try { 1 } catch (e) { 2 }`, "javascript: TryExpression is not supported"},
+		{"let {a} = {};", "javascript: HashLetStatement is not supported"},
+		{"fn() { defer puts(1); }", "javascript: DeferStatement is not supported"},
+		{`1 in [1]`, "javascript: InfixExpression is not supported"},
+		{"let f = fn(x) { puts(if (x) { return 1; }) }", "javascript: IfExpression is not supported"},
+	}
+
+	for _, test := range tests {
+		_, err := Transpile(parse(t, test.input))
+		if err == nil || !strings.HasPrefix(err.Error(), test.expected) {
+			t.Errorf("wrong error for %q. expected=%q, got=%v", test.input, test.expected, err)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+// The runtime the JavaScript modules generated from Monkey programs are
+// built on. Integers are BigInts, floats are numbers, arrays are arrays,
+// hashes are Maps, which keep insertion order, and null is null.
+
+class MonkeyError extends Error {}
+
+class MonkeyExit {
+  constructor(code) {
+    this.code = code;
+  }
+}
+
+function fail(message) {
+  throw new MonkeyError(message);
+}
+
+function report(error) {
+  if (error instanceof MonkeyExit) {
+    if (typeof process !== "undefined") process.exitCode = Number(error.code);
+    return;
+  }
+  if (!(error instanceof MonkeyError)) throw error;
+  console.error("ERROR: " + error.message);
+  if (typeof process !== "undefined") process.exitCode = 1;
+}
+
+function typeName(value) {
+  switch (typeof value) {
+    case "bigint":
+      return "INTEGER";
+    case "number":
+      return "FLOAT";
+    case "boolean":
+      return "BOOLEAN";
+    case "string":
+      return "STRING";
+    case "function":
+      return "FUNCTION";
+  }
+  if (Array.isArray(value)) return "ARRAY";
+  if (value instanceof Map) return "HASH";
+  return "NULL";
+}
+
+// formatFloat formats value like Go's strconv.FormatFloat(value, 'g', -1, 64),
+// which the interpreter inspects floats with.
+function formatFloat(value) {
+  if (Number.isNaN(value)) return "NaN";
+  if (!Number.isFinite(value)) return value > 0 ? "+Inf" : "-Inf";
+  if (Object.is(value, -0)) return "-0";
+
+  const [mantissa, exponent] = value.toExponential().split("e");
+  const power = Number(exponent);
+  if (power < -4 || power >= 6) {
+    return mantissa + "e" + (power < 0 ? "-" : "+") + String(Math.abs(power)).padStart(2, "0");
+  }
+  return String(value);
+}
+
+function inspect(value) {
+  switch (typeName(value)) {
+    case "INTEGER":
+    case "BOOLEAN":
+    case "STRING":
+      return String(value);
+    case "FLOAT": {
+      const out = formatFloat(value);
+      return /[.eIN]/.test(out) ? out : out + ".0";
+    }
+    case "FUNCTION":
+      return value.inspect;
+    case "ARRAY":
+      return "[" + value.map(inspect).join(", ") + "]";
+    case "HASH":
+      return "{" + [...value].map(([key, value]) => inspect(key) + ": " + inspect(value)).join(", ") + "}";
+    default:
+      return "null";
+  }
+}
+
+function truthy(value) {
+  return value !== null && value !== false;
+}
+
+function hashable(key) {
+  return ["bigint", "number", "boolean", "string"].includes(typeof key);
+}
+
+// hash returns the hash of keysAndValues, a key followed by its value for
+// each pair.
+function hash(...keysAndValues) {
+  const hash = new Map();
+  for (let i = 0; i < keysAndValues.length; i += 2) {
+    const key = keysAndValues[i];
+    if (!hashable(key)) fail("unusable as hash key: " + typeName(key));
+    hash.set(key, keysAndValues[i + 1]);
+  }
+  return hash;
+}
+
+function fn(inspect, body) {
+  body.inspect = inspect;
+  return body;
+}
+
+function argument(args, index) {
+  if (index < args.length) return args[index];
+  return fail("wrong number of arguments. got=" + args.length + ", want=" + (index + 1));
+}
+
+function call(fn, ...args) {
+  if (typeof fn !== "function") return fail("not a function: " + typeName(fn));
+  return fn(...args);
+}
+
+// failAssigning fails with message once the value being assigned has been
+// evaluated.
+function failAssigning(value, message) {
+  return fail(message);
+}
+
+function not(value) {
+  return !truthy(value);
+}
+
+function negate(value) {
+  switch (typeof value) {
+    case "bigint":
+      return BigInt.asIntN(64, -value);
+    case "number":
+      return -value;
+  }
+  return fail("unknown operator: -" + typeName(value));
+}
+
+function coalesce(left, right) {
+  return left !== null ? left : right();
+}
+
+function isNumber(value) {
+  return typeof value === "bigint" || typeof value === "number";
+}
+
+function infix(operator, left, right) {
+  if (typeof left === "bigint" && typeof right === "bigint") {
+    switch (operator) {
+      case "+":
+        return BigInt.asIntN(64, left + right);
+      case "-":
+        return BigInt.asIntN(64, left - right);
+      case "*":
+        return BigInt.asIntN(64, left * right);
+      case "/":
+        if (right === 0n) return fail("division by zero");
+        return BigInt.asIntN(64, left / right);
+      case "**":
+        if (right < 0n) return fail("negative exponent: " + right);
+        return BigInt.asIntN(64, left ** right);
+      case "<":
+        return left < right;
+      case ">":
+        return left > right;
+      case "==":
+        return left === right;
+      case "!=":
+        return left !== right;
+      case "..":
+      case "..=": {
+        const end = operator === "..=" ? right + 1n : right;
+        const elements = [];
+        for (let i = left; i < end; i++) elements.push(i);
+        return elements;
+      }
+    }
+  } else if (isNumber(left) && isNumber(right)) {
+    const [l, r] = [Number(left), Number(right)];
+    switch (operator) {
+      case "+":
+        return l + r;
+      case "-":
+        return l - r;
+      case "*":
+        return l * r;
+      case "/":
+        return l / r;
+      case "**":
+        return l ** r;
+      case "<":
+        return l < r;
+      case ">":
+        return l > r;
+      case "==":
+        return l === r;
+      case "!=":
+        return l !== r;
+    }
+  } else if (typeof left === "string" && typeof right === "string") {
+    if (operator === "+") return left + right;
+  } else if (operator === "==") {
+    return left === right;
+  } else if (operator === "!=") {
+    return left !== right;
+  } else if (typeName(left) !== typeName(right)) {
+    return fail("type mismatch: " + typeName(left) + " " + operator + " " + typeName(right));
+  }
+  return fail("unknown operator: " + typeName(left) + " " + operator + " " + typeName(right));
+}
+
+function index(left, index) {
+  if (Array.isArray(left) && typeof index === "bigint") {
+    return index >= 0n && index < BigInt(left.length) ? left[Number(index)] : null;
+  }
+  if (left instanceof Map) {
+    if (!hashable(index)) return fail("unusable as hash key: " + typeName(index));
+    return left.has(index) ? left.get(index) : null;
+  }
+  return fail("index operator not supported: " + typeName(left));
+}
+
+function arrayArgument(name, args) {
+  if (args.length !== 1) fail("wrong number of arguments. got=" + args.length + ", want=1");
+  if (!Array.isArray(args[0])) fail("argument to `" + name + "` must be ARRAY, got " + typeName(args[0]));
+  return args[0];
+}
+
+const builtins = {
+  puts: fn("builtin function", (...args) => {
+    args.forEach((arg) => console.log(inspect(arg)));
+    return null;
+  }),
+  len: fn("builtin function", (...args) => {
+    if (args.length !== 1) return fail("wrong number of arguments. got=" + args.length + ", want=1");
+    const [arg] = args;
+    if (Array.isArray(arg)) return BigInt(arg.length);
+    if (typeof arg === "string") return BigInt([...arg].length);
+    if (arg instanceof Map) return BigInt(arg.size);
+    return fail("argument to `len` not supported, got " + typeName(arg));
+  }),
+  first: fn("builtin function", (...args) => {
+    const elements = arrayArgument("first", args);
+    return elements.length > 0 ? elements[0] : null;
+  }),
+  last: fn("builtin function", (...args) => {
+    const elements = arrayArgument("last", args);
+    return elements.length > 0 ? elements[elements.length - 1] : null;
+  }),
+  rest: fn("builtin function", (...args) => {
+    const elements = arrayArgument("rest", args);
+    return elements.length > 0 ? elements.slice(1) : null;
+  }),
+  push: fn("builtin function", (...args) => {
+    if (args.length !== 2) return fail("wrong number of arguments. got=" + args.length + ", want=2");
+    return [...arrayArgument("push", args.slice(0, 1)), args[1]];
+  }),
+  str: fn("builtin function", (...args) => {
+    if (args.length !== 1) return fail("wrong number of arguments. got=" + args.length + ", want=1");
+    return inspect(args[0]);
+  }),
+  exit: fn("builtin function", (...args) => {
+    throw new MonkeyExit(args.length > 0 && typeof args[0] === "bigint" ? args[0] : 0n);
+  }),
+};
+
+function builtin(name) {
+  if (Object.hasOwn(builtins, name)) return builtins[name];
+  return fail("identifier not found: " + name);
+}
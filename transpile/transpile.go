@@ -0,0 +1,96 @@
+// Package transpile holds what the backends turning Monkey programs into
+// source in other languages share. The backends are in its subpackages.
+package transpile
+
+import (
+	"monkey/ast"
+	"monkey/token"
+)
+
+// A Scope holds the names bound in a function body or the whole program,
+// leaving out those bound in nested functions. Since bindings are visible
+// throughout their function, backends declare them all at its start.
+type Scope struct {
+	Names   []string        // Names bound with let or var, without the parameters
+	Mutable map[string]bool // Whether each name, parameters included, is bound with var
+}
+
+// NewScope returns the scope of a function taking parameters with body, or
+// of a program when parameters is nil.
+func NewScope(parameters []*ast.Identifier, body ast.Node) *Scope {
+	scope := &Scope{Mutable: make(map[string]bool)}
+	for _, parameter := range parameters {
+		scope.Mutable[parameter.Value] = false
+	}
+
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.LetStatement:
+			mutable, bound := scope.Mutable[node.Name.Value]
+			if !bound {
+				scope.Names = append(scope.Names, node.Name.Value)
+			}
+			scope.Mutable[node.Name.Value] = mutable || node.Token.Type == token.VAR
+		}
+		return true
+	})
+	return scope
+}
+
+// Binds reports whether name is bound in scope.
+func (scope *Scope) Binds(name string) bool {
+	_, ok := scope.Mutable[name]
+	return ok
+}
+
+// Escapes reports whether node contains a return, or a break or continue for
+// a loop outside of it. Backends writing an if or loop used as a value as a
+// function that's called right away can't do so for those, since the
+// function would be what's returned from or broken out of.
+func Escapes(node ast.Node) bool {
+	return escapes(node, false, map[string]bool{})
+}
+
+func escapes(node ast.Node, inLoop bool, labels map[string]bool) bool {
+	found := false
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.ReturnStatement:
+			found = true
+		case *ast.BreakStatement:
+			found = found || node.Label == nil && !inLoop || node.Label != nil && !labels[node.Label.Value]
+		case *ast.ContinueStatement:
+			found = found || node.Label == nil && !inLoop || node.Label != nil && !labels[node.Label.Value]
+		case *ast.DoWhileExpression, *ast.WhileExpression:
+			label, condition, body, _ := Loop(node.(ast.Expression))
+			inner := map[string]bool{}
+			for name := range labels {
+				inner[name] = true
+			}
+			if label != nil {
+				inner[label.Value] = true
+			}
+			found = found || escapes(body, true, inner) || escapes(condition, inLoop, labels)
+			return false
+		}
+		return !found
+	})
+	return found
+}
+
+// Loop returns the parts of a do-while or while loop, with pretest set for a
+// while loop, which checks its condition before the first iteration.
+func Loop(loop ast.Expression) (label *ast.Identifier, condition ast.Expression, body *ast.BlockStatement, pretest bool) {
+	switch loop := loop.(type) {
+	case *ast.DoWhileExpression:
+		return loop.Label, loop.Condition, loop.Body, false
+	case *ast.WhileExpression:
+		return loop.Label, loop.Condition, loop.Body, true
+	default:
+		return nil, nil, nil, false
+	}
+}
@@ -0,0 +1,202 @@
+package wasm
+
+import "bytes"
+
+// The WebAssembly value types Monkey's integers and booleans are held in.
+const (
+	i32 byte = 0x7f
+	i64 byte = 0x7e
+)
+
+// The instructions the compiler writes.
+const (
+	opUnreachable byte = 0x00
+	opBlock       byte = 0x02
+	opLoop        byte = 0x03
+	opIf          byte = 0x04
+	opElse        byte = 0x05
+	opEnd         byte = 0x0b
+	opBr          byte = 0x0c
+	opBrIf        byte = 0x0d
+	opReturn      byte = 0x0f
+	opCall        byte = 0x10
+	opDrop        byte = 0x1a
+	opLocalGet    byte = 0x20
+	opLocalSet    byte = 0x21
+	opLocalTee    byte = 0x22
+	opGlobalGet   byte = 0x23
+	opGlobalSet   byte = 0x24
+	opI32Const    byte = 0x41
+	opI64Const    byte = 0x42
+	opI32Eqz      byte = 0x45
+	opI32Eq       byte = 0x46
+	opI32Ne       byte = 0x47
+	opI64Eq       byte = 0x51
+	opI64Ne       byte = 0x52
+	opI64LtS      byte = 0x53
+	opI64GtS      byte = 0x55
+	opI64Add      byte = 0x7c
+	opI64Sub      byte = 0x7d
+	opI64Mul      byte = 0x7e
+	opI64DivS     byte = 0x7f
+
+	blockEmpty byte = 0x40
+)
+
+// A signature is the parameter and result types of a function.
+type signature struct {
+	params  []byte
+	results []byte
+}
+
+// An imported function is one the host provides, by module and field name.
+type imported struct {
+	module, field string
+	signature     signature
+}
+
+// A function is one defined in the module, with the types of its locals
+// beyond the parameters and its code, the final end left out.
+type function struct {
+	name      string
+	signature signature
+	locals    []byte
+	code      bytes.Buffer
+	exported  bool
+}
+
+// A module is what's encoded into a WebAssembly binary.
+type module struct {
+	imports   []imported
+	functions []*function
+	globals   []byte
+}
+
+// encode returns the binary encoding of module.
+func (module *module) encode() []byte {
+	var types []signature
+	typeIndex := func(s signature) uint64 {
+		for i, t := range types {
+			if bytes.Equal(t.params, s.params) && bytes.Equal(t.results, s.results) {
+				return uint64(i)
+			}
+		}
+		types = append(types, s)
+		return uint64(len(types) - 1)
+	}
+
+	var imports, functions, globals, exports, code bytes.Buffer
+
+	writeUnsigned(&imports, uint64(len(module.imports)))
+	for _, imported := range module.imports {
+		writeName(&imports, imported.module)
+		writeName(&imports, imported.field)
+		imports.WriteByte(0x00)
+		writeUnsigned(&imports, typeIndex(imported.signature))
+	}
+
+	writeUnsigned(&functions, uint64(len(module.functions)))
+	writeUnsigned(&code, uint64(len(module.functions)))
+	exported := 0
+	for _, function := range module.functions {
+		writeUnsigned(&functions, typeIndex(function.signature))
+		if function.exported {
+			exported++
+		}
+
+		var body bytes.Buffer
+		writeUnsigned(&body, uint64(len(function.locals)))
+		for _, local := range function.locals {
+			writeUnsigned(&body, 1)
+			body.WriteByte(local)
+		}
+		body.Write(function.code.Bytes())
+		body.WriteByte(opEnd)
+		writeUnsigned(&code, uint64(body.Len()))
+		code.Write(body.Bytes())
+	}
+
+	writeUnsigned(&globals, uint64(len(module.globals)))
+	for _, global := range module.globals {
+		globals.WriteByte(global)
+		globals.WriteByte(0x01)
+		if global == i64 {
+			globals.WriteByte(opI64Const)
+		} else {
+			globals.WriteByte(opI32Const)
+		}
+		globals.WriteByte(0x00)
+		globals.WriteByte(opEnd)
+	}
+
+	writeUnsigned(&exports, uint64(exported))
+	for i, function := range module.functions {
+		if function.exported {
+			writeName(&exports, function.name)
+			exports.WriteByte(0x00)
+			writeUnsigned(&exports, uint64(len(module.imports)+i))
+		}
+	}
+
+	var encodedTypes bytes.Buffer
+	writeUnsigned(&encodedTypes, uint64(len(types)))
+	for _, t := range types {
+		encodedTypes.WriteByte(0x60)
+		writeUnsigned(&encodedTypes, uint64(len(t.params)))
+		encodedTypes.Write(t.params)
+		writeUnsigned(&encodedTypes, uint64(len(t.results)))
+		encodedTypes.Write(t.results)
+	}
+
+	var out bytes.Buffer
+	out.Write([]byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00})
+	for _, section := range []struct {
+		id       byte
+		contents *bytes.Buffer
+	}{
+		{1, &encodedTypes},
+		{2, &imports},
+		{3, &functions},
+		{6, &globals},
+		{7, &exports},
+		{10, &code},
+	} {
+		out.WriteByte(section.id)
+		writeUnsigned(&out, uint64(section.contents.Len()))
+		out.Write(section.contents.Bytes())
+	}
+	return out.Bytes()
+}
+
+func writeName(out *bytes.Buffer, name string) {
+	writeUnsigned(out, uint64(len(name)))
+	out.WriteString(name)
+}
+
+// writeUnsigned writes value in unsigned LEB128.
+func writeUnsigned(out *bytes.Buffer, value uint64) {
+	for {
+		b := byte(value & 0x7f)
+		value >>= 7
+		if value != 0 {
+			b |= 0x80
+		}
+		out.WriteByte(b)
+		if value == 0 {
+			return
+		}
+	}
+}
+
+// writeSigned writes value in signed LEB128.
+func writeSigned(out *bytes.Buffer, value int64) {
+	for {
+		b := byte(value & 0x7f)
+		value >>= 7
+		if value == 0 && b&0x40 == 0 || value == -1 && b&0x40 != 0 {
+			out.WriteByte(b)
+			return
+		}
+		out.WriteByte(b | 0x80)
+	}
+}
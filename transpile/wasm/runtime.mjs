@@ -0,0 +1,24 @@
+// Runs a Monkey program compiled to WebAssembly, providing the functions it
+// imports: node runtime.mjs program.wasm
+
+import { readFile } from "node:fs/promises";
+
+const imports = {
+  monkey: {
+    puts_int: (value) => console.log(value.toString()),
+    puts_bool: (value) => console.log(value ? "true" : "false"),
+  },
+};
+
+const { instance } = await WebAssembly.instantiate(await readFile(process.argv[2]), imports);
+try {
+  instance.exports.main();
+} catch (error) {
+  if (!(error instanceof WebAssembly.RuntimeError)) {
+    throw error;
+  }
+  // Traps are named as the interpreter names the same errors.
+  const message = error.message === "divide by zero" ? "division by zero" : error.message;
+  console.error(`ERROR: ${message}`);
+  process.exitCode = 1;
+}
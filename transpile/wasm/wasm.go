@@ -0,0 +1,542 @@
+// Package wasm compiles Monkey programs into WebAssembly modules, so the
+// numeric parts of scripts can run in the browser or any other WebAssembly
+// host at native speed.
+//
+// Only a numeric subset of Monkey is supported: integers, held in i64, and
+// booleans, held in i32, with the arithmetic and comparison operators on
+// them, let and var bindings, if expressions, while and do-while loops with
+// break and continue, and the functions bound with let at the top of the program,
+// which take and return integers. Other values, closures and builtins but
+// puts are reported as unsupported. Top-level bindings become globals, and
+// the top-level statements the function exported as main.
+//
+// The module imports the functions printing puts's arguments from the host,
+// as puts_int and puts_bool in the monkey module; Runtime provides them.
+package wasm
+
+import (
+	_ "embed"
+	"fmt"
+	"monkey/ast"
+	"monkey/token"
+	"monkey/transpile"
+	"strings"
+)
+
+// Runtime is an ES module running a compiled program with Node.js, given
+// the path to the module: node runtime.mjs program.wasm.
+//
+//go:embed runtime.mjs
+var Runtime string
+
+// Transpile returns the binary WebAssembly module of a program doing what
+// program does.
+func Transpile(program *ast.Program) ([]byte, error) {
+	compiler := &compiler{
+		module: &module{imports: []imported{
+			{"monkey", "puts_int", signature{params: []byte{i64}}},
+			{"monkey", "puts_bool", signature{params: []byte{i32}}},
+		}},
+		functions: make(map[string]*declared),
+		globals:   make(map[string]binding),
+		program:   transpile.NewScope(nil, program),
+	}
+
+	bound := make(map[string]int)
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.LetStatement:
+			bound[node.Name.Value]++
+		}
+		return true
+	})
+
+	var order []*declared
+	for _, statement := range program.Statements {
+		let, ok := statement.(*ast.LetStatement)
+		if !ok || let.Token.Type != token.LET || bound[let.Name.Value] != 1 {
+			continue
+		}
+		literal, ok := let.Value.(*ast.FunctionLiteral)
+		if !ok {
+			continue
+		}
+
+		params := make([]byte, len(literal.Parameters))
+		for i := range params {
+			params[i] = i64
+		}
+		function := &function{
+			name:      let.Name.Value,
+			signature: signature{params: params, results: []byte{i64}},
+			exported:  let.Name.Value != "main",
+		}
+		compiler.functions[let.Name.Value] = &declared{
+			index:    uint64(len(compiler.module.imports) + len(compiler.module.functions)),
+			literal:  literal,
+			function: function,
+		}
+		order = append(order, compiler.functions[let.Name.Value])
+		compiler.module.functions = append(compiler.module.functions, function)
+	}
+
+	// The top-level statements are compiled first, so the types of the
+	// globals are known in the functions.
+	compiler.current = &function{name: "main", exported: true}
+	compiler.module.functions = append(compiler.module.functions, compiler.current)
+	compiler.statements(program.Statements, false)
+	for _, declared := range order {
+		compiler.function(declared)
+	}
+
+	if compiler.err != nil {
+		return nil, compiler.err
+	}
+	return compiler.module.encode(), nil
+}
+
+// A kind is the type of the value an expression leaves.
+type kind int
+
+const (
+	none    kind = iota // No value, like what puts returns
+	integer             // An i64
+	boolean             // An i32, 0 or 1
+	never               // Control doesn't get past it, as with a return
+)
+
+func (kind kind) valueType() byte {
+	if kind == boolean {
+		return i32
+	}
+	return i64
+}
+
+// A binding is the global, or the local of the function being compiled, a
+// name is bound to.
+type binding struct {
+	index   uint64
+	kind    kind
+	global  bool
+	mutable bool
+}
+
+// A declared function is one bound with let at the top of the program.
+type declared struct {
+	index    uint64
+	literal  *ast.FunctionLiteral
+	function *function
+	defined  bool // Whether the top-level statements reached its let
+}
+
+// A loop is a while or do-while loop being compiled, with the Monkey label
+// breaks and continues can target it by and the depths of the blocks they
+// branch to.
+type loop struct {
+	label         string
+	breakDepth    int
+	continueDepth int
+}
+
+type compiler struct {
+	module    *module
+	functions map[string]*declared
+	globals   map[string]binding
+	program   *transpile.Scope
+
+	// What's specific to the function being compiled, scope being nil for
+	// the top-level statements.
+	current *function
+	scope   *transpile.Scope
+	locals  map[string]binding
+	loops   []loop
+	depth   int
+
+	err error
+}
+
+func (compiler *compiler) write(code ...byte) {
+	compiler.current.code.Write(code)
+}
+
+func (compiler *compiler) writeIndex(index uint64) {
+	writeUnsigned(&compiler.current.code, index)
+}
+
+func (compiler *compiler) unsupported(node ast.Node) kind {
+	if compiler.err == nil {
+		compiler.err = fmt.Errorf("wasm: %s is not supported: %s", strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast."), node)
+	}
+	return none
+}
+
+func (compiler *compiler) function(declared *declared) {
+	literal := declared.literal
+	compiler.current = declared.function
+	compiler.scope = transpile.NewScope(literal.Parameters, literal.Body)
+	compiler.locals = make(map[string]binding)
+	compiler.loops, compiler.depth = nil, 0
+	for i, parameter := range literal.Parameters {
+		compiler.locals[parameter.Value] = binding{index: uint64(i), kind: integer}
+	}
+
+	if kind := compiler.statements(literal.Body.Statements, true); kind != integer && kind != never {
+		compiler.unsupported(literal)
+	}
+}
+
+// resolve returns the binding of name, reporting false if it isn't bound
+// to a value yet.
+func (compiler *compiler) resolve(name string) (binding, bool) {
+	if compiler.scope != nil && compiler.scope.Binds(name) {
+		binding, ok := compiler.locals[name]
+		return binding, ok
+	}
+	binding, ok := compiler.globals[name]
+	return binding, ok
+}
+
+// bind returns the binding of name in the innermost scope, which holds
+// values of kind, reporting false if it was bound to another kind before.
+func (compiler *compiler) bind(name string, kind kind) (binding, bool) {
+	bindings, scope := compiler.globals, compiler.program
+	if compiler.scope != nil {
+		bindings, scope = compiler.locals, compiler.scope
+	}
+	if existing, ok := bindings[name]; ok {
+		return existing, existing.kind == kind
+	}
+
+	bound := binding{kind: kind, global: compiler.scope == nil, mutable: scope.Mutable[name]}
+	if bound.global {
+		bound.index = uint64(len(compiler.module.globals))
+		compiler.module.globals = append(compiler.module.globals, kind.valueType())
+	} else {
+		bound.index = uint64(len(compiler.current.signature.params) + len(compiler.current.locals))
+		compiler.current.locals = append(compiler.current.locals, kind.valueType())
+	}
+	bindings[name] = bound
+	return bound, true
+}
+
+func (compiler *compiler) get(binding binding) {
+	if binding.global {
+		compiler.write(opGlobalGet)
+	} else {
+		compiler.write(opLocalGet)
+	}
+	compiler.writeIndex(binding.index)
+}
+
+func (compiler *compiler) set(binding binding) {
+	if binding.global {
+		compiler.write(opGlobalSet)
+	} else {
+		compiler.write(opLocalSet)
+	}
+	compiler.writeIndex(binding.index)
+}
+
+// statements compiles statements, leaving the value of the last one if
+// keep is set, and returns its kind.
+func (compiler *compiler) statements(statements []ast.Statement, keep bool) kind {
+	kind := none
+	for i, statement := range statements {
+		kind = compiler.statement(statement, keep && i == len(statements)-1)
+	}
+	if !keep {
+		return none
+	}
+	return kind
+}
+
+func (compiler *compiler) statement(statement ast.Statement, keep bool) kind {
+	switch statement := statement.(type) {
+	case *ast.ExpressionStatement:
+		if keep {
+			return compiler.expression(statement.Expression)
+		}
+		switch expression := statement.Expression.(type) {
+		case *ast.IfExpression:
+			compiler.ifExpression(expression, false)
+		case *ast.DoWhileExpression, *ast.WhileExpression:
+			compiler.loop(expression)
+		default:
+			if kind := compiler.expression(expression); kind == integer || kind == boolean {
+				compiler.write(opDrop)
+			}
+		}
+		return none
+
+	case *ast.LetStatement:
+		if declared, ok := compiler.functions[statement.Name.Value]; ok && declared.literal == statement.Value {
+			declared.defined = true
+			return none
+		}
+		kind := compiler.expression(statement.Value)
+		if kind != integer && kind != boolean {
+			return compiler.unsupported(statement)
+		}
+		binding, ok := compiler.bind(statement.Name.Value, kind)
+		if !ok {
+			return compiler.unsupported(statement)
+		}
+		compiler.set(binding)
+		return none
+
+	case *ast.ReturnStatement:
+		if statement.ReturnValue == nil {
+			return compiler.unsupported(statement)
+		}
+		kind := compiler.expression(statement.ReturnValue)
+		switch {
+		case compiler.scope == nil && (kind == integer || kind == boolean):
+			compiler.write(opDrop)
+		case compiler.scope != nil && kind != integer && kind != never:
+			return compiler.unsupported(statement)
+		}
+		compiler.write(opReturn)
+		return never
+
+	case *ast.BreakStatement:
+		loop, ok := compiler.loopOf(statement.Label)
+		if !ok || statement.Value != nil {
+			return compiler.unsupported(statement)
+		}
+		compiler.write(opBr)
+		compiler.writeIndex(uint64(compiler.depth - loop.breakDepth))
+		return never
+
+	case *ast.ContinueStatement:
+		loop, ok := compiler.loopOf(statement.Label)
+		if !ok {
+			return compiler.unsupported(statement)
+		}
+		compiler.write(opBr)
+		compiler.writeIndex(uint64(compiler.depth - loop.continueDepth))
+		return never
+
+	default:
+		return compiler.unsupported(statement)
+	}
+}
+
+// loopOf returns the loop targeted by a break or continue with label, which
+// is nil for the innermost loop.
+func (compiler *compiler) loopOf(label *ast.Identifier) (loop, bool) {
+	for i := len(compiler.loops) - 1; i >= 0; i-- {
+		if label == nil || compiler.loops[i].label == label.Value {
+			return compiler.loops[i], true
+		}
+	}
+	return loop{}, false
+}
+
+// condition compiles expression, leaving whether it's truthy as an i32.
+func (compiler *compiler) condition(expression ast.Expression) {
+	switch compiler.expression(expression) {
+	case integer:
+		compiler.write(opDrop, opI32Const, 1)
+	case none:
+		compiler.write(opI32Const, 0)
+	}
+}
+
+// ifExpression compiles expression, leaving its value if keep is set. An if
+// without an else has no value, since it would be null when the condition
+// is false.
+func (compiler *compiler) ifExpression(expression *ast.IfExpression, keep bool) kind {
+	compiler.condition(expression.Condition)
+	start := compiler.current.code.Len()
+	compiler.write(opIf, blockEmpty)
+	compiler.depth++
+	consequence, alternative := compiler.statements(expression.Consequence.Statements, keep), none
+	if expression.Alternative != nil {
+		compiler.write(opElse)
+		alternative = compiler.statements(expression.Alternative.Statements, keep)
+	}
+	compiler.write(opEnd)
+	compiler.depth--
+	if !keep {
+		return none
+	}
+
+	result := consequence
+	if consequence == never {
+		result = alternative
+	} else if alternative != never && alternative != consequence {
+		return compiler.unsupported(expression)
+	}
+
+	switch result {
+	case integer, boolean:
+		compiler.current.code.Bytes()[start+1] = result.valueType()
+	case never:
+		compiler.write(opUnreachable)
+	default:
+		return compiler.unsupported(expression)
+	}
+	return result
+}
+
+// loop compiles a do-while or while loop, whose value is dropped, as a
+// block to break out of around a loop, which holds a block to continue
+// from. A while loop breaks out before the body when its condition is
+// false, and a do-while loop branches back after it when it's true.
+func (compiler *compiler) loop(expression ast.Expression) {
+	labeled, condition, body, pretest := transpile.Loop(expression)
+	label := ""
+	if labeled != nil {
+		label = labeled.Value
+	}
+
+	compiler.write(opBlock, blockEmpty, opLoop, blockEmpty)
+	compiler.depth += 2
+	if pretest {
+		compiler.condition(condition)
+		compiler.write(opI32Eqz, opBrIf, 1)
+	}
+	compiler.write(opBlock, blockEmpty)
+	compiler.depth++
+	compiler.loops = append(compiler.loops, loop{label: label, breakDepth: compiler.depth - 2, continueDepth: compiler.depth})
+	compiler.statements(body.Statements, false)
+	compiler.loops = compiler.loops[:len(compiler.loops)-1]
+	compiler.write(opEnd)
+	compiler.depth--
+
+	if pretest {
+		compiler.write(opBr, 0)
+	} else {
+		compiler.condition(condition)
+		compiler.write(opBrIf, 0)
+	}
+	compiler.write(opEnd, opEnd)
+	compiler.depth -= 2
+}
+
+// expression compiles expression, leaving its value, and returns its kind.
+func (compiler *compiler) expression(expression ast.Expression) kind {
+	switch expression := expression.(type) {
+	case *ast.IntegerLiteral:
+		compiler.write(opI64Const)
+		writeSigned(&compiler.current.code, expression.Value)
+		return integer
+
+	case *ast.Boolean:
+		if expression.Value {
+			compiler.write(opI32Const, 1)
+		} else {
+			compiler.write(opI32Const, 0)
+		}
+		return boolean
+
+	case *ast.Identifier:
+		binding, ok := compiler.resolve(expression.Value)
+		if !ok {
+			break
+		}
+		compiler.get(binding)
+		return binding.kind
+
+	case *ast.PrefixExpression:
+		switch expression.Operator {
+		case "-":
+			compiler.write(opI64Const, 0)
+			if compiler.expression(expression.Right) != integer {
+				break
+			}
+			compiler.write(opI64Sub)
+			return integer
+		case "!":
+			switch compiler.expression(expression.Right) {
+			case boolean:
+				compiler.write(opI32Eqz)
+				return boolean
+			case integer:
+				compiler.write(opDrop, opI32Const, 0)
+				return boolean
+			}
+		}
+
+	case *ast.InfixExpression:
+		left, right := compiler.expression(expression.Left), compiler.expression(expression.Right)
+		if left == integer && right == integer {
+			if op, ok := map[string]byte{"+": opI64Add, "-": opI64Sub, "*": opI64Mul, "/": opI64DivS}[expression.Operator]; ok {
+				compiler.write(op)
+				return integer
+			}
+			if op, ok := map[string]byte{"<": opI64LtS, ">": opI64GtS, "==": opI64Eq, "!=": opI64Ne}[expression.Operator]; ok {
+				compiler.write(op)
+				return boolean
+			}
+		}
+		if left == boolean && right == boolean {
+			if op, ok := map[string]byte{"==": opI32Eq, "!=": opI32Ne}[expression.Operator]; ok {
+				compiler.write(op)
+				return boolean
+			}
+		}
+
+	case *ast.AssignExpression:
+		binding, ok := compiler.resolve(expression.Name.Value)
+		if !ok || !binding.mutable || compiler.expression(expression.Value) != binding.kind {
+			break
+		}
+		if binding.global {
+			compiler.set(binding)
+			compiler.get(binding)
+		} else {
+			compiler.write(opLocalTee)
+			compiler.writeIndex(binding.index)
+		}
+		return binding.kind
+
+	case *ast.CallExpression:
+		return compiler.call(expression)
+
+	case *ast.IfExpression:
+		return compiler.ifExpression(expression, true)
+	}
+
+	return compiler.unsupported(expression)
+}
+
+// call compiles a call of a declared function, or of puts, which prints its
+// arguments with the functions the host provides.
+func (compiler *compiler) call(expression *ast.CallExpression) kind {
+	callee, ok := expression.Function.(*ast.Identifier)
+	if !ok || compiler.scope != nil && compiler.scope.Binds(callee.Value) {
+		return compiler.unsupported(expression)
+	}
+
+	if declared, ok := compiler.functions[callee.Value]; ok {
+		if len(expression.Arguments) != len(declared.literal.Parameters) || compiler.scope == nil && !declared.defined {
+			return compiler.unsupported(expression)
+		}
+		for _, argument := range expression.Arguments {
+			if compiler.expression(argument) != integer {
+				return compiler.unsupported(expression)
+			}
+		}
+		compiler.write(opCall)
+		compiler.writeIndex(declared.index)
+		return integer
+	}
+
+	if callee.Value != "puts" || compiler.program.Binds("puts") {
+		return compiler.unsupported(expression)
+	}
+	for _, argument := range expression.Arguments {
+		switch compiler.expression(argument) {
+		case integer:
+			compiler.write(opCall, 0)
+		case boolean:
+			compiler.write(opCall, 1)
+		default:
+			return compiler.unsupported(expression)
+		}
+	}
+	return none
+}
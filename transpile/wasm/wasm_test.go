@@ -0,0 +1,131 @@
+package wasm
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Diagnostics())
+	}
+	return program
+}
+
+func TestTranspile(t *testing.T) {
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skip("node isn't installed")
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`var i = 0;
+var count = 0;
+outer: while (true) {
+	i = i + 1;
+	var j = 0;
+	while (j < 5) {
+		j = j + 1;
+		if (j == 3) { continue outer; }
+		if (i == 3) { break outer; }
+		count = count + 1;
+	}
+}
+var n = 0;
+while (false) { n = 1; }
+puts(count, n)`,
+			"4\n0\n",
+		},
+		{
+			"let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) }; puts(fib(15))",
+			"610\n",
+		},
+		{
+			`let even = fn(n) { if (n == 0) { 1 } else { odd(n - 1) } };
+let odd = fn(n) { if (n == 0) { 0 } else { even(n - 1) } };
+puts(even(10) == 1, odd(7) != 1)`,
+			"true\nfalse\n",
+		},
+		{
+			`var i = 0;
+var total = 0;
+outer: do {
+	i = i + 1;
+	do { if (i > 100) { break outer; } continue; } while (false);
+	total = total + i;
+	if (total > 50) { break; }
+} while (true);
+puts(i, total, -i, !(i < 3), 7 / 2, !5)`,
+			"10\n55\n-10\ntrue\n3\nfalse\n",
+		},
+		{
+			`let f = fn(x) { var y = x; y = y * 2; if (y > 10) { return y; } else { y + 1 } };
+let big = 9223372036854775807;
+puts(f(3), f(10), big + 1, if (big > 0) { true } else { false })`,
+			"7\n20\n-9223372036854775808\ntrue\n",
+		},
+		{"puts(1); return 2; puts(3)", "1\n"},
+		{"let zero = 0; puts(1 / zero)", "ERROR: division by zero\n"},
+	}
+
+	dir := t.TempDir()
+	runtime := filepath.Join(dir, "runtime.mjs")
+	if err := os.WriteFile(runtime, []byte(Runtime), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		module, err := Transpile(parse(t, test.input))
+		if err != nil {
+			t.Errorf("transpiling %q failed: %s", test.input, err)
+			continue
+		}
+
+		path := filepath.Join(dir, "main.wasm")
+		if err := os.WriteFile(path, module, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		output, _ := exec.Command("node", runtime, path).CombinedOutput()
+		if string(output) != test.expected {
+			t.Errorf("wrong output for %q. expected=%q, got=%q", test.input, test.expected, output)
+		}
+	}
+}
+
+func TestTranspileUnsupported(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`puts("a")`, "wasm: StringLiteral is not supported"},
+		{"let x = 1.5;", "wasm: FloatLiteral is not supported"},
+		{"let f = fn() { true }; f()", "wasm: FunctionLiteral is not supported"},
+		{"let f = fn(x) { fn() { x } };", "wasm: FunctionLiteral is not supported"},
+		{"let x = 1; x = 2", "wasm: AssignExpression is not supported"},
+		{"var x = 1; x = true", "wasm: AssignExpression is not supported"},
+		{"1 + true", "wasm: InfixExpression is not supported"},
+		{"let x = if (true) { 1 };", "wasm: IfExpression is not supported"},
+		{"let x = do { break 1; } while (true);", "wasm: DoWhileExpression is not supported"},
+		{"f(1); let f = fn(x) { x };", "wasm: CallExpression is not supported"},
+		{"let f = fn(x) { x }; f(1, 2)", "wasm: CallExpression is not supported"},
+		{"let f = fn(x) { y };", "wasm: Identifier is not supported"},
+		{"len([1])", "wasm: CallExpression is not supported"},
+	}
+
+	for _, test := range tests {
+		_, err := Transpile(parse(t, test.input))
+		if err == nil || !strings.HasPrefix(err.Error(), test.expected) {
+			t.Errorf("wrong error for %q. expected=%q, got=%v", test.input, test.expected, err)
+		}
+	}
+}